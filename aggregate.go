@@ -0,0 +1,368 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AggregateOp is the calculation done by an Aggregation.
+type AggregateOp int
+
+const (
+	// AggregateCount counts the rows of a group.
+	AggregateCount AggregateOp = iota
+	// AggregateSum adds the values of a numeric column, the result is an int64, uint64 or float64 depending on the kind
+	// of the field.
+	AggregateSum
+	// AggregateMin is the smallest value of a numeric, string, time.Time or Date column.
+	AggregateMin
+	// AggregateMax is the largest value of a numeric, string, time.Time or Date column.
+	AggregateMax
+)
+
+var aggregateOpNames = map[AggregateOp]string{
+	AggregateCount: "count",
+	AggregateSum:   "sum",
+	AggregateMin:   "min",
+	AggregateMax:   "max",
+}
+
+// An Aggregation is a value calculated for each group of rows by an Aggregator, eg Sum("amount").As("total").
+type Aggregation struct {
+	Op     AggregateOp
+	Column string // the column aggregated, unused by AggregateCount
+	Name   string // the name of the result column, defaults to eg sum(amount)
+}
+
+// Count returns an Aggregation that counts the rows of each group, the result column is called count.
+func Count() Aggregation {
+	return Aggregation{Op: AggregateCount}
+}
+
+// Sum returns an Aggregation that adds the values of column.
+func Sum(column string) Aggregation {
+	return Aggregation{Op: AggregateSum, Column: column}
+}
+
+// Min returns an Aggregation that finds the smallest value of column.
+func Min(column string) Aggregation {
+	return Aggregation{Op: AggregateMin, Column: column}
+}
+
+// Max returns an Aggregation that finds the largest value of column.
+func Max(column string) Aggregation {
+	return Aggregation{Op: AggregateMax, Column: column}
+}
+
+// As sets the name of the result column.
+func (a Aggregation) As(name string) Aggregation {
+	a.Name = name
+	return a
+}
+
+// name returns the name of the result column.
+func (a Aggregation) name() string {
+	switch {
+	case a.Name != "":
+		return a.Name
+	case a.Op == AggregateCount:
+		return aggregateOpNames[a.Op]
+	default:
+		return fmt.Sprintf("%s(%s)", aggregateOpNames[a.Op], a.Column)
+	}
+}
+
+// An Aggregator groups rows by the values of some columns and calculates Aggregations for each group, eg for simple
+// reports. Rows can be added one at a time (eg while streaming with DecodeOneStruct) or use GroupBy for a slice. The
+// result rows can be written using MarshalMaps(agg.Header(), agg.Rows()).
+type Aggregator struct {
+	by   []string
+	aggs []Aggregation
+
+	st     reflect.Type
+	keyFis []fieldInfo
+	aggFis []*fieldInfo // nil for AggregateCount
+	enc    *Encoder
+	groups map[string]*group
+	order  []*group // groups in the order they're first seen
+	n      int
+}
+
+// group is the state of a group of rows.
+type group struct {
+	keys []interface{}
+	vals []reflect.Value // invalid until a value is aggregated
+	n    int
+}
+
+// NewAggregator returns an Aggregator that groups rows by the columns in by (all rows are a single group if there
+// aren't any) and calculates aggs for each group.
+func NewAggregator(by []string, aggs ...Aggregation) *Aggregator {
+	return &Aggregator{
+		by:     by,
+		aggs:   aggs,
+		enc:    &Encoder{},
+		groups: make(map[string]*group),
+	}
+}
+
+// Add adds a row, v must be a struct or pointer to a struct, all rows must be the same type. Nil pointer fields aren't
+// included in sums, minimums or maximums.
+func (agg *Aggregator) Add(v interface{}) error {
+	sv := reflect.ValueOf(v)
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			return newEncodeError(agg.st, "", agg.n, errors.New("nil row"))
+		}
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct {
+		return fmt.Errorf("expected struct, got %T: %w", v, ErrNotStructPtr)
+	}
+	if agg.st == nil {
+		if err := agg.mapColumns(sv.Type()); err != nil {
+			return err
+		}
+	} else if sv.Type() != agg.st {
+		return fmt.Errorf("expected %s, got %s", agg.st, sv.Type())
+	}
+	if !sv.CanAddr() {
+		// fieldValue needs an addressable struct
+		p := reflect.New(agg.st).Elem()
+		p.Set(sv)
+		sv = p
+	}
+
+	keys := make([]string, len(agg.keyFis))
+	for i, fi := range agg.keyFis {
+		k, err := agg.enc.fieldString(sv, fi)
+		if err != nil {
+			return newEncodeError(agg.st, fi.Name, agg.n, err)
+		}
+		keys[i] = k
+	}
+	key := strings.Join(keys, "\x00")
+	g, found := agg.groups[key]
+	if !found {
+		g = &group{vals: make([]reflect.Value, len(agg.aggs))}
+		for _, fi := range agg.keyFis {
+			fv, err := fieldValue(sv, fi)
+			if err != nil {
+				return newEncodeError(agg.st, fi.Name, agg.n, err)
+			}
+			g.keys = append(g.keys, fv.Interface())
+		}
+		agg.groups[key] = g
+		agg.order = append(agg.order, g)
+	}
+	g.n++
+
+	for i, a := range agg.aggs {
+		fi := agg.aggFis[i]
+		if fi == nil {
+			continue
+		}
+		fv, err := fieldValue(sv, *fi)
+		if err != nil {
+			return newEncodeError(agg.st, fi.Name, agg.n, err)
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if g.vals[i], err = aggregate(a.Op, g.vals[i], fv); err != nil {
+			return newEncodeError(agg.st, fi.Name, agg.n, err)
+		}
+	}
+	agg.n++
+	return nil
+}
+
+// mapColumns finds the fields of the group by and aggregated columns of the struct st.
+func (agg *Aggregator) mapColumns(st reflect.Type) error {
+	fis, err := defaultEncRegister.encodeFields(st)
+	if err != nil {
+		return err
+	}
+	fields := make(map[string]*fieldInfo, len(fis))
+	for i := range fis {
+		fields[fis[i].ColName] = &fis[i]
+	}
+
+	var missing []string
+	keyFis := make([]fieldInfo, 0, len(agg.by))
+	for _, col := range agg.by {
+		if fi, found := fields[col]; found {
+			keyFis = append(keyFis, *fi)
+		} else {
+			missing = append(missing, col)
+		}
+	}
+	aggFis := make([]*fieldInfo, len(agg.aggs))
+	for i, a := range agg.aggs {
+		if a.Op == AggregateCount {
+			continue
+		}
+		fi, found := fields[a.Column]
+		if !found {
+			missing = append(missing, a.Column)
+			continue
+		}
+		if fi.ArrayLen > 0 {
+			return fmt.Errorf("array column %s can't be aggregated", a.Column)
+		}
+		aggFis[i] = fi
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingColumn, strings.Join(missing, ", "))
+	}
+
+	agg.st, agg.keyFis, agg.aggFis = st, keyFis, aggFis
+	return nil
+}
+
+// Header returns the names of the result columns, the group by columns followed by the aggregations.
+func (agg *Aggregator) Header() []string {
+	header := make([]string, 0, len(agg.by)+len(agg.aggs))
+	header = append(header, agg.by...)
+	for _, a := range agg.aggs {
+		header = append(header, a.name())
+	}
+	return header
+}
+
+// Rows returns a row for each group in the order they were first added, keyed by the names from Header. Group by
+// columns have the values of the fields, a minimum or maximum has the type of the field and is nil if there weren't
+// any values.
+func (agg *Aggregator) Rows() []map[string]interface{} {
+	rows := make([]map[string]interface{}, len(agg.order))
+	for i, g := range agg.order {
+		row := make(map[string]interface{}, len(agg.by)+len(agg.aggs))
+		for j, col := range agg.by {
+			row[col] = g.keys[j]
+		}
+		for j, a := range agg.aggs {
+			switch {
+			case a.Op == AggregateCount:
+				row[a.name()] = g.n
+			case g.vals[j].IsValid():
+				row[a.name()] = g.vals[j].Interface()
+			case a.Op == AggregateSum:
+				row[a.name()] = 0
+			default:
+				row[a.name()] = nil
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// GroupBy groups the rows of the slice v (of structs or pointers to structs, or a pointer to the slice) by the columns
+// in by and returns the result rows and their header, see Aggregator.
+func GroupBy(v interface{}, by []string, aggs ...Aggregation) ([]string, []map[string]interface{}, error) {
+	slice := reflect.Indirect(reflect.ValueOf(v))
+	if slice.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("expected slice, got %T: %w", v, ErrNotSlicePtr)
+	}
+	agg := NewAggregator(by, aggs...)
+	for i := 0; i < slice.Len(); i++ {
+		if err := agg.Add(slice.Index(i).Interface()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return agg.Header(), agg.Rows(), nil
+}
+
+// aggregate returns the result of applying op to the aggregated value acc (invalid if there isn't one yet) and the
+// field value fv.
+func aggregate(op AggregateOp, acc, fv reflect.Value) (reflect.Value, error) {
+	switch op {
+	case AggregateSum:
+		var sum interface{}
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			sum = fv.Int()
+			if acc.IsValid() {
+				sum = acc.Int() + fv.Int()
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			sum = fv.Uint()
+			if acc.IsValid() {
+				sum = acc.Uint() + fv.Uint()
+			}
+		case reflect.Float32, reflect.Float64:
+			sum = fv.Float()
+			if acc.IsValid() {
+				sum = acc.Float() + fv.Float()
+			}
+		default:
+			return acc, fmt.Errorf("can't sum values of type %s", fv.Type())
+		}
+		return reflect.ValueOf(sum), nil
+
+	case AggregateMin, AggregateMax:
+		c, err := compareValues(fv, acc)
+		if err != nil {
+			return acc, err
+		}
+		if !acc.IsValid() || (op == AggregateMin && c < 0) || (op == AggregateMax && c > 0) {
+			return fv, nil
+		}
+		return acc, nil
+	}
+	return acc, fmt.Errorf("unsupported aggregation %d", op)
+}
+
+// compareValues returns -1, 0 or 1 depending on whether a is less than, equal to or greater than b, b can be invalid
+// (0 is returned) so the type of a is always checked.
+func compareValues(a, b reflect.Value) (int, error) {
+	var less, greater bool
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if b.IsValid() {
+			less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if b.IsValid() {
+			less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+		}
+	case reflect.Float32, reflect.Float64:
+		if b.IsValid() {
+			less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+		}
+	case reflect.String:
+		if b.IsValid() {
+			less, greater = a.String() < b.String(), a.String() > b.String()
+		}
+	default:
+		switch at := a.Interface().(type) {
+		case time.Time:
+			if b.IsValid() {
+				bt := b.Interface().(time.Time)
+				less, greater = at.Before(bt), at.After(bt)
+			}
+		case Date:
+			if b.IsValid() {
+				bt := b.Interface().(Date).In(time.UTC)
+				less, greater = at.In(time.UTC).Before(bt), at.In(time.UTC).After(bt)
+			}
+		default:
+			return 0, fmt.Errorf("can't compare values of type %s", a.Type())
+		}
+	}
+
+	switch {
+	case less:
+		return -1, nil
+	case greater:
+		return 1, nil
+	}
+	return 0, nil
+}
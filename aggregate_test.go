@@ -0,0 +1,83 @@
+package csvplus_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestGroupBy(t *testing.T) {
+	type Sale struct {
+		Region string       `csvplus:"region"`
+		Amount float64      `csvplus:"amount"`
+		Units  *int         `csvplus:"units"`
+		Day    csvplus.Date `csvplus:"day"`
+	}
+	var sales []Sale
+	data := "region,amount,units,day\nnorth,1.5,2,2024-01-02\nsouth,3,,2024-01-01\nnorth,2,5,2024-01-01\n"
+	if err := csvplus.Unmarshal([]byte(data), &sales); err != nil {
+		t.Fatal(err)
+	}
+
+	header, rows, err := csvplus.GroupBy(sales, []string{"region"},
+		csvplus.Count(),
+		csvplus.Sum("amount").As("total"),
+		csvplus.Sum("units"),
+		csvplus.Max("units"),
+		csvplus.Min("day"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHeader := []string{"region", "count", "total", "sum(units)", "max(units)", "min(day)"}
+	if !reflect.DeepEqual(header, expectedHeader) {
+		t.Errorf("expected header %v, got %v", expectedHeader, header)
+	}
+	expected := []map[string]interface{}{
+		{"region": "north", "count": 2, "total": 3.5, "sum(units)": int64(7), "max(units)": 5,
+			"min(day)": csvplus.Date{Year: 2024, Month: 1, Day: 1}},
+		{"region": "south", "count": 1, "total": 3.0, "sum(units)": 0, "max(units)": nil,
+			"min(day)": csvplus.Date{Year: 2024, Month: 1, Day: 1}},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %v, got %v", expected, rows)
+	}
+
+	out, err := csvplus.MarshalMaps(header, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOut := "region,count,total,sum(units),max(units),min(day)\nnorth,2,3.5,7,5,2024-01-01\nsouth,1,3,0,,2024-01-01\n"
+	if string(out) != expectedOut {
+		t.Errorf("expected %q, got %q", expectedOut, out)
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		agg := csvplus.NewAggregator(nil, csvplus.Count(), csvplus.Max("region"))
+		for i := range sales {
+			if err := agg.Add(&sales[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected := []map[string]interface{}{{"count": 3, "max(region)": "south"}}
+		if rows := agg.Rows(); !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected %v, got %v", expected, rows)
+		}
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		_, _, err := csvplus.GroupBy(sales, []string{"nope"}, csvplus.Sum("amount"))
+		if !errors.Is(err, csvplus.ErrMissingColumn) {
+			t.Errorf("expected ErrMissingColumn, got: %v", err)
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, _, err := csvplus.GroupBy(sales, nil, csvplus.Sum("region"))
+		if err == nil {
+			t.Error("expected an error summing strings")
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package csvplus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// bomReader strips a byte order mark from the start of the input (often added by Excel), input with a UTF-16 byte
+// order mark is transcoded to UTF-8.
+type bomReader struct {
+	r       io.Reader
+	checked bool
+	bomLen  int              // length of the UTF-8 BOM stripped from the input
+	prefix  []byte           // bytes read while checking for a BOM that haven't been returned yet
+	order   binary.ByteOrder // only set for UTF-16 input
+	pending []byte           // UTF-16 input that hasn't been decoded yet
+	out     []byte           // decoded UTF-8 output that hasn't been returned yet
+	err     error
+	n       int64  // offset of the next byte returned
+	limit   int64  // offset errReadLimit is returned at, 0 means no limit
+	total   int64  // bytes returned, unlike n it isn't changed by Seek
+	peeked  []byte // output read by peek that hasn't been returned yet
+}
+
+func newBOMReader(r io.Reader) *bomReader {
+	return &bomReader{r: r}
+}
+
+func (br *bomReader) Read(p []byte) (int, error) {
+	if br.limit > 0 {
+		if br.n >= br.limit {
+			return 0, errReadLimit
+		}
+		if int64(len(p)) > br.limit-br.n {
+			p = p[:br.limit-br.n]
+		}
+	}
+	n, err := br.read(p)
+	br.n += int64(n)
+	br.total += int64(n)
+	return n, err
+}
+
+func (br *bomReader) read(p []byte) (int, error) {
+	if len(br.peeked) > 0 {
+		n := copy(p, br.peeked)
+		br.peeked = br.peeked[n:]
+		return n, nil
+	}
+	return br.readInput(p)
+}
+
+// peek returns up to n bytes of output without consuming them, fewer are only returned at the end of the input.
+func (br *bomReader) peek(n int) ([]byte, error) {
+	for len(br.peeked) < n {
+		buf := make([]byte, n-len(br.peeked))
+		m, err := br.readInput(buf)
+		br.peeked = append(br.peeked, buf[:m]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return br.peeked, nil
+}
+
+// readInput reads output that hasn't been peeked.
+func (br *bomReader) readInput(p []byte) (int, error) {
+	if !br.checked {
+		if err := br.checkBOM(); err != nil {
+			return 0, err
+		}
+	}
+
+	if br.order != nil {
+		return br.readUTF16(p)
+	}
+	if len(br.prefix) > 0 {
+		n := copy(p, br.prefix)
+		br.prefix = br.prefix[n:]
+		return n, nil
+	}
+	return br.r.Read(p)
+}
+
+// checkBOM reads enough of the input to check for a BOM.
+func (br *bomReader) checkBOM() error {
+	br.checked = true
+	buf := make([]byte, len(utf8BOM))
+	n, err := io.ReadFull(br.r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	buf = buf[:n]
+
+	switch {
+	case bytes.HasPrefix(buf, utf8BOM):
+		buf = buf[len(utf8BOM):]
+		br.bomLen = len(utf8BOM)
+	case bytes.HasPrefix(buf, utf16LEBOM):
+		br.order = binary.LittleEndian
+		br.pending = buf[len(utf16LEBOM):]
+		return nil
+	case bytes.HasPrefix(buf, utf16BEBOM):
+		br.order = binary.BigEndian
+		br.pending = buf[len(utf16BEBOM):]
+		return nil
+	}
+	br.prefix = buf
+	return nil
+}
+
+// readUTF16 reads UTF-16 input and returns it as UTF-8.
+func (br *bomReader) readUTF16(p []byte) (int, error) {
+	for len(br.out) == 0 {
+		if br.err != nil {
+			return 0, br.err
+		}
+		var buf [4096]byte
+		n, err := br.r.Read(buf[:])
+		br.pending = append(br.pending, buf[:n]...)
+		br.err = err
+		br.decodeUTF16()
+	}
+
+	n := copy(p, br.out)
+	br.out = br.out[n:]
+	return n, nil
+}
+
+// decodeUTF16 decodes as much of the pending input as possible, a trailing surrogate pair or odd byte is kept until
+// more input is read.
+func (br *bomReader) decodeUTF16() {
+	b := br.pending
+	for len(b) >= 2 {
+		r := rune(br.order.Uint16(b))
+		if utf16.IsSurrogate(r) {
+			if len(b) < 4 && br.err == nil {
+				// wait for the second half of the pair
+				break
+			}
+			if len(b) >= 4 {
+				if pair := utf16.DecodeRune(r, rune(br.order.Uint16(b[2:]))); pair != utf8.RuneError {
+					br.out = utf8.AppendRune(br.out, pair)
+					b = b[4:]
+					continue
+				}
+			}
+			r = utf8.RuneError
+		}
+		br.out = utf8.AppendRune(br.out, r)
+		b = b[2:]
+	}
+
+	br.pending = append(br.pending[:0], b...)
+	if br.err != nil && len(br.pending) > 0 {
+		// odd number of bytes at the end of the input
+		br.out = utf8.AppendRune(br.out, utf8.RuneError)
+		br.pending = nil
+	}
+}
@@ -0,0 +1,60 @@
+package csvplus
+
+import (
+	"encoding/csv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// lookupCharset returns the encoding for an IANA charset name (eg iso-8859-1, windows-1252, shift_jis).
+func lookupCharset(name string) (encoding.Encoding, error) {
+	e, err := ianaindex.IANA.Encoding(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unknown charset %s", name)
+	}
+	if e == nil {
+		return nil, errors.Errorf("unsupported charset %s", name)
+	}
+	return e, nil
+}
+
+// Charset sets the charset (IANA name, eg iso-8859-1 or windows-1252) of the input, it's transcoded to UTF-8 as it's
+// read (after a byte order mark is removed). An unknown charset causes Decode to return an error. Has no effect if
+// SetCSVReader is used.
+func (dec *Decoder) Charset(name string) *Decoder {
+	e, err := lookupCharset(name)
+	if err != nil {
+		dec.err = err
+		return dec
+	}
+	// the BOM is removed before the input is transcoded, it would be decoded as characters of the charset
+	dec.src.r = transform.NewReader(newBOMReader(dec.src.r), e.NewDecoder())
+	return dec
+}
+
+// Charset sets the charset (IANA name, eg iso-8859-1 or windows-1252) of the output, characters that can't be
+// represented in the charset cause Encode to return an error. The csv.Writer is replaced with one that writes to the
+// transcoding writer, the Comma and UseCRLF options are kept so they can be set before or after Charset, but a
+// csv.Writer set by SetCSVWriter is replaced. A UTF-8 BOM can't be written in other charsets, Encode returns an error if
+// WriteBOM is also set.
+func (enc *Encoder) Charset(name string) *Encoder {
+	e, err := lookupCharset(name)
+	if err != nil {
+		enc.err = err
+		return enc
+	}
+	enc.charset = ""
+	if e != unicode.UTF8 {
+		enc.charset = name
+	}
+	enc.out = transform.NewWriter(enc.w, e.NewEncoder())
+	csvWriter := csv.NewWriter(enc.out)
+	csvWriter.Comma = enc.csvWriter.Comma
+	csvWriter.UseCRLF = enc.csvWriter.UseCRLF
+	enc.csvWriter = csvWriter
+	return enc
+}
@@ -0,0 +1,219 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// tagOptionNames are the options that can follow the column name in a csvplus tag.
+var tagOptionNames = map[string]bool{
+	"currency":    true,
+	"percent":     true,
+	"delim":       true,
+	"char":        true,
+	"order":       true,
+	"strictempty": true,
+}
+
+// CheckType checks the csvplus tags and field types of the struct (or pointer to struct) type t, it's intended to be
+// called at startup or from a test so mistakes are found before they cause Decode or Encode to fail. It detects
+// fields that use the same column name or index, unknown tag options, options and formats that don't suit the type of
+// the field, invalid time layouts, bases and time zones, and fields of types that can't be converted to or from csv
+// values. All the problems found are included in the returned error.
+func CheckType(t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.Errorf("%s isn't a struct", t)
+	}
+
+	var problems []string
+	columns := make(map[string]string) // column name to field name
+	indices := make(map[string]string) // column index to field name
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, opts := parseTag(sf.Tag.Get("csvplus"))
+		if sf.PkgPath != "" {
+			if tag != "" {
+				problems = append(problems, fmt.Sprintf("unexported field %s has a csvplus tag", sf.Name))
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+
+		names := []string{sf.Name}
+		if tag != "" {
+			names = strings.Split(tag, "|")
+		}
+		for _, name := range names {
+			if other, found := columns[name]; found {
+				problems = append(problems, fmt.Sprintf("fields %s and %s both use column %s", other, sf.Name, name))
+				continue
+			}
+			columns[name] = sf.Name
+		}
+		if idx := sf.Tag.Get("csvplusIndex"); idx != "" {
+			if _, ok := getColIndex(sf); !ok {
+				problems = append(problems, fmt.Sprintf("field %s has an invalid csvplusIndex tag %q", sf.Name, idx))
+			} else if other, found := indices[idx]; found {
+				problems = append(problems, fmt.Sprintf("fields %s and %s both use column index %s", other, sf.Name, idx))
+			} else {
+				indices[idx] = sf.Name
+			}
+		}
+
+		if field, _ := opts.Get("currency"); field != "" {
+			if cf, ok := t.FieldByName(field); !ok || cf.Type.Kind() != reflect.String {
+				problems = append(problems, fmt.Sprintf("field %s has a currency field %s that isn't a string field", sf.Name,
+					field))
+			}
+		}
+		for _, p := range checkField(sf, opts) {
+			problems = append(problems, fmt.Sprintf("field %s %s", sf.Name, p))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("%s: %s", t, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkField returns the problems with the tags and type of the struct field sf.
+func checkField(sf reflect.StructField, opts tagOptions) []string { // nolint: gocyclo
+	var problems []string
+	t := sf.Type
+	if getArrayLen(sf) > 0 {
+		t = t.Elem()
+	} else if _, ok := opts.Get("delim"); ok {
+		problems = append(problems, "has a delim option but isn't an array")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+	isFloat := kind == reflect.Float32 || kind == reflect.Float64
+	isInt := kind >= reflect.Int && kind <= reflect.Uint64
+
+	if opts != "" {
+		for _, opt := range strings.Split(string(opts), ",") {
+			name := strings.SplitN(opt, "=", 2)[0]
+			if !tagOptionNames[name] {
+				problems = append(problems, fmt.Sprintf("has an unknown option %s", name))
+			}
+		}
+	}
+	if order, ok := opts.Get("order"); ok {
+		if n, err := strconv.Atoi(order); err != nil || n < 0 {
+			problems = append(problems, fmt.Sprintf("has an invalid order option %q", order))
+		}
+	}
+	if opts.Contains("currency") && !isFloat {
+		problems = append(problems, "has a currency option but isn't a float")
+	}
+	if d, ok := sf.Tag.Lookup("csvplusDecimal"); ok {
+		if d != "." && d != "," {
+			problems = append(problems, fmt.Sprintf("has an invalid csvplusDecimal tag %q", d))
+		} else if !opts.Contains("currency") {
+			problems = append(problems, "has a csvplusDecimal tag but no currency option")
+		}
+	}
+	if opts.Contains("percent") && !isFloat {
+		problems = append(problems, "has a percent option but isn't a float")
+	}
+	if opts.Contains("char") && !isInt {
+		problems = append(problems, "has a char option but isn't an integer")
+	}
+	if base := sf.Tag.Get("csvplusBase"); base != "" {
+		if n, err := strconv.Atoi(base); err != nil || n < 2 || n > 36 {
+			problems = append(problems, fmt.Sprintf("has an invalid csvplusBase tag %q", base))
+		} else if !isInt {
+			problems = append(problems, "has a csvplusBase tag but isn't an integer")
+		}
+	}
+	if _, err := getRules(sf); err != nil {
+		problems = append(problems, fmt.Sprintf("has an invalid validation tag: %s", errors.Cause(err)))
+	}
+	if _, err := getLocation(sf); err != nil {
+		problems = append(problems, fmt.Sprintf("has an invalid csvplusTZ tag: %s", errors.Cause(err)))
+	}
+
+	if format := sf.Tag.Get("csvplusFormat"); format != "" {
+		switch {
+		case t.String() == timeType:
+			if err := checkTimeLayout(getTimeFormat(reflect.StructField{Type: t, Tag: sf.Tag}), true); err != nil {
+				problems = append(problems, err.Error())
+			}
+		case t == dateType:
+			if err := checkTimeLayout(getTimeFormat(reflect.StructField{Type: t, Tag: sf.Tag}), false); err != nil {
+				problems = append(problems, err.Error())
+			}
+		case isFloat:
+			if s := fmt.Sprintf(format, 1.5); strings.Contains(s, "%!") {
+				problems = append(problems, fmt.Sprintf("has an invalid float format %q", format))
+			}
+		case kind == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			if _, err := decodeBytes("", format); err != nil {
+				problems = append(problems, fmt.Sprintf("has an invalid []byte format %q", format))
+			}
+		}
+	}
+
+	if !supportedType(sf.Type) {
+		problems = append(problems, fmt.Sprintf("has an unsupported type %s", sf.Type))
+	}
+	return problems
+}
+
+// checkTimeLayout returns an error if layout isn't a valid time.Parse layout, auto (or epoch format if epoch is set).
+func checkTimeLayout(layout string, epoch bool) error {
+	if layout == formatAuto || epoch && (layout == formatUnix || layout == formatUnixMilli || layout == formatUnixNano) {
+		return nil
+	}
+	ref := time.Date(2017, 11, 23, 21, 37, 58, 0, time.UTC)
+	s := ref.Format(layout)
+	if s == layout {
+		return errors.Errorf("has a time layout %q without any elements", layout)
+	}
+	if _, err := time.Parse(layout, s); err != nil {
+		return errors.Errorf("has an invalid time layout %q", layout)
+	}
+	return nil
+}
+
+// supportedType reports whether values of type t can be converted to or from csv values.
+func supportedType(t reflect.Type) bool {
+	for _, it := range []reflect.Type{csvUnmarshalerType, csvMarshalerType} {
+		if t.Implements(it) || reflect.PtrTo(t).Implements(it) {
+			return true
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isNetType(t) || t.String() == timeType || t == dateType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Array:
+		return t.Elem().Kind() != reflect.Array && supportedType(t.Elem())
+	}
+	return false
+}
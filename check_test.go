@@ -0,0 +1,67 @@
+package csvplus_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestCheckType(t *testing.T) {
+	type Good struct {
+		ID      int            `csvplus:"id,order=1"`
+		Email   string         `csvplus:"email|e-mail"`
+		Price   float64        `csvplus:"price,currency" csvplusFormat:"%.2f" csvplusDecimal:","`
+		Created time.Time      `csvplusFormat:"2006-01-02 15:04" csvplusTZ:"Europe/London"`
+		Seen    *time.Time     `csvplusFormat:"unix"`
+		Joined  csvplus.Date   `csvplusFormat:"02/01/2006"`
+		Flags   int            `csvplusBase:"16"`
+		Coords  [2]float64     `csvplus:"coords,delim=;"`
+		Data    []byte         `csvplusFormat:"base64"`
+		Ignored map[string]int `csvplus:"-"`
+		private string
+	}
+	if err := csvplus.CheckType(reflect.TypeOf(&Good{})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	type Bad struct {
+		A string            `csvplus:"name"`
+		B string            `csvplus:"other|name"`
+		C int               `csvplus:"c,percent,bogus"`
+		D time.Time         `csvplusFormat:"yesterday"`
+		E int               `csvplusBase:"99"`
+		F map[string]string `csvplus:"f"`
+		G string            `csvplus:"g,delim=;" csvplusTZ:"Nowhere/Special"`
+		H float64           `csvplus:"h,order=x" csvplusFormat:"%d %d"`
+		I float64           `csvplus:"i,currency" csvplusDecimal:"x"`
+		J float64           `csvplus:"j,currency=C"`
+	}
+	err := csvplus.CheckType(reflect.TypeOf(Bad{}))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, expected := range []string{
+		"fields A and B both use column name",
+		"field C has an unknown option bogus",
+		"field C has a percent option but isn't a float",
+		`field D has a time layout "yesterday" without any elements`,
+		`field E has an invalid csvplusBase tag "99"`,
+		"field F has an unsupported type map[string]string",
+		"field G has a delim option but isn't an array",
+		`field H has an invalid order option "x"`,
+		`field H has an invalid float format "%d %d"`,
+		`field I has an invalid csvplusDecimal tag "x"`,
+		"field J has a currency field C that isn't a string field",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %v", expected, err)
+		}
+	}
+
+	if err := csvplus.CheckType(reflect.TypeOf(1)); err == nil {
+		t.Error("expected error for non struct type")
+	}
+}
@@ -0,0 +1,285 @@
+// Command csvplus inspects and converts csv files using the csvplus package.
+//
+// Usage:
+//
+//	csvplus convert [flags] [file]   re-write csv data with a different delimiter/encoding
+//	csvplus validate [flags] [file]  check every row can be read, reports bad rows with line numbers
+//	csvplus head [flags] [file]      preview the first rows as an aligned table
+//	csvplus schema [flags] [file]    print a Go struct (with csvplus tags) for the csv data
+//
+// Data is read from stdin if no file is given, run `csvplus <command> -h` for the flags of each command.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/j0hnsmith/csvplus"
+	"github.com/pkg/errors"
+)
+
+const usage = `usage: csvplus <command> [flags] [file]
+
+commands:
+  convert   re-write csv data with a different delimiter/encoding
+  validate  check every row can be read, reports bad rows with line numbers
+  head      preview the first rows as an aligned table
+  schema    print a Go struct (with csvplus tags) for the csv data
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "convert":
+		err = convert(args, os.Stdin, os.Stdout)
+	case "validate":
+		err = validate(args, os.Stdin, os.Stdout)
+	case "head":
+		err = head(args, os.Stdin, os.Stdout)
+	case "schema":
+		err = schema(args, os.Stdin, os.Stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "csvplus: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// inputFlags are the flags shared by all commands that control how the input is read.
+type inputFlags struct {
+	comma   string
+	charset string
+	skip    int
+}
+
+func (f *inputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.comma, "comma", ",", "input field delimiter (use \\t for tab)")
+	fs.StringVar(&f.charset, "charset", "", "input character encoding, eg windows-1252 (default utf-8)")
+	fs.IntVar(&f.skip, "skip", 0, "number of rows to skip before the header row")
+}
+
+// decoder returns a Decoder for r configured from the flags.
+func (f *inputFlags) decoder(r io.Reader) (*csvplus.Decoder, error) {
+	comma, err := parseDelimiter(f.comma)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := csvplus.NewDecoder(r).Comma(comma).SkipRows(f.skip)
+	if f.charset != "" {
+		dec.Charset(f.charset)
+	}
+	return dec, nil
+}
+
+// parseDelimiter converts a delimiter flag value to a rune, \t is accepted for tab.
+func parseDelimiter(s string) (rune, error) {
+	if s == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError || size != len(s) {
+		return 0, fmt.Errorf("invalid delimiter %q, must be a single character", s)
+	}
+	return r, nil
+}
+
+// openInput returns the file named by the first argument of fs, or stdin if there isn't one.
+func openInput(fs *flag.FlagSet, stdin io.Reader) (io.ReadCloser, error) {
+	switch fs.NArg() {
+	case 0:
+		return io.NopCloser(stdin), nil
+	case 1:
+		f, err := os.Open(fs.Arg(0))
+		return f, errors.Wrap(err, "unable to open input")
+	default:
+		return nil, fmt.Errorf("expected at most one file, got %d", fs.NArg())
+	}
+}
+
+func convert(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	var in inputFlags
+	in.register(fs)
+	outComma := fs.String("out-comma", ",", "output field delimiter (use \\t for tab)")
+	outCharset := fs.String("out-charset", "", "output character encoding, eg windows-1252 (default utf-8)")
+	crlf := fs.Bool("crlf", false, "use \\r\\n as the output line terminator")
+	bom := fs.Bool("bom", false, "write a UTF-8 byte order mark (utf-8 output only)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := openInput(fs, stdin)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	dec, err := in.decoder(r)
+	if err != nil {
+		return err
+	}
+	comma, err := parseDelimiter(*outComma)
+	if err != nil {
+		return err
+	}
+	enc := csvplus.NewEncoder(stdout).Comma(comma).UseCRLF(*crlf).WriteBOM(*bom)
+	if *outCharset != "" {
+		enc.Charset(*outCharset)
+	}
+
+	// the input may have a different number of fields per row, it's converted as is
+	dec.FieldsPerRecord(-1)
+	for {
+		record, err := dec.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	var in inputFlags
+	in.register(fs)
+	require := fs.String("require", "", "comma separated list of columns the header row must contain")
+	maxErrors := fs.Int("max-errors", 20, "stop after this many bad rows (0 for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := openInput(fs, stdin)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	dec, err := in.decoder(r)
+	if err != nil {
+		return err
+	}
+
+	header, err := dec.ReadRecord()
+	if err != nil {
+		return errors.Wrap(err, "unable to read header row")
+	}
+
+	var problems int
+	if *require != "" {
+		cols := make(map[string]bool, len(header))
+		for _, col := range header {
+			cols[col] = true
+		}
+		for _, col := range strings.Split(*require, ",") {
+			if !cols[col] {
+				fmt.Fprintf(stdout, "header: missing column %q\n", col)
+				problems++
+			}
+		}
+	}
+
+	var rows int
+	for *maxErrors == 0 || problems < *maxErrors {
+		_, err := dec.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		rows++
+		if err != nil {
+			// parse errors include the line number, anything else means the data can't be read any further
+			fmt.Fprintf(stdout, "%s\n", err)
+			problems++
+			if _, ok := err.(*csv.ParseError); !ok {
+				break
+			}
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+	fmt.Fprintf(stdout, "ok: %d columns, %d rows\n", len(header), rows)
+	return nil
+}
+
+func head(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("head", flag.ContinueOnError)
+	var in inputFlags
+	in.register(fs)
+	n := fs.Int("n", 10, "number of data rows to show")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := openInput(fs, stdin)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	dec, err := in.decoder(r)
+	if err != nil {
+		return err
+	}
+	dec.FieldsPerRecord(-1)
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for row := 0; row <= *n; row++ {
+		record, err := dec.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(tw, strings.Join(record, "\t"))
+	}
+	return tw.Flush()
+}
+
+func schema(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	name := fs.String("name", "Record", "name of the generated struct type")
+	sample := fs.Int("sample", 100, "number of data rows used to infer the field types")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := openInput(fs, stdin)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	src, err := csvplus.GenerateStruct(r, *name, *sample)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(src)
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	in := strings.NewReader("Export\nfirst;second\na;\"b,c\"\n")
+	var out bytes.Buffer
+	err := convert([]string{"-comma", ";", "-out-comma", `\t`, "-skip", "1"}, in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "first\tsecond\na\tb,c\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, out.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		var out bytes.Buffer
+		err := validate([]string{"-require", "first,second"}, strings.NewReader("first,second\na,1\nb,2\n"), &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "ok: 2 columns, 2 rows\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got: %q", expected, out.String())
+		}
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		var out bytes.Buffer
+		err := validate([]string{"-require", "first,third"}, strings.NewReader("first,second\na,1\nb\nc,3\n"), &out)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(out.String(), `missing column "third"`) {
+			t.Errorf("expected missing column in output, got: %q", out.String())
+		}
+		if !strings.Contains(out.String(), "line 3") {
+			t.Errorf("expected bad row line number in output, got: %q", out.String())
+		}
+	})
+}
+
+func TestHead(t *testing.T) {
+	var out bytes.Buffer
+	err := head([]string{"-n", "1"}, strings.NewReader("first,second\nabc,1\nd,2\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "first  second\nabc    1\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, out.String())
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var out bytes.Buffer
+	err := schema([]string{"-name", "Item"}, strings.NewReader("id,name\n1,a\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "type Item struct") {
+		t.Errorf("expected struct definition, got: %q", out.String())
+	}
+}
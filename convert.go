@@ -0,0 +1,434 @@
+package csvplus
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// trimBasePrefix removes a 0x, 0o or 0b prefix (if present) from s when it matches base, strconv only accepts these
+// prefixes when parsing with base 0.
+func trimBasePrefix(s string, base int) string {
+	var prefix string
+	switch base {
+	case 16:
+		prefix = "0x"
+	case 8:
+		prefix = "0o"
+	case 2:
+		prefix = "0b"
+	default:
+		return s
+	}
+
+	sign := ""
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	if len(s) > 2 && strings.EqualFold(s[:2], prefix) {
+		s = s[2:]
+	}
+	return sign + s
+}
+
+// Converter converts a csv value, converters are registered by name on a Decoder (applied to values before they're
+// unmarshalled) or Encoder (applied to values after they're marshalled) and selected per field with a csvplusConv
+// struct tag.
+type Converter func(string) (string, error)
+
+// applyConverters applies the converters called names (in order) to s.
+func applyConverters(converters map[string]Converter, names []string, s string) (string, error) {
+	for _, name := range names {
+		var err error
+		if s, err = converters[name](s); err != nil {
+			return "", errors.Wrapf(err, "converter %s", name)
+		}
+	}
+	return s, nil
+}
+
+// checkConverters returns an error if any of the converters used by fis aren't in converters.
+func checkConverters(converters map[string]Converter, fis []fieldInfo) error {
+	for _, fi := range fis {
+		for _, name := range fi.Converters {
+			if _, found := converters[name]; !found {
+				return errors.Errorf("unknown converter %s on field %s", name, fi.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// setChar sets the integer value f (eg a byte or rune) to the single character s.
+func setChar(f reflect.Value, s string) error {
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || (r == utf8.RuneError && size == 1) {
+		return errors.Errorf("expected a single character, got %q", s)
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f.OverflowInt(int64(r)) {
+			return errors.Errorf("character %q out of range for %s", r, f.Type())
+		}
+		f.SetInt(int64(r))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f.OverflowUint(uint64(r)) {
+			return errors.Errorf("character %q out of range for %s", r, f.Type())
+		}
+		f.SetUint(uint64(r))
+	default:
+		return errors.Errorf("char option used on %s field", f.Type())
+	}
+	return nil
+}
+
+// formatChar formats the integer value fv (eg a byte or rune) as a single character, the zero value is formatted as an
+// empty value.
+func formatChar(fv reflect.Value) (string, error) {
+	var r rune
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		r = rune(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		r = rune(fv.Uint())
+	default:
+		return "", errors.Errorf("char option used on %s field", fv.Type())
+	}
+	if r == 0 {
+		// symmetrical with unmarshalling, empty values leave the field as the zero value
+		return "", nil
+	}
+	return string(r), nil
+}
+
+// Encodings that can be used in a csvplusFormat tag on []byte fields.
+const (
+	formatBase64    = "base64"
+	formatBase64URL = "base64url"
+	formatHex       = "hex"
+)
+
+// decodeBytes decodes s using the encoding from a csvplusFormat tag, s is used as is if there's no format.
+func decodeBytes(s, format string) ([]byte, error) {
+	switch format {
+	case "":
+		return []byte(s), nil
+	case formatBase64:
+		return base64.StdEncoding.DecodeString(s)
+	case formatBase64URL:
+		return base64.URLEncoding.DecodeString(s)
+	case formatHex:
+		return hex.DecodeString(s)
+	}
+	return nil, errors.Errorf("unknown []byte format %s", format)
+}
+
+// encodeBytes encodes b using the encoding from a csvplusFormat tag, b is used as is if there's no format.
+func encodeBytes(b []byte, format string) (string, error) {
+	switch format {
+	case "":
+		return string(b), nil
+	case formatBase64:
+		return base64.StdEncoding.EncodeToString(b), nil
+	case formatBase64URL:
+		return base64.URLEncoding.EncodeToString(b), nil
+	case formatHex:
+		return hex.EncodeToString(b), nil
+	}
+	return "", errors.Errorf("unknown []byte format %s", format)
+}
+
+// formatFloat formats f using the fmt verb from a csvplusFormat tag (eg %.2f), falling back to the shortest
+// representation if there's no format. Trailing zeros (and a trailing decimal point) are removed if trimZeros is set.
+func formatFloat(f float64, format string, trimZeros bool) string {
+	if format == "" {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	s := fmt.Sprintf(format, f)
+	if trimZeros && strings.Contains(s, ".") && !strings.ContainsAny(s, "eE") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	return s
+}
+
+// parseCurrency parses a currency formatted value (eg "$1,234.50", "€9,99", "-1.234,50 EUR") into a float, the
+// currency symbol (if any) is also returned. The symbol (currency signs and letters, eg US$ or EUR) can be before or
+// after the number, letters must be an upper case currency code or be separated from the number by a space. Negative
+// values have a '-' before or after the number (and symbol) or are wrapped in parentheses.
+//
+// decimal is the decimal separator ('.' or ','), or 0 to detect it: when both '.' and ',' are present the last one is
+// the decimal separator, when only one is present it's a grouping separator if it appears more than once and a decimal
+// separator if it isn't followed by exactly 3 digits (or the integer part is 0, eg 0.125). A single separator followed
+// by 3 digits (eg 1.500) is ambiguous, it's resolved by grouping (the Decoder's grouping separators) or is an error.
+func parseCurrency(s, grouping string, decimal byte) (float64, string, error) { // nolint: gocyclo
+	v := strings.TrimSpace(s)
+	parens := strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")")
+	if parens {
+		v = strings.TrimSpace(v[1 : len(v)-1])
+	}
+
+	start := strings.IndexFunc(v, isCurrencyNumberRune)
+	if start < 0 {
+		return 0, "", errors.Errorf("no number in currency value %q", s)
+	}
+	end := strings.LastIndexFunc(v, isCurrencyNumberRune) + 1
+	prefix, n, suffix := v[:start], v[start:end], v[end:]
+
+	prefix, negPrefix := cutSign(prefix)
+	suffix, negSuffix := cutSign(suffix)
+	if (negPrefix && negSuffix) || (parens && (negPrefix || negSuffix)) {
+		return 0, "", errors.Errorf("more than one sign in currency value %q", s)
+	}
+	negative := parens || negPrefix || negSuffix
+
+	symbol := prefix + suffix
+	if prefix != "" && suffix != "" {
+		return 0, "", errors.Errorf("currency symbols before and after the number in %q", s)
+	}
+	spaced := (prefix != "" && !strings.HasSuffix(v[:start], prefix)) || (suffix != "" && !strings.HasPrefix(v[end:], suffix))
+	if symbol != "" && !isCurrencySymbol(symbol, spaced) {
+		return 0, "", errors.Errorf("invalid currency symbol %q in %q", symbol, s)
+	}
+
+	var digits strings.Builder
+	for _, r := range n {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',':
+			digits.WriteRune(r)
+		case unicode.IsSpace(r), r == '\'':
+			// grouping separators
+		default:
+			return 0, "", errors.Errorf("invalid character %q in currency value %q", r, s)
+		}
+	}
+	n = digits.String()
+
+	decimalSep, err := currencyDecimal(n, grouping, decimal)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "currency value %q", s)
+	}
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for i := 0; i < len(n); i++ {
+		switch {
+		case n[i] == decimalSep:
+			b.WriteByte('.')
+		case n[i] == '.', n[i] == ',':
+			// grouping separator
+		default:
+			b.WriteByte(n[i])
+		}
+	}
+
+	f, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return f, symbol, nil
+}
+
+// currencyDecimal returns the decimal separator of the number n (digits, '.' and ','), 0 if it doesn't have one. If
+// decimal isn't 0 it's the decimal separator and it must appear at most once, after any grouping separators.
+func currencyDecimal(n, grouping string, decimal byte) (byte, error) {
+	dot, comma := strings.LastIndex(n, "."), strings.LastIndex(n, ",")
+	if decimal != 0 {
+		group := byte(',')
+		if decimal == ',' {
+			group = '.'
+		}
+		idx := strings.IndexByte(n, decimal)
+		if idx >= 0 && (strings.Count(n, string(decimal)) > 1 || strings.LastIndexByte(n, group) > idx) {
+			return 0, errors.Errorf("misplaced decimal separator %c", decimal)
+		}
+		return decimal, nil
+	}
+
+	switch {
+	case dot >= 0 && comma >= 0:
+		if comma > dot {
+			return ',', nil
+		}
+		return '.', nil
+	case dot >= 0 || comma >= 0:
+		idx, sep := dot, byte('.')
+		if comma >= 0 {
+			idx, sep = comma, ','
+		}
+		if strings.Count(n, string(sep)) > 1 {
+			return 0, nil
+		}
+		if len(n)-idx-1 != 3 || strings.Trim(n[:idx], "0") == "" {
+			return sep, nil
+		}
+		other := byte(',')
+		if sep == ',' {
+			other = '.'
+		}
+		switch {
+		case strings.IndexByte(grouping, sep) >= 0:
+			return 0, nil
+		case strings.IndexByte(grouping, other) >= 0:
+			return sep, nil
+		}
+		return 0, errors.Errorf("%c could be a decimal or grouping separator, set a csvplusDecimal tag", sep)
+	}
+	return 0, nil
+}
+
+// isCurrencyNumberRune reports whether r can start or end the number in a currency value.
+func isCurrencyNumberRune(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '.' || r == ','
+}
+
+// cutSign removes a '-' from the start or end of s (a currency symbol and the spaces around it), it reports whether
+// there was one.
+func cutSign(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if t := strings.TrimPrefix(s, "-"); t != s {
+		return strings.TrimSpace(t), true
+	}
+	if t := strings.TrimSuffix(s, "-"); t != s {
+		return strings.TrimSpace(t), true
+	}
+	return s, false
+}
+
+// isCurrencySymbol reports whether s is a currency symbol, ie currency signs and letters (eg $, R$, kr). Symbols
+// without a currency sign must be an upper case currency code (eg EUR) unless spaced reports that they're separated
+// from the number (eg by a space).
+func isCurrencySymbol(s string, spaced bool) bool {
+	sign, upper := false, true
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Sc, r):
+			sign = true
+		case unicode.IsLetter(r):
+			upper = upper && r >= 'A' && r <= 'Z'
+		default:
+			return false
+		}
+	}
+	return sign || spaced || (upper && len(s) == 3)
+}
+
+// parsePercent parses a percentage (eg "45%") into a fraction (0.45), or into a whole number (45) if whole is set. The
+// % sign is optional.
+func parsePercent(s string, whole bool) (float64, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if whole {
+		return f, nil
+	}
+	return f / 100, nil
+}
+
+// formatPercent formats f as a percentage (eg "45%"), f is a fraction (0.45) unless whole is set.
+func formatPercent(f float64, format string, trimZeros, whole bool) string {
+	if whole {
+		return formatFloat(f, format, trimZeros) + "%"
+	}
+	if format != "" {
+		return formatFloat(f*100, format, trimZeros) + "%"
+	}
+	// shift the decimal point in the shortest representation to avoid float rounding errors (eg 0.45*100)
+	return shiftDecimal(strconv.FormatFloat(f, 'f', -1, 64), 2) + "%"
+}
+
+// shiftDecimal moves the decimal point in the decimal number s n places to the right.
+func shiftDecimal(s string, n int) string {
+	var sign string
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	for len(frac) < n {
+		frac += "0"
+	}
+	intPart = strings.TrimLeft(intPart+frac[:n], "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if frac = frac[n:]; frac != "" {
+		return sign + intPart + "." + frac
+	}
+	return sign + intPart
+}
+
+// parseBoolWord parses the yes/no, y/n and on/off bools accepted by Decoder.BoolWords, case insensitively.
+func parseBoolWord(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return false, errors.Errorf("invalid bool %q", s)
+}
+
+// Epoch formats that can be used in a csvplusFormat tag on time.Time fields, values are integer unix timestamps.
+const (
+	formatUnix      = "unix"
+	formatUnixMilli = "unixmilli"
+	formatUnixNano  = "unixnano"
+)
+
+// formatAuto is the csvplusFormat for time.Time and Date fields whose layout is detected from the values, see
+// Decoder.autoLayout.
+const formatAuto = "auto"
+
+// parseTime parses s using the time.Parse layout (or epoch format) from a csvplusFormat tag, values without time zone
+// information are in loc.
+func parseTime(s, format string, loc *time.Location) (time.Time, error) {
+	if format != formatUnix && format != formatUnixMilli && format != formatUnixNano {
+		return time.ParseInLocation(format, s, loc)
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch format {
+	case formatUnix:
+		return time.Unix(n, 0).In(loc), nil
+	case formatUnixMilli:
+		return time.UnixMilli(n).In(loc), nil
+	default:
+		return time.Unix(0, n).In(loc), nil
+	}
+}
+
+// formatTime formats t using the time.Format layout (or epoch format) from a csvplusFormat tag.
+func formatTime(t time.Time, format string) string {
+	return string(appendTime(nil, t, format))
+}
+
+// appendTime is the same as formatTime but appends the value to dst.
+func appendTime(dst []byte, t time.Time, format string) []byte {
+	switch format {
+	case formatUnix:
+		return strconv.AppendInt(dst, t.Unix(), 10)
+	case formatUnixMilli:
+		return strconv.AppendInt(dst, t.UnixMilli(), 10)
+	case formatUnixNano:
+		return strconv.AppendInt(dst, t.UnixNano(), 10)
+	}
+	return t.AppendFormat(dst, format)
+}
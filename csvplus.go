@@ -3,12 +3,16 @@
 package csvplus
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
+	"log/slog"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -19,10 +23,18 @@ import (
 // For common types (eg int, bool, float64...) a standard conversion from a string is applied. If a type implements
 // the Unmarshaler interface, that will be used to unmarshal the record instead.
 // This function assumes the csv data has a header row (which is skipped), see the Decoder type if your data doesn't
-// have a header row.
+// have a header row. Values can be validated using csvplusValidate and csvplusPattern tags, see ValidationError.
 func Unmarshal(data []byte, v interface{}) error {
-	buf := bytes.NewBuffer(data)
-	return NewDecoder(buf).Decode(v)
+	// data is read in place, it isn't copied
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// UnmarshalWithSizeHint is the same as Unmarshal but counts the lines of data first so the slice pointed to by v is
+// allocated once (see Decoder.SizeHint). It's an extra pass over data, and values with newlines make it allocate more
+// than is needed, but it avoids growing large slices as rows are decoded.
+func UnmarshalWithSizeHint(data []byte, v interface{}) error {
+	// the number of lines is an upper bound for the number of rows
+	return NewDecoder(bytes.NewReader(data)).SizeHint(bytes.Count(data, []byte{'\n'}) + 1).Decode(v)
 }
 
 // UnmarshalReader is the same as Unmarshal but takes it's input data from an io.Reader.
@@ -30,10 +42,19 @@ func UnmarshalReader(r io.Reader, v interface{}) error {
 	return NewDecoder(r).Decode(v)
 }
 
+// UnmarshalSingle parses csv data with a header row and a single data row (eg a config style export) into the struct
+// pointed to by v, any rows after the first data row are ignored.
+func UnmarshalSingle(data []byte, v interface{}) error {
+	err := NewDecoder(bytes.NewReader(data)).DecodeOneStruct(v)
+	if err == io.EOF {
+		return errors.New("no data row in csv data")
+	}
+	return err
+}
+
 // UnmarshalWithoutHeader is used to unmarshal csv data that doesn't have a header row.
 func UnmarshalWithoutHeader(data []byte, v interface{}) error {
-	buf := bytes.NewBuffer(data)
-	return NewDecoder(buf).UseHeader(false).Decode(v)
+	return NewDecoder(bytes.NewReader(data)).UseHeader(false).Decode(v)
 }
 
 // Unmarshaler is the interface implemented by types that can unmarshal a csv record of themselves.
@@ -41,23 +62,214 @@ type Unmarshaler interface {
 	UnmarshalCSV(string) error
 }
 
+// BeforeUnmarshaler is the interface implemented by row structs that need to inspect (or modify) the raw record before
+// it's unmarshalled, an error fails the row.
+type BeforeUnmarshaler interface {
+	BeforeUnmarshalCSV(record []string) error
+}
+
+// AfterUnmarshaler is the interface implemented by row structs that need to normalise or validate themselves (or set
+// derived fields) once a record has been unmarshalled, an error fails the row.
+type AfterUnmarshaler interface {
+	AfterUnmarshalCSV() error
+}
+
 // A Decoder reads and decodes CSV records from an input stream. Useful if your data doesn't have a header row.
 type Decoder struct {
-	headerPassed  bool
-	withoutHeader bool
-	csvReader     *csv.Reader
+	headerPassed       bool
+	withoutHeader      bool
+	src                *bomReader // input the csvReader created by NewDecoder reads from
+	csvReader          *csv.Reader
+	recordReader       RecordReader // used instead of csvReader if set
+	groupingSeparators string
+	location           *time.Location
+	requireColumns     bool
+	exactHeader        bool
+	detectHeader       bool
+	mapHeader          func(string) string
+	looseHeaders       bool
+	skipRows           int
+	skipFooterRows     int
+	footer             []footerRecord
+	stopAt             func(record []string) bool
+	filter             func(record []string) bool
+	filterDecoded      func(v interface{}) bool
+	dedupe             *dedupe // set by DropDuplicates
+	maxFieldBytes      int
+	maxRows            int
+	rowsRead           int // data rows counted against maxRows
+	stopped            bool
+	onError            func(row int, record []string, err error) error
+	logger             *slog.Logger // set by SetLogger
+	stats              Stats        // Bytes is read from src
+	onStats            func(s Stats)
+	inCall             bool        // a call is being measured, see startCall
+	closers            []io.Closer // closed by Close, in reverse order
+	scanRow            int         // row read by Scan
+	scanRecord         []string
+	scanErr            error
+	pos                recordPos // position of the record most recently returned by readRecord
+	sizeHint           int
+	reuseElements      bool
+	shortRow           ShortRow
+	width              int // number of fields in the header row (or first row)
+	truncateLongRows   bool
+	schema             *Schema
+	converters         map[string]Converter
+	header             []string     // header row after MapHeader has been applied
+	rowType            reflect.Type // type fis was created for
+	fis                []fieldInfo  // maps columns to the fields of rowType
+	row                int          // number of rows read, including the header row
+	index              *Index       // used by Seek
+	posBase            recordPos    // position of the start of the csvReader's input, set by Seek
+	customReader       bool         // set by SetCSVReader
+	skipUnmapped       bool
+	strictEmpty        bool
+	nilValue           *string // set by NilValue
+	boolWords          bool
+	duplicateHeaders   DuplicateHeaders
+	project            *projectReader // used instead of csvReader if skipUnmapped is set
+	backslashEscapes   bool
+	escape             *EscapeReader // used instead of csvReader if backslashEscapes is set
+	needed             []bool        // columns mapped to fields, nil until the header row has been mapped
+	inferRows          int
+	sample             []footerRecord   // rows read by InferTypes, returned before any more are read
+	autoLayouts        map[int][]string // candidate layouts of csvplusFormat:"auto" columns, by column index
+	tags               tagSource
+	err                error // set by options that fail, returned by Decode
+}
+
+// recordPos is the position of a record in the input.
+type recordPos struct {
+	line   int
+	offset int64
+}
+
+// footerRecord is a record (and any error from reading it) buffered until it's known it's not a footer row.
+type footerRecord struct {
+	record []string
+	err    error
+	pos    recordPos
 }
 
-// NewDecoder reads and decodes CSV records from r.
+// NewDecoder reads and decodes CSV records from r. A UTF-8 byte order mark at the start of r is ignored, if r starts
+// with a UTF-16 byte order mark it's transcoded to UTF-8.
 func NewDecoder(r io.Reader) *Decoder {
+	src := newBOMReader(r)
+	csvReader := csv.NewReader(src)
+	// records are only used until the next one is read
+	csvReader.ReuseRecord = true
 	return &Decoder{
-		csvReader: csv.NewReader(r),
+		src:       src,
+		csvReader: csvReader,
 	}
 }
 
+// RecordReader is the interface implemented by sources of records, it allows data that isn't csv (eg a sheet in an
+// xlsx workbook) to be decoded into structs using the same struct tags. Read returns io.EOF when there are no more
+// records. *csv.Reader implements RecordReader.
+type RecordReader interface {
+	Read() (record []string, err error)
+}
+
+// NewRecordDecoder reads and decodes records from rr. Options that configure the csv reader (eg Comma, Charset) have no
+// effect.
+func NewRecordDecoder(rr RecordReader) *Decoder {
+	dec := NewDecoder(strings.NewReader(""))
+	dec.recordReader = rr
+	return dec
+}
+
 // SetCSVReader allows for using a custom csv.Reader (eg | field separator instead of ,).
 func (dec *Decoder) SetCSVReader(r *csv.Reader) *Decoder {
 	dec.csvReader = r
+	dec.recordReader = nil
+	dec.customReader = true
+	return dec
+}
+
+// Comma sets the field delimiter of the underlying csv.Reader (eg ';' or '\t').
+func (dec *Decoder) Comma(r rune) *Decoder {
+	dec.csvReader.Comma = r
+	return dec
+}
+
+// Comment sets the comment character of the underlying csv.Reader, lines beginning with it are ignored.
+func (dec *Decoder) Comment(r rune) *Decoder {
+	dec.csvReader.Comment = r
+	return dec
+}
+
+// TrimLeadingSpace sets whether leading white space in a field is ignored by the underlying csv.Reader.
+func (dec *Decoder) TrimLeadingSpace(b bool) *Decoder {
+	dec.csvReader.TrimLeadingSpace = b
+	return dec
+}
+
+// FieldsPerRecord sets the number of expected fields per record of the underlying csv.Reader, see
+// csv.Reader.FieldsPerRecord.
+func (dec *Decoder) FieldsPerRecord(n int) *Decoder {
+	dec.csvReader.FieldsPerRecord = n
+	return dec
+}
+
+// AllowLazyQuotes sets whether the underlying csv.Reader accepts quotes in unquoted fields and non doubled quotes in
+// quoted fields, see csv.Reader.LazyQuotes.
+func (dec *Decoder) AllowLazyQuotes(b bool) *Decoder {
+	dec.csvReader.LazyQuotes = b
+	return dec
+}
+
+// PadShortRows sets whether rows with fewer fields than the header row (or first row) are padded with empty values
+// rather than causing an error, it's the same as OnShortRow(ShortRowPad) or OnShortRow(ShortRowError).
+func (dec *Decoder) PadShortRows(b bool) *Decoder {
+	dec.shortRow = ShortRowError
+	if b {
+		dec.shortRow = ShortRowPad
+	}
+	return dec
+}
+
+// ShortRow is the policy for rows with fewer fields than the header row (or first row).
+type ShortRow int
+
+const (
+	// ShortRowError makes short rows an error wrapping ErrShortRow, it's the default. The error is passed to the
+	// OnError function so short rows can still be skipped individually.
+	ShortRowError ShortRow = iota
+	// ShortRowPad pads short rows with empty values.
+	ShortRowPad
+	// ShortRowSkip discards short rows.
+	ShortRowSkip
+)
+
+// OnShortRow sets the policy for rows with fewer fields than the header row (or first row). It also applies when
+// FieldsPerRecord is negative, short rows are then only an error if a field's column is missing.
+func (dec *Decoder) OnShortRow(p ShortRow) *Decoder {
+	dec.shortRow = p
+	return dec
+}
+
+// fitShortRow applies the ShortRow policy to record (at line in the input), false is returned if it's skipped.
+func (dec *Decoder) fitShortRow(record []string, width, line int) ([]string, bool) {
+	switch {
+	case len(record) >= width:
+	case dec.shortRow == ShortRowPad:
+		dec.debug("csvplus: short row padded with empty values", "line", line, "fields", len(record), "columns", width)
+		for len(record) < width {
+			record = append(record, "")
+		}
+	case dec.shortRow == ShortRowSkip:
+		dec.debug("csvplus: short row skipped", "line", line, "fields", len(record), "columns", width)
+		return nil, false
+	}
+	return record, true
+}
+
+// TruncateLongRows sets whether rows with more fields than the header row (or first row) have the extra fields
+// discarded rather than causing an error.
+func (dec *Decoder) TruncateLongRows(b bool) *Decoder {
+	dec.truncateLongRows = b
 	return dec
 }
 
@@ -67,148 +279,1053 @@ func (dec *Decoder) UseHeader(b bool) *Decoder {
 	return dec
 }
 
-// Decode reads reads csv recorder into v.
+// GroupingSeparators sets characters (eg "," or " ") that are stripped from number records before they are parsed, so
+// values such as "1,234,567" can be unmarshalled into int/uint/float fields.
+func (dec *Decoder) GroupingSeparators(seps string) *Decoder {
+	dec.groupingSeparators = seps
+	return dec
+}
+
+// stripGrouping removes any grouping separators set via GroupingSeparators from s.
+func (dec *Decoder) stripGrouping(s string) string {
+	if dec.groupingSeparators == "" {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(dec.groupingSeparators, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Location sets the location used to parse time.Time fields without time zone information, fields with a csvplusTZ tag
+// use the location in the tag instead. Defaults to UTC.
+func (dec *Decoder) Location(loc *time.Location) *Decoder {
+	dec.location = loc
+	return dec
+}
+
+// RequireColumns sets whether Decode should fail if a field with a csvplus tag doesn't have a matching column in the
+// header row, by default such fields are left as zero values.
+func (dec *Decoder) RequireColumns(b bool) *Decoder {
+	dec.requireColumns = b
+	return dec
+}
+
+// MapHeader sets a function that's applied to each column name in the header row before it's matched against struct
+// fields and tags, use it to normalise headers (eg trim spaces, lowercase).
+func (dec *Decoder) MapHeader(fn func(string) string) *Decoder {
+	dec.mapHeader = fn
+	return dec
+}
+
+// LooseHeaderMatching sets whether header columns are matched to struct fields and tags case insensitively, ignoring
+// spaces, underscores and hyphens (eg "Created At" and "created_at" both match a CreatedAt field).
+func (dec *Decoder) LooseHeaderMatching(b bool) *Decoder {
+	dec.looseHeaders = b
+	return dec
+}
+
+// TagName sets the struct tag that column names (and options) are read from, eg csv so structs tagged for
+// gocarina/gocsv or jszwec/csvutil can be decoded without renaming their tags. Fields without the tag fall back to
+// their csvplus tag, other tags (eg csvplusFormat) are unaffected.
+func (dec *Decoder) TagName(name string) *Decoder {
+	dec.tags.name = name
+	return dec
+}
+
+// JSONTags sets whether fields without a csvplus tag (or TagName tag) are matched to columns using the name in their
+// json tag, so structs whose json tags match the header row don't need csvplus tags too. Fields with a json:"-" tag
+// are skipped.
+func (dec *Decoder) JSONTags(b bool) *Decoder {
+	dec.tags.json = b
+	return dec
+}
+
+// Converter registers fn as the converter called name, fields with a csvplusConv:"name" tag have their values passed
+// through fn before they're unmarshalled (eg to upper case or reformat values).
+func (dec *Decoder) Converter(name string, fn Converter) *Decoder {
+	if dec.converters == nil {
+		dec.converters = make(map[string]Converter)
+	}
+	dec.converters[name] = fn
+	return dec
+}
+
+// SkipUnmappedColumns sets whether the values of columns that aren't mapped to fields are skipped rather than copied
+// out of the input, it speeds up decoding a few columns of wide csv data. The records passed to StopAt, Filter, OnError
+// and BeforeUnmarshalCSV have empty values for unmapped columns. Has no effect if SetCSVReader is used.
+func (dec *Decoder) SkipUnmappedColumns(b bool) *Decoder {
+	dec.skipUnmapped = b
+	return dec
+}
+
+// NilValue sets a value (eg NULL or \N) that's decoded the same as an empty value, so pointer fields are set to nil
+// (and the defaults of a Schema are used). It's compared before converters are applied.
+func (dec *Decoder) NilValue(s string) *Decoder {
+	dec.nilValue = &s
+	return dec
+}
+
+// StrictEmpty sets whether empty values are an error (wrapping ErrEmptyValue) for fields that aren't pointers, strings
+// or slices (eg []byte), rather than leaving the field set to its zero value which can't be told apart from a real 0 or false.
+// Fields can be made strict individually with a strictempty tag option, eg `csvplus:"age,strictempty"`.
+func (dec *Decoder) StrictEmpty(b bool) *Decoder {
+	dec.strictEmpty = b
+	return dec
+}
+
+// BoolWords sets whether bool fields also accept yes/no, y/n and on/off (in any case) as well as the values accepted
+// by strconv.ParseBool (eg true, false, 1 and 0).
+func (dec *Decoder) BoolWords(b bool) *Decoder {
+	dec.boolWords = b
+	return dec
+}
+
+// SkipRows sets the number of rows to discard before the header row (or first data row if there's no header), eg
+// titles or export metadata. Blank lines are ignored by the csv reader so don't need to be counted.
+func (dec *Decoder) SkipRows(n int) *Decoder {
+	dec.skipRows = n
+	return dec
+}
+
+// skipPreamble discards the rows set by SkipRows, they're allowed to have any number of fields.
+func (dec *Decoder) skipPreamble() error {
+	fieldsPerRecord := dec.csvReader.FieldsPerRecord
+	dec.csvReader.FieldsPerRecord = -1
+	defer func() {
+		dec.csvReader.FieldsPerRecord = fieldsPerRecord
+	}()
+
+	for ; dec.skipRows > 0; dec.skipRows-- {
+		if _, _, err := dec.read(); err != nil && err != io.EOF {
+			return errors.Wrap(err, "error skipping rows")
+		}
+	}
+	return nil
+}
+
+// SkipFooterRows sets the number of rows at the end of the data to discard, eg totals or summary rows. Footer rows can
+// have a different number of fields to the data rows.
+func (dec *Decoder) SkipFooterRows(n int) *Decoder {
+	dec.skipFooterRows = n
+	return dec
+}
+
+// StopAt sets a function that's called with each data row, decoding stops (without error) at the first row fn returns
+// true for, eg a row where the first field is TOTAL. The row fn returns true for can have a different number of fields
+// to the data rows. record is reused for the next row so must not be retained.
+func (dec *Decoder) StopAt(fn func(record []string) bool) *Decoder {
+	dec.stopAt = fn
+	return dec
+}
+
+// Filter sets a function that's called with each data row before it's unmarshalled, rows fn returns false for are
+// skipped without being converted. record is reused for the next row so must not be retained.
+func (dec *Decoder) Filter(fn func(record []string) bool) *Decoder {
+	dec.filter = fn
+	return dec
+}
+
+// FilterDecoded sets a function that's called with a pointer to each row after it's been unmarshalled (eg *Item, or
+// *map[string]interface{} with a Schema), rows fn returns false for are dropped. Use Filter if the decision can be made
+// from the raw values as it avoids the conversion.
+func (dec *Decoder) FilterDecoded(fn func(v interface{}) bool) *Decoder {
+	dec.filterDecoded = fn
+	return dec
+}
+
+// OnError sets a function that's called when a row can't be read or unmarshalled, if fn returns nil the row is skipped
+// and decoding continues, otherwise decoding stops and the error fn returns is returned by Decode. Use it to log or
+// collect bad rows, record is reused for the next row so must be copied if it's retained.
+func (dec *Decoder) OnError(fn func(row int, record []string, err error) error) *Decoder {
+	dec.onError = fn
+	return dec
+}
+
+// handleError passes err to the OnError function (if set), a nil return value means the row should be skipped.
+func (dec *Decoder) handleError(row int, record []string, err error) error {
+	if dec.onError == nil {
+		return err
+	}
+	if err := dec.onError(row, record, err); err != nil {
+		return err
+	}
+	dec.stats.Rejected++
+	dec.debug("csvplus: row skipped after error", "row", row, "code", ErrorCodeOf(err), "error", err)
+	return nil
+}
+
+// readRecord reads the next record from the csv reader, data rows are checked against the StopAt function and held
+// back until it's known they aren't footer rows. io.EOF is returned when there are no more records.
+func (dec *Decoder) readRecord() ([]string, error) {
+	if dec.stopped {
+		return nil, io.EOF
+	}
+
+	for {
+		record, pos, err := dec.read()
+		dec.pos = pos
+		if err == io.EOF {
+			// anything still buffered is the footer
+			dec.footer = nil
+			return nil, err
+		}
+		if !dec.headerPassed && !dec.withoutHeader {
+			return record, err
+		}
+
+		if dec.stopAt != nil && record != nil && dec.stopAt(record) {
+			dec.stopped = true
+			dec.footer = nil
+			return nil, io.EOF
+		}
+
+		if dec.skipFooterRows == 0 {
+			return record, err
+		}
+		if err != nil && !isFieldCountError(err) {
+			return nil, err
+		}
+
+		if dec.recordReader != nil || dec.csvReader.ReuseRecord {
+			record = append([]string(nil), record...)
+		}
+		dec.footer = append(dec.footer, footerRecord{record: record, err: err, pos: pos})
+		if len(dec.footer) > dec.skipFooterRows {
+			fr := dec.footer[0]
+			copy(dec.footer, dec.footer[1:])
+			dec.footer = dec.footer[:len(dec.footer)-1]
+			dec.pos = fr.pos
+			return fr.record, fr.err
+		}
+	}
+}
+
+// read reads the next record from the RecordReader or csv reader, the position of the record in the input is only
+// known for csv data.
+func (dec *Decoder) read() ([]string, recordPos, error) {
+	for {
+		record, pos, err := dec.readNext()
+		if isFieldCountError(err) {
+			want := dec.csvReader.FieldsPerRecord
+			if len(record) < want {
+				var ok bool
+				if record, ok = dec.fitShortRow(record, want, pos.line); !ok {
+					continue
+				}
+				if len(record) == want {
+					err = nil
+				}
+			} else if dec.truncateLongRows {
+				record, err = record[:want], nil
+			}
+		}
+		if err == nil {
+			err = dec.checkFieldSizes(record)
+		}
+		return record, pos, err
+	}
+}
+
+// readNext reads the next record from the RecordReader or csv reader.
+func (dec *Decoder) readNext() ([]string, recordPos, error) {
+	if dec.recordReader != nil {
+		record, err := dec.recordReader.Read()
+		return record, recordPos{}, err
+	}
+
+	pos := recordPos{offset: dec.inputOffset()}
+	dec.setReadLimit()
+	var record []string
+	var err error
+	if dec.backslashEscapes && !dec.customReader {
+		record, pos.line, err = dec.readEscaped()
+	} else if dec.skipUnmapped && !dec.customReader {
+		if dec.project == nil {
+			dec.project = newProjectReader(dec.src, dec.csvReader)
+		}
+		record, pos.line, err = dec.project.Read(dec.needed)
+	} else {
+		record, err = dec.csvReader.Read()
+		if len(record) > 0 {
+			pos.line, _ = dec.csvReader.FieldPos(0)
+		}
+	}
+	if len(record) > 0 {
+		pos.line += dec.posBase.line
+	}
+	return record, pos, err
+}
+
+// inputOffset returns the byte offset in the input of the next record.
+func (dec *Decoder) inputOffset() int64 {
+	if dec.escape != nil {
+		return dec.posBase.offset + dec.escape.offset
+	}
+	if dec.project != nil {
+		return dec.posBase.offset + dec.project.offset
+	}
+	return dec.posBase.offset + dec.csvReader.InputOffset()
+}
+
+// isFieldCountError reports whether err is from a record with the wrong number of fields.
+func isFieldCountError(err error) bool {
+	pe, ok := err.(*csv.ParseError)
+	return ok && pe.Err == csv.ErrFieldCount
+}
+
+// ReadRecord reads the next raw record, the header row (if there is one) is returned by the first call. SkipRows,
+// SkipFooterRows and StopAt are applied as they are by Decode, io.EOF is returned when there are no more records.
+// ReadRecord and Decode shouldn't both be used with the same Decoder. The record is reused by the next call so must be
+// copied if it's retained.
+func (dec *Decoder) ReadRecord() ([]string, error) {
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	c := dec.startCall()
+	defer dec.endCall(c)
+	if !dec.headerPassed && dec.skipRows > 0 {
+		if err := dec.skipPreamble(); err != nil {
+			return nil, err
+		}
+	}
+
+	record, err := dec.readRecord()
+	if err != io.EOF {
+		dec.row++
+	}
+	if err == nil {
+		if dec.headerPassed || dec.withoutHeader {
+			dec.stats.Rows++
+		}
+		if !dec.headerPassed {
+			if !dec.withoutHeader {
+				dec.setHeader(record)
+			}
+			dec.width = len(record)
+		}
+		dec.headerPassed = true
+	}
+	return record, err
+}
+
+// SizeHint sets the expected number of rows, the slice passed to Decode is allocated with enough capacity for n more
+// rows up front rather than growing as rows are decoded.
+func (dec *Decoder) SizeHint(n int) *Decoder {
+	dec.sizeHint = n
+	return dec
+}
+
+// ReuseElements sets whether Decode overwrites the slice v points to rather than appending to it, its backing array is
+// reused along with the structs (or maps) its elements point to, so repeated decodes into the same slice (eg in a hot
+// loop) stop allocating rows once it's large enough. Fields are reset to their zero values before a row is decoded into
+// them, references to rows from a previous decode mustn't be retained. DecodeBatch always truncates the slice, with
+// ReuseElements the rows pointed to are reused too.
+func (dec *Decoder) ReuseElements(b bool) *Decoder {
+	dec.reuseElements = b
+	return dec
+}
+
+// Decode reads csv records into v, a pointer to a slice of structs (or pointers to structs).
 func (dec *Decoder) Decode(v interface{}) error {
+	_, err := dec.decode(v, -1)
+	return err
+}
+
+// DecodeBatch reads up to n csv records into v, a pointer to a slice of structs (or pointers to structs), the slice is
+// truncated first so its backing array is reused by each call. It allows large inputs to be processed in bounded
+// memory (eg n rows per database transaction), io.EOF is returned when there are no more rows.
+func (dec *Decoder) DecodeBatch(n int, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Slice {
+		rv.Elem().SetLen(0)
+	}
+	decoded, err := dec.decode(v, n)
+	if err == nil && decoded == 0 {
+		return io.EOF
+	}
+	return err
+}
+
+// decode appends up to limit (or all if limit is negative) decoded rows to the slice pointed to by v and returns the
+// number appended.
+func (dec *Decoder) decode(v interface{}, limit int) (int, error) {
+	if dec.err != nil {
+		return 0, dec.err
+	}
+	c := dec.startCall()
+	defer dec.endCall(c)
+
 	rv := reflect.ValueOf(v)
 	rt := rv.Type()
 	if rv.Kind() != reflect.Ptr {
-		return fmt.Errorf("non pointer %s", rt)
+		return 0, fmt.Errorf("non pointer %s: %w", rt, ErrNotSlicePtr)
 	}
 	if rv.Elem().Kind() != reflect.Slice {
-		return fmt.Errorf("expected slice to store data in, got %s", rv.Elem().Type())
+		return 0, fmt.Errorf("expected slice to store data in, got %s: %w", rv.Elem().Type(), ErrNotSlicePtr)
 	}
 
 	containerValue := rv.Elem()
-	structType := rt.Elem().Elem()
-	var fis []fieldInfo
+	if dec.reuseElements {
+		containerValue.SetLen(0)
+	}
+	elemType := rt.Elem().Elem()
+	// rowType is the type each row is unmarshalled into, elemType is a pointer to it for slices of pointers
+	rowType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		rowType = elemType.Elem()
+	}
+	if rowType == mapType && dec.schema == nil && dec.inferRows > 0 {
+		if err := dec.inferSchema(); err != nil {
+			return 0, err
+		}
+	}
+	if err := checkElemType(rowType, dec.schema); err != nil {
+		return 0, err
+	}
+	if dec.sizeHint > containerValue.Cap()-containerValue.Len() {
+		growSlice(containerValue, containerValue.Len()+dec.sizeHint)
+	}
+	dec.sizeHint = 0
 
-	var row int
-	for {
-		record, err := dec.csvReader.Read()
+	start := containerValue.Len()
+	var invalid ValidationError
+	for limit < 0 || containerValue.Len()-start < limit {
+		row, record, err := dec.nextRecord(rowType)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return errors.Wrap(err, "error reading csv reader")
+			return containerValue.Len() - start, err
+		}
+
+		// unmarshal directly into a new element at the end of the slice to avoid allocating a struct per row, the slice
+		// is grown in chunks rather than appending (and maybe copying) it for each row
+		n := containerValue.Len()
+		if n == containerValue.Cap() {
+			growSlice(containerValue, 2*n+16)
+		}
+		containerValue.SetLen(n + 1)
+		elem := containerValue.Index(n)
+		if elemType.Kind() == reflect.Ptr {
+			if !dec.reuseElements || elem.IsNil() {
+				elem.Set(reflect.New(rowType))
+			} else if rowType != mapType {
+				elem.Elem().Set(reflect.Zero(rowType))
+			}
+			elem = elem.Elem()
+		} else if !dec.reuseElements || rowType != mapType {
+			// the element can be left over from a previous decode or a row that was removed, reused maps are cleared
+			// by unmarshalRow
+			elem.Set(reflect.Zero(elemType))
+		}
+
+		if err := dec.unmarshalRow(row, record, elem); err != nil {
+			containerValue.SetLen(n)
+			if ve, ok := err.(ValidationError); ok && dec.onError == nil {
+				// keep going so every invalid value is reported
+				invalid.Violations = append(invalid.Violations, ve.Violations...)
+				dec.stats.Rejected++
+				dec.debug("csvplus: row failed validation", "row", row, "violations", len(ve.Violations))
+				continue
+			}
+			if err = dec.handleError(row, record, err); err != nil {
+				return containerValue.Len() - start, err
+			}
+		} else if dec.filterDecoded != nil && !dec.filterDecoded(elem.Addr().Interface()) {
+			containerValue.SetLen(n)
+		} else {
+			dec.stats.Rows++
+		}
+	}
+
+	if len(invalid.Violations) > 0 {
+		return containerValue.Len() - start, invalid
+	}
+	return containerValue.Len() - start, nil
+}
+
+// growSlice replaces the backing array of the slice s with one with capacity c, keeping the elements.
+func growSlice(s reflect.Value, c int) {
+	grown := reflect.MakeSlice(s.Type(), s.Len(), c)
+	reflect.Copy(grown, s)
+	s.Set(grown)
+}
+
+// DecodeOneStruct reads the next data row (after the header row, if there is one) into the struct pointed to by v,
+// io.EOF is returned when there are no more rows. Fields without a column in the csv data are set to their zero values.
+// Rows that can't be read or unmarshalled are passed to the OnError function, if they're skipped the next row is
+// decoded instead.
+func (dec *Decoder) DecodeOneStruct(v interface{}) error {
+	if dec.err != nil {
+		return dec.err
+	}
+	c := dec.startCall()
+	defer dec.endCall(c)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("non pointer or nil %T: %w", v, ErrNotStructPtr)
+	}
+	rowType := rv.Type().Elem()
+	if err := checkElemType(rowType, dec.schema); err != nil {
+		return fmt.Errorf("%s: %w", err, ErrNotStructPtr)
+	}
+
+	for {
+		row, record, err := dec.nextRecord(rowType)
+		if err != nil {
+			return err
+		}
+
+		rv.Elem().Set(reflect.Zero(rowType))
+		err = dec.unmarshalRow(row, record, rv.Elem())
+		if err == nil {
+			if dec.filterDecoded != nil && !dec.filterDecoded(v) {
+				continue
+			}
+			dec.stats.Rows++
+			return nil
+		}
+		if err = dec.handleError(row, record, err); err != nil {
+			return err
+		}
+	}
+}
+
+// nextRecord reads the next data row and returns it with its row number, the header row (or first row if there's no
+// header) is used to map columns to the fields of rowType (unless it's nil, then mapColumns must be called before the
+// row is unmarshalled). Rows that can't be read are passed to the OnError function, io.EOF is returned when there are
+// no more rows.
+func (dec *Decoder) nextRecord(rowType reflect.Type) (int, []string, error) {
+	if !dec.headerPassed && dec.skipRows > 0 {
+		if err := dec.skipPreamble(); err != nil {
+			return 0, nil, err
+		}
+	}
+	if dec.headerPassed && rowType != nil && dec.rowType != rowType {
+		// decoding into a different type to last time
+		if err := dec.mapColumns(rowType, dec.header); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for {
+		record, err := dec.readSample()
+		if err == io.EOF {
+			return 0, nil, err
+		}
+		row := dec.row
+		dec.row++
+
+		if err != nil {
+			_, isParseErr := err.(*csv.ParseError)
+			var kind error
+			if isFieldCountError(err) && len(record) < dec.csvReader.FieldsPerRecord {
+				kind = ErrShortRow
+			}
+			if le, ok := err.(limitError); ok {
+				kind = le.kind
+			}
+			code := codeOf(kind)
+			if code == "" && isParseErr {
+				code = CodeParse
+			}
+			err = UnmarshalError{
+				Row:    row,
+				Line:   dec.pos.line,
+				Offset: dec.pos.offset,
+				RawErr: errors.Wrap(err, "error reading csv reader"),
+				Code:   code,
+				kind:   kind,
+			}
+			if !isParseErr || !dec.headerPassed {
+				return 0, nil, err
+			}
+			// the csv reader can carry on after a parse error so the row can be skipped
+			if err = dec.handleError(row, record, err); err != nil {
+				return 0, nil, err
+			}
+			continue
 		}
 
 		if !dec.headerPassed {
-			fis = getFieldInfo(structType, dec.withoutHeader, record)
-			dec.headerPassed = true
+			if dec.detectHeader && rowType != nil {
+				dec.withoutHeader = !dec.isHeader(rowType, record)
+				dec.debug("csvplus: header row detected", "header", !dec.withoutHeader)
+			}
+			header := record
+			if !dec.withoutHeader {
+				header = dec.setHeader(record)
+			}
+			if rowType != nil {
+				if err := dec.mapColumns(rowType, header); err != nil {
+					return 0, nil, err
+				}
+			}
+			dec.headerPassed, dec.width = true, len(header)
 			if !dec.withoutHeader {
-				row++
 				continue
 			}
 		}
+		if err := dec.countRow(); err != nil {
+			return 0, nil, UnmarshalError{Row: row, Line: dec.pos.line, Offset: dec.pos.offset, RawErr: err,
+				Code: CodeTooManyRows, kind: ErrTooManyRows}
+		}
 
-		structPZeroValue := reflect.New(structType)
+		var ok bool
+		if record, ok = dec.fitShortRow(record, dec.width, dec.pos.line); !ok {
+			continue
+		}
 
-		if err := dec.unmarshalRecord(row, record, structPZeroValue.Interface(), fis); err != nil {
-			return err
+		if dec.filter != nil && !dec.filter(record) {
+			continue
 		}
+		if dec.dedupe != nil {
+			dup, err := dec.dedupe.isDuplicate(record, dec.header)
+			if err != nil {
+				return 0, nil, err
+			}
+			if dup {
+				continue
+			}
+		}
+		return row, record, nil
+	}
+}
+
+// setHeader stores a copy of the header row (the record is reused by the csv reader) with MapHeader applied.
+func (dec *Decoder) setHeader(record []string) []string {
+	header := make([]string, len(record))
+	for i, colName := range record {
+		if dec.mapHeader != nil {
+			colName = dec.mapHeader(colName)
+		}
+		header[i] = colName
+	}
+	if dec.duplicateHeaders == DuplicateHeadersSuffix {
+		suffixDuplicates(header)
+	}
+	dec.header = header
+	return header
+}
+
+// Header returns the header row (after MapHeader and the DuplicateHeaders policy have been applied), it's nil until the
+// header row has been read by Decode, DecodeOneStruct or ReadRecord, and if there's no header row.
+func (dec *Decoder) Header() []string {
+	return dec.header
+}
 
-		containerValue.Set(reflect.Append(containerValue, structPZeroValue.Elem()))
-		row++
+// FieldMap returns how the columns of the csv data were mapped to the fields of the struct most recently decoded into,
+// keyed by column name (or column index if there's no header row) with the field name as the value. Columns that
+// aren't mapped to a field aren't included. It's nil until the first row has been decoded.
+func (dec *Decoder) FieldMap() map[string]string {
+	if dec.rowType == nil {
+		return nil
+	}
+	m := make(map[string]string, len(dec.fis))
+	for _, fi := range dec.fis {
+		if fi.SkipField || fi.ColName == "" {
+			continue
+		}
+		for k := 0; k < fi.columns(); k++ {
+			col := strconv.Itoa(fi.ColIndex + k)
+			if fi.ColIndex+k < len(dec.header) {
+				col = dec.header[fi.ColIndex+k]
+			}
+			m[col] = fi.Name
+		}
 	}
+	return m
+}
 
+// mapColumns maps the columns in the header row (or first row if there's no header) to the fields of rowType.
+func (dec *Decoder) mapColumns(rowType reflect.Type, header []string) error {
+	mo := matchOptions{
+		withoutHeader: dec.withoutHeader,
+		loose:         dec.looseHeaders,
+		firstDup:      dec.duplicateHeaders == DuplicateHeadersFirst,
+		tags:          dec.tags,
+	}
+	if dec.duplicateHeaders == DuplicateHeadersError && !dec.withoutHeader {
+		if dups := duplicateColumns(header); len(dups) > 0 {
+			return fmt.Errorf("%w: %s", ErrDuplicateColumn, strings.Join(dups, ", "))
+		}
+	}
+	if dec.exactHeader && !dec.withoutHeader {
+		if err := dec.checkExactHeader(rowType, header); err != nil {
+			return err
+		}
+	}
+	var fis []fieldInfo
+	var missing []string
+	var err error
+	if dec.schema != nil {
+		fis, err = dec.schema.fieldInfo(rowType, header, mo)
+		missing = dec.schema.missingColumns(fis, dec.requireColumns)
+	} else {
+		fis, err = defaultDecRegister.GetFieldInfo(rowType, header, mo)
+		if dec.requireColumns && !dec.withoutHeader {
+			missing = missingColumns(rowType, fis, dec.tags)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err = checkConverters(dec.converters, fis); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingColumn, strings.Join(missing, ", "))
+	}
+	dec.fis, dec.rowType, dec.autoLayouts = fis, rowType, nil
+	if dec.skipUnmapped {
+		dec.needed = neededColumns(fis)
+	}
+	dec.logMapping(rowType, header)
 	return nil
 }
 
-// unmarshalRecord sets the values from a single CSV record to the (exported) fields of the struct v.
-func (dec *Decoder) unmarshalRecord(row int, record []string, v interface{}, fis []fieldInfo) error { // nolint: gocyclo
-	rv := reflect.ValueOf(v)
-	s := rv.Elem()
+// neededColumns returns which columns are mapped to the fields in fis.
+func neededColumns(fis []fieldInfo) []bool {
+	needed := make([]bool, 0)
+	for _, fi := range fis {
+		if fi.SkipField || fi.ColName == "" {
+			continue
+		}
+		for k := 0; k < fi.columns(); k++ {
+			for len(needed) <= fi.ColIndex+k {
+				needed = append(needed, false)
+			}
+			needed[fi.ColIndex+k] = true
+		}
+	}
+	return needed
+}
+
+// unmarshalRow unmarshals record into the (addressable) struct or map s, the position of the record is added to
+// unmarshal errors.
+func (dec *Decoder) unmarshalRow(row int, record []string, s reflect.Value) error {
+	if s.Type() == mapType {
+		if dec.reuseElements && !s.IsNil() {
+			for _, k := range s.MapKeys() {
+				s.SetMapIndex(k, reflect.Value{})
+			}
+		} else {
+			s.Set(reflect.MakeMapWithSize(mapType, len(dec.fis)))
+		}
+	}
+	err := dec.unmarshalRecord(row, record, s, dec.fis)
+	switch e := err.(type) {
+	case UnmarshalError:
+		e.Line, e.Offset = dec.pos.line, dec.pos.offset
+		return e
+	case ValidationError:
+		for i := range e.Violations {
+			e.Violations[i].Line, e.Violations[i].Offset = dec.pos.line, dec.pos.offset
+		}
+	}
+	return err
+}
 
+// unmarshalRecord sets the values from a single CSV record to the (exported) fields of the (addressable) struct s, or
+// the keys of the map s if a schema is used.
+func (dec *Decoder) unmarshalRecord(row int, record []string, s reflect.Value, fis []fieldInfo) error { // nolint: gocyclo
+	var hooks interface{}
+	if s.Kind() == reflect.Struct {
+		hooks = s.Addr().Interface()
+	}
+	if bu, ok := hooks.(BeforeUnmarshaler); ok {
+		if err := bu.BeforeUnmarshalCSV(record); err != nil {
+			return UnmarshalError{Row: row, RawErr: errors.Wrap(err, "BeforeUnmarshalCSV()")}
+		}
+	}
+
+	var violations []UnmarshalError
 	for _, fi := range fis {
 		if fi.SkipField || fi.ColName == "" {
 			continue
 		}
 
-		if (len(record) - 1) < fi.ColIndex {
-			return errors.Errorf("not enough columns in csv data (row %d)", row)
+		if (len(record) - 1) < fi.ColIndex+fi.columns()-1 {
+			return newUnmarshalError(fi, row, "", errors.New("not enough columns in csv data"), ErrShortRow)
+		}
+
+		if fi.ArrayLen > 0 && fi.ArrayDelim == "" {
+			// array elements are in consecutive columns
+			f := s.Field(fi.FieldIndex)
+			for k := 0; k < fi.ArrayLen; k++ {
+				recVal, err := dec.cellValue(row, fi, record[fi.ColIndex+k])
+				if err != nil {
+					return err
+				}
+				if err := checkRules(fi.Rules, recVal); err != nil {
+					violations = append(violations, newUnmarshalError(fi, row, recVal, err, ErrValidation))
+					continue
+				}
+				if err := dec.unmarshalField(row, recVal, f.Index(k), s, fi); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		recVal, err := dec.cellValue(row, fi, record[fi.ColIndex])
+		if err != nil {
+			return err
+		}
+		if err := checkRules(fi.Rules, recVal); err != nil {
+			violations = append(violations, newUnmarshalError(fi, row, recVal, err, ErrValidation))
+			continue
+		}
+
+		if fi.ArrayLen > 0 {
+			if err := dec.unmarshalDelimited(row, recVal, s.Field(fi.FieldIndex), s, fi); err != nil {
+				return err
+			}
+			continue
+		}
+		if s.Kind() == reflect.Map {
+			f := reflect.New(fi.Type).Elem()
+			if err := dec.unmarshalField(row, recVal, f, reflect.Value{}, fi); err != nil {
+				return err
+			}
+			s.SetMapIndex(reflect.ValueOf(fi.ColName), f)
+			continue
+		}
+		if err := dec.unmarshalField(row, recVal, s.Field(fi.FieldIndex), s, fi); err != nil {
+			return err
+		}
+	}
+
+	if len(violations) > 0 {
+		return ValidationError{Violations: violations}
+	}
+	if au, ok := hooks.(AfterUnmarshaler); ok {
+		if err := au.AfterUnmarshalCSV(); err != nil {
+			return UnmarshalError{Row: row, RawErr: errors.Wrap(err, "AfterUnmarshalCSV()")}
+		}
+	}
+	return nil
+}
+
+// cellValue applies the converters and default value (or required check) of fi to the csv value v.
+func (dec *Decoder) cellValue(row int, fi fieldInfo, v string) (string, error) {
+	if dec.nilValue != nil && v == *dec.nilValue {
+		v = ""
+	}
+	recVal := v
+	if len(fi.Converters) > 0 {
+		var err error
+		if recVal, err = applyConverters(dec.converters, fi.Converters, recVal); err != nil {
+			return "", newUnmarshalError(fi, row, v, err, ErrTypeConversion)
+		}
+	}
+	if recVal == "" {
+		if fi.Required {
+			return "", newUnmarshalError(fi, row, recVal, errors.New("empty value in required column"), ErrEmptyValue)
+		}
+		recVal = fi.Default
+	}
+	return recVal, nil
+}
+
+// unmarshalDelimited sets the elements of the array f (a field of the struct s) to the values in the csv value recVal,
+// which are separated by the delim tag option.
+func (dec *Decoder) unmarshalDelimited(row int, recVal string, f, s reflect.Value, fi fieldInfo) error {
+	if recVal == "" {
+		// no data to store in field
+		return nil
+	}
+	vals := strings.Split(recVal, fi.ArrayDelim)
+	if len(vals) != fi.ArrayLen {
+		return newUnmarshalError(fi, row, recVal, fmt.Errorf("expected %d values, got %d", fi.ArrayLen, len(vals)), ErrTypeConversion)
+	}
+	for k, val := range vals {
+		if err := dec.unmarshalField(row, val, f.Index(k), s, fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalField sets f (a field of the struct s) to the value parsed from the csv value recVal.
+func (dec *Decoder) unmarshalField(row int, recVal string, f, s reflect.Value, fi fieldInfo) error { // nolint: gocyclo
+
+	// if field implements csvplus.Unmarshaler use that
+	if f.Type().Implements(csvUnmarshalerType) {
+		p := reflect.New(f.Type().Elem())
+		uc := p.Interface().(Unmarshaler)
+		err := uc.UnmarshalCSV(recVal)
+		if err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "%s.UnmarshalCSV()", fi.Name), ErrTypeConversion)
+		}
+		f.Set(reflect.ValueOf(uc))
+		return nil
+
+	} else if reflect.PtrTo(f.Type()).Implements(csvUnmarshalerType) {
+
+		p := reflect.New(f.Type())
+		uc := p.Interface().(Unmarshaler)
+		err := uc.UnmarshalCSV(recVal)
+		if err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "%s.UnmarshalCSV()", fi.Name), ErrTypeConversion)
+		}
+		f.Set(reflect.ValueOf(uc).Elem())
+		return nil
+	}
+
+	if recVal == "" {
+		strict := dec.strictEmpty || fi.StrictEmpty
+		if strict && f.Kind() != reflect.Ptr && f.Kind() != reflect.String && f.Kind() != reflect.Slice {
+			return newUnmarshalError(fi, row, recVal, errors.New("empty value for non pointer field"), ErrEmptyValue)
+		}
+		// no data to store in field
+		return nil
+	}
+
+	if f.Kind() == reflect.Ptr {
+		// the field is a pointer so we create a new pointer initialised with a zero value
+		val := reflect.New(f.Type().Elem())
+		// set the struct field to the initialised pointer
+		f.Set(val)
+		// and switch f from the field to 'thing' that we actually now want to set
+		f = val.Elem()
+	}
+
+	if isNetType(f.Type()) {
+		if err := parseNet(f, recVal); err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrap(err, "parseNet"), ErrTypeConversion)
+		}
+		return nil
+	}
+
+	if fi.Char {
+		if err := setChar(f, recVal); err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrap(err, "setChar"), ErrTypeConversion)
+		}
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(recVal)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		ival, err := strconv.ParseInt(trimBasePrefix(dec.stripGrouping(recVal), fi.Base), fi.Base, 64)
+		if err == nil && f.OverflowInt(ival) {
+			err = strconv.ErrRange
+		}
+		if err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "strconv.ParseInt"), ErrTypeConversion)
+		}
+		f.SetInt(ival)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		ival, err := strconv.ParseUint(trimBasePrefix(dec.stripGrouping(recVal), fi.Base), fi.Base, 64)
+		if err == nil && f.OverflowUint(ival) {
+			err = strconv.ErrRange
+		}
+		if err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "strconv.ParseUint"), ErrTypeConversion)
+		}
+		f.SetUint(ival)
+	case reflect.Float32, reflect.Float64:
+		if fi.Currency {
+			fval, symbol, err := parseCurrency(recVal, dec.groupingSeparators, fi.Decimal)
+			if err == nil && f.OverflowFloat(fval) {
+				err = strconv.ErrRange
+			}
+			if err != nil {
+				return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "parseCurrency"), ErrTypeConversion)
+			}
+			f.SetFloat(fval)
+			if fi.CurrencyField != "" {
+				s.FieldByName(fi.CurrencyField).SetString(symbol) // checked by getFieldInfo
+			}
+			break
 		}
-
-		recVal := record[fi.ColIndex]
-		f := s.FieldByName(fi.Name)
-
-		// if field implements csvplus.Unmarshaler use that
-		if f.Type().Implements(csvUnmarshalerType) {
-			p := reflect.New(f.Type().Elem())
-			uc := p.Interface().(Unmarshaler)
-			err := uc.UnmarshalCSV(recVal)
-			if err != nil {
-				return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "%s.UnmarshalCSV()", fi.Name))
+		if fi.Percent {
+			fval, err := parsePercent(dec.stripGrouping(recVal), fi.PercentWhole)
+			if err == nil && f.OverflowFloat(fval) {
+				err = strconv.ErrRange
 			}
-			f.Set(reflect.ValueOf(uc))
-			continue
-
-		} else if reflect.PtrTo(f.Type()).Implements(csvUnmarshalerType) {
-
-			p := reflect.New(f.Type())
-			uc := p.Interface().(Unmarshaler)
-			err := uc.UnmarshalCSV(recVal)
 			if err != nil {
-				return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "%s.UnmarshalCSV()", fi.Name))
+				return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "parsePercent"), ErrTypeConversion)
 			}
-			f.Set(reflect.ValueOf(uc).Elem())
-			continue
+			f.SetFloat(fval)
+			break
 		}
-
-		if recVal == "" {
-			// no data to store in field
-			continue
+		fval, err := strconv.ParseFloat(dec.stripGrouping(recVal), 64)
+		if err == nil && f.OverflowFloat(fval) {
+			err = strconv.ErrRange
 		}
-
-		if f.Kind() == reflect.Ptr {
-			// the field is a pointer so we create a new pointer initialised with a zero value
-			val := reflect.New(f.Type().Elem())
-			// set the struct field to the initialised pointer
-			f.Set(val)
-			// and switch f from the field to 'thing' that we actually now want to set
-			f = val.Elem()
+		if err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "strconv.ParseFloat"), ErrTypeConversion)
 		}
-
-		switch f.Kind() {
-		case reflect.String:
-			f.SetString(recVal)
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			ival, err := strconv.ParseInt(recVal, 10, 64)
-			if err != nil || f.OverflowInt(ival) {
-				return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "strconv.ParseInt"))
+		f.SetFloat(fval)
+	case reflect.Bool:
+		bval, err := strconv.ParseBool(recVal)
+		if err != nil && dec.boolWords {
+			bval, err = parseBoolWord(recVal)
+		}
+		if err != nil {
+			return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "strconv.ParseBool"), ErrTypeConversion)
+		}
+		f.SetBool(bval)
+	case reflect.Struct:
+		if f.Type().String() == timeType {
+			loc := fi.Location
+			if loc == nil {
+				loc = dec.location
 			}
-			f.SetInt(ival)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			ival, err := strconv.ParseUint(recVal, 10, 64)
-			if err != nil || f.OverflowUint(ival) {
-				return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "strconv.ParseUint"))
+			if loc == nil {
+				loc = time.UTC
 			}
-			f.SetUint(ival)
-		case reflect.Float32, reflect.Float64:
-			fval, err := strconv.ParseFloat(recVal, 64)
-			if err != nil || f.OverflowFloat(fval) {
-				return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "strconv.ParseFloat"))
+			format := fi.Format
+			if format == formatAuto {
+				var ok bool
+				if format, ok = dec.autoLayout(fi, recVal, autoTimeLayouts); !ok {
+					return newUnmarshalError(fi, row, recVal, errors.New("no layout matches the column values"), ErrBadTimeLayout)
+				}
 			}
-			f.SetFloat(fval)
-		case reflect.Bool:
-			bval, err := strconv.ParseBool(recVal)
+			d, err := parseTime(recVal, format, loc)
 			if err != nil {
-				return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "strconv.ParseBool"))
+				return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "time.Parse %s", format), ErrBadTimeLayout)
 			}
-			f.SetBool(bval)
-		case reflect.Struct:
-			if f.Type().String() == timeType {
-				d, err := time.Parse(fi.Format, recVal)
-				if err != nil {
-					return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, errors.Wrapf(err, "time.Parse %s", fi.Format))
+			f.Set(reflect.ValueOf(d))
+			break
+		}
+		if f.Type() == dateType {
+			format := fi.Format
+			if format == formatAuto {
+				var ok bool
+				if format, ok = dec.autoLayout(fi, recVal, dateLayouts); !ok {
+					return newUnmarshalError(fi, row, recVal, errors.New("no layout matches the column values"), ErrBadTimeLayout)
 				}
-				f.Set(reflect.ValueOf(d))
-				break
 			}
-			fallthrough
+			d, err := ParseDate(format, recVal)
+			if err != nil {
+				return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "ParseDate %s", format), ErrBadTimeLayout)
+			}
+			f.Set(reflect.ValueOf(d))
+			break
+		}
+		fallthrough
 
-		default:
-			return newUnmarshalError(fi.ColName, fi.ColIndex, row, recVal, fmt.Errorf("unsupported type %s", f.Type().String()))
+	case reflect.Slice:
+		if f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := decodeBytes(recVal, fi.Format)
+			if err != nil {
+				return newUnmarshalError(fi, row, recVal, errors.Wrapf(err, "decodeBytes %s", fi.Format), ErrTypeConversion)
+			}
+			f.SetBytes(b)
+			break
 		}
+		fallthrough
+
+	default:
+		return newUnmarshalError(fi, row, recVal, fmt.Errorf("unsupported type %s", f.Type().String()), nil)
 	}
 
 	return nil
@@ -222,6 +1339,12 @@ type Marshaler interface {
 	MarshalCSV() ([]byte, error)
 }
 
+// BeforeMarshaler is the interface implemented by row structs that need to prepare themselves (eg set derived fields)
+// before they're marshalled, an error stops Encode.
+type BeforeMarshaler interface {
+	BeforeMarshalCSV() error
+}
+
 // Marshal marshals v into csv data.
 func Marshal(v interface{}) ([]byte, error) {
 	var buf bytes.Buffer
@@ -251,22 +1374,152 @@ func MarshalWithoutHeader(v interface{}) ([]byte, error) {
 
 // An Encoder writes csv data from a list of struct.
 type Encoder struct {
+	w                io.Writer
 	csvWriter        *csv.Writer
 	withoutHeaderRow bool
-	encRegister      encRegister
+	headerWritten    bool // set by WriteHeader, the header row isn't written by Encode
+	omitEmptyHeader  bool
+	encRegister      *encRegister
+	tags             tagSource // struct tags encRegister reads column names from
+	writeBOM         bool
+	charset          string // set by Charset, empty for UTF-8
+	schema           *Schema
+	converters       map[string]Converter
+	quote            QuoteMode
+	columns          []string // set by SelectColumns
+	mapHeader        func(field, tag string) string
+	nilValue         *string         // set by NilValue
+	rotating         *rotatingWriter // set by NewRotatingEncoder
+	record           []string        // reused by encodeRow
+	strs             []bool          // reused by encodeRow
+	buf              []byte          // values formatted by appendValue, reused by encodeRow
+	spans            []valueSpan     // values of record that are in buf, reused by encodeRow
+	workers          int             // set by Parallel
+	counter          *countingWriter // counts the bytes written to w
+	stats            Stats           // Bytes is read from counter
+	onStats          func(s Stats)
+	inCall           bool          // a call is being measured, see startCall
+	closers          []io.Closer   // closed by Close, in reverse order
+	out              io.Writer     // output of csvWriter, nil if SetCSVWriter is used
+	bw               *bufio.Writer // used instead of csvWriter for quote modes other than QuoteMinimal
+	err              error         // set by options that fail, returned by Encode
 }
 
 // NewEncoder returns an initialised Encoder.
 func NewEncoder(w io.Writer) *Encoder {
+	cw := &countingWriter{w: w}
 	return &Encoder{
-		csvWriter:   csv.NewWriter(w),
+		w:           cw,
+		csvWriter:   csv.NewWriter(cw),
 		encRegister: defaultEncRegister,
+		out:         cw,
+		counter:     cw,
 	}
 }
 
 // SetCSVWriter allows for using a csv.Writer with custom config (eg | field separator instead of ,).
 func (enc *Encoder) SetCSVWriter(r *csv.Writer) *Encoder {
+	if enc.rotating != nil {
+		enc.err = errors.New("SetCSVWriter can't be used with a rotating Encoder")
+		return enc
+	}
 	enc.csvWriter = r
+	enc.out = nil
+	return enc
+}
+
+// Comma sets the field delimiter of the underlying csv.Writer (eg ';' or '\t').
+func (enc *Encoder) Comma(r rune) *Encoder {
+	enc.csvWriter.Comma = r
+	return enc
+}
+
+// UseCRLF sets whether the underlying csv.Writer uses \r\n as the line terminator.
+func (enc *Encoder) UseCRLF(b bool) *Encoder {
+	enc.csvWriter.UseCRLF = b
+	return enc
+}
+
+// WriteBOM sets whether a UTF-8 byte order mark is written before the csv data, Excel needs it to display non ASCII
+// characters correctly. The BOM is written to the io.Writer passed to NewEncoder (even if SetCSVWriter is used) the
+// next time Encode is called.
+func (enc *Encoder) WriteBOM(b bool) *Encoder {
+	enc.writeBOM = b
+	return enc
+}
+
+// writeBOMOnce writes the BOM if WriteBOM is set and it hasn't been written yet.
+func (enc *Encoder) writeBOMOnce() error {
+	if !enc.writeBOM {
+		return nil
+	}
+	if enc.charset != "" {
+		return errors.Errorf("a UTF-8 BOM can't be written in charset %s", enc.charset)
+	}
+	if _, err := enc.w.Write(utf8BOM); err != nil {
+		return errors.Wrap(err, "unable to write BOM")
+	}
+	enc.writeBOM = false
+	return nil
+}
+
+// TagName sets the struct tag that column names (and options) are read from, eg csv so structs tagged for
+// gocarina/gocsv or jszwec/csvutil can be encoded without renaming their tags. Fields without the tag fall back to
+// their csvplus tag, other tags (eg csvplusFormat) are unaffected.
+func (enc *Encoder) TagName(name string) *Encoder {
+	enc.tags.name = name
+	enc.encRegister = tagEncRegister(enc.tags)
+	return enc
+}
+
+// JSONTags sets whether fields without a csvplus tag (or TagName tag) use the name in their json tag as the column
+// name, fields with a json:"-" tag aren't written.
+func (enc *Encoder) JSONTags(b bool) *Encoder {
+	enc.tags.json = b
+	enc.encRegister = tagEncRegister(enc.tags)
+	return enc
+}
+
+// ComputedColumns sets whether computed columns are written, the results of methods called CSV<Name> (eg CSVTotal)
+// that take no arguments and return a string (or a string and an error) are written in columns called Name after the
+// fields. They're only written by Encoders with this option, eg they aren't inserted by SQLInserter.
+func (enc *Encoder) ComputedColumns(b bool) *Encoder {
+	enc.tags.computed = b
+	enc.encRegister = tagEncRegister(enc.tags)
+	return enc
+}
+
+// Converter registers fn as the converter called name, fields with a csvplusConv:"name" tag have their values passed
+// through fn after they're marshalled (eg to trim trailing zeros).
+func (enc *Encoder) Converter(name string, fn Converter) *Encoder {
+	if enc.converters == nil {
+		enc.converters = make(map[string]Converter)
+	}
+	enc.converters[name] = fn
+	return enc
+}
+
+// NilValue sets the value written for nil pointer fields (and nil or missing map values without a default) in place
+// of an empty value, eg NULL for loaders that treat empty values as empty strings. A csvplusNilVal tag (eg
+// csvplusNilVal:"-") sets the value for a single field. Nil values aren't passed to converters, or quoted by
+// QuoteStrings.
+func (enc *Encoder) NilValue(s string) *Encoder {
+	enc.nilValue = &s
+	return enc
+}
+
+// SelectColumns sets the columns (csvplus tag names, or field names for fields without a tag) that are written by
+// Encode, in order. Encode returns an error if a column doesn't exist. By default all fields are written.
+func (enc *Encoder) SelectColumns(names ...string) *Encoder {
+	enc.columns = names
+	return enc
+}
+
+// MapHeader sets a function that's used to create the header row, it's called with the name of each struct field and
+// its column name (from the csvplus tag, or the field name if there's no tag) and returns the header for the column (eg
+// title cased, translated or with units added). When a Schema is used with maps the field name is the column name.
+func (enc *Encoder) MapHeader(fn func(field, tag string) string) *Encoder {
+	enc.mapHeader = fn
 	return enc
 }
 
@@ -276,120 +1529,582 @@ func (enc *Encoder) UseHeader(v bool) *Encoder {
 	return enc
 }
 
+// OmitEmptyHeader sets whether Encode writes nothing (not even the header row or BOM) when the slice is empty.
+func (enc *Encoder) OmitEmptyHeader(b bool) *Encoder {
+	enc.omitEmptyHeader = b
+	return enc
+}
+
+// WriteHeader writes the header row (and the BOM if WriteBOM is set) for v, a struct, a slice of structs, a pointer to
+// either, or a []map[string]interface{} if a Schema is set, without encoding any rows. It's written even if UseHeader
+// is false, and Encode doesn't write a header row after WriteHeader has been called, so callers composing output from
+// several parts control exactly where the header row appears.
+func (enc *Encoder) WriteHeader(v interface{}) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil {
+		return errors.New("can't write a header row for nil")
+	}
+
+	withoutHeaderRow := enc.withoutHeaderRow
+	enc.withoutHeaderRow = false
+	_, err := enc.begin(t, enc.schema)
+	enc.withoutHeaderRow = withoutHeaderRow
+	if err != nil {
+		return err
+	}
+	enc.headerWritten = true
+	return enc.flush()
+}
+
 // Encode encodes v into csv data.
-func (enc *Encoder) Encode(v interface{}) error { // nolint: gocyclo
+func (enc *Encoder) Encode(v interface{}) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	c := enc.startCall()
+	defer enc.endCall(c)
+
 	rv := reflect.ValueOf(v)
 	rt := rv.Type()
 	if rv.Kind() != reflect.Ptr {
-		return fmt.Errorf("non pointer %s", rt)
+		return fmt.Errorf("non pointer %s: %w", rt, ErrNotSlicePtr)
 	}
 	if rv.Elem().Kind() != reflect.Slice {
-		return fmt.Errorf("expected slice, got %s", rv.Elem().Type())
+		return fmt.Errorf("expected slice, got %s: %w", rv.Elem().Type(), ErrNotSlicePtr)
 	}
 
 	st := reflect.TypeOf(v).Elem().Elem()
-	enc.encRegister.Register(st)
+	if st.Kind() == reflect.Ptr {
+		// slice of pointers
+		st = st.Elem()
+	}
+	containerValue := rv.Elem()
+	if enc.omitEmptyHeader && containerValue.Len() == 0 {
+		return checkElemType(st, enc.schema)
+	}
+	fis, err := enc.begin(st, enc.schema)
+	if err != nil {
+		return err
+	}
 
-	if !enc.withoutHeaderRow {
-		err := enc.csvWriter.Write(enc.encRegister.GetEncodeHeaders(st))
-		if err != nil {
-			return errors.Wrap(err, "unable to write header row")
+	if enc.workers > 1 {
+		if err := enc.encodeParallel(st, fis, containerValue); err != nil {
+			return err
+		}
+		return enc.flush()
+	}
+	for i := 0; i < containerValue.Len(); i++ {
+		if err := enc.encodeRow(st, fis, i, containerValue.Index(i)); err != nil {
+			return err
 		}
 	}
 
-	containerValue := rv.Elem()
+	return enc.flush()
+}
 
-	var record []string
-	for i := 0; i < containerValue.Len(); i++ {
-		record = nil
-		sv := containerValue.Index(i)
+// begin returns the fields to encode for values of type st (a struct, or map if schema isn't nil), the BOM and header
+// row are written if they're needed.
+func (enc *Encoder) begin(st reflect.Type, schema *Schema) ([]fieldInfo, error) {
+	if err := checkElemType(st, schema); err != nil {
+		return nil, err
+	}
 
-		for _, fieldIndex := range enc.encRegister.GetEncodeIndices(st) {
-			fv := sv.Field(fieldIndex)
+	var fis []fieldInfo
+	if schema != nil {
+		var err error
+		if fis, err = schema.fieldInfo(st, nil, matchOptions{withoutHeader: true}); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if fis, err = enc.encRegister.encodeFields(st); err != nil {
+			return nil, err
+		}
+	}
+	if enc.columns != nil {
+		var err error
+		if fis, err = selectColumns(fis, enc.columns); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkConverters(enc.converters, fis); err != nil {
+		return nil, err
+	}
+	header := headerRow(fis, enc.mapHeader)
 
-			var m Marshaler
-			if fv.Type().Implements(csvMarshalerType) {
-				m = fv.Interface().(Marshaler)
-			} else if reflect.PtrTo(fv.Type()).Implements(csvMarshalerType) {
-				m = fv.Addr().Interface().(Marshaler)
-			}
-			if m != nil {
-				b, err := m.MarshalCSV()
-				if err != nil {
-					return err
-				}
-				record = append(record, string(b))
-				continue
-			}
+	if err := enc.writeBOMOnce(); err != nil {
+		return nil, err
+	}
 
-			if fv.Kind() == reflect.Ptr {
-				if fv.IsNil() {
-					record = append(record, "")
-					continue
-				}
+	if !enc.withoutHeaderRow && !enc.headerWritten {
+		err := enc.write(header, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to write header row")
+		}
+	}
+	if enc.rotating != nil && !enc.headerWritten {
+		if err := enc.flush(); err != nil {
+			return nil, errors.Wrap(err, "unable to write header row")
+		}
+		enc.rotating.endHeader()
+	}
+	return fis, nil
+}
+
+// encodeRow writes the struct (or map) sv as row i, sv can also be a pointer to a struct.
+func (enc *Encoder) encodeRow(st reflect.Type, fis []fieldInfo, i int, sv reflect.Value) error {
+	record, strs, err := enc.marshalRow(st, fis, i, sv)
+	if err != nil {
+		enc.stats.Rejected++
+		return err
+	}
+	if err := enc.write(record, strs); err != nil {
+		return newEncodeError(st, "", i, errors.Wrap(err, "unable to write row"))
+	}
+	if err := enc.endRow(); err != nil {
+		return newEncodeError(st, "", i, errors.Wrap(err, "unable to write row"))
+	}
+	return nil
+}
 
-				// dereference
-				fv = fv.Elem()
+// marshalRow converts the struct (or map) sv, row i, to a record and reports which of its values are from string
+// fields. The record is reused by the next call.
+func (enc *Encoder) marshalRow(st reflect.Type, fis []fieldInfo, i int, sv reflect.Value) ([]string, []bool, error) {
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			return nil, nil, newEncodeError(st, "", i, errors.New("nil element"))
+		}
+		sv = sv.Elem()
+	}
+	if sv.Kind() == reflect.Struct {
+		if !sv.CanAddr() {
+			// values received from a channel aren't addressable
+			p := reflect.New(st).Elem()
+			p.Set(sv)
+			sv = p
+		}
+		if bm, ok := sv.Addr().Interface().(BeforeMarshaler); ok {
+			if err := bm.BeforeMarshalCSV(); err != nil {
+				return nil, nil, newEncodeError(st, "", i, errors.Wrap(err, "BeforeMarshalCSV()"))
 			}
+		}
+	}
 
-			switch fv.Kind() {
-			case reflect.String:
-				record = append(record, fv.String())
-				continue
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				record = append(record, strconv.Itoa(int(fv.Int())))
-				continue
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				record = append(record, strconv.Itoa(int(fv.Uint())))
-				continue
-			case reflect.Float32, reflect.Float64:
-				// TODO: consider fmt.Sprintf("%.6f", fv.Float()), this could come from a struct tag
-				record = append(record, strconv.FormatFloat(fv.Float(), 'f', -1, 64))
-				continue
-			case reflect.Bool:
-				record = append(record, strconv.FormatBool(fv.Bool()))
-				continue
-			case reflect.Struct:
-				if fv.Type().String() == timeType {
-					t := fv.Interface().(time.Time)
-					record = append(record, t.Format(enc.encRegister.Fields[st].fields[fieldIndex].Format))
+	// record and strs (which values in record are from string fields) are reused for each row
+	record, strs := enc.record[:0], enc.strs[:0]
+	// numbers and times are formatted into buf and converted to a single string for the row
+	buf, spans := enc.buf[:0], enc.spans[:0]
+
+	for _, fi := range fis {
+		if sv.Kind() == reflect.Map {
+			mv := sv.MapIndex(reflect.ValueOf(fi.ColName))
+			if !mv.IsValid() || mv.IsNil() {
+				if fi.Default == "" && enc.nilValue != nil {
+					record = append(record, *enc.nilValue)
+					strs = append(strs, false)
 					continue
 				}
+				record = append(record, fi.Default)
+				strs = append(strs, isStringType(fi.Type))
+				continue
+			}
+			// unwrap the interface{} to get the concrete value
+			mv = mv.Elem()
+			val, err := marshalField(mv, fi)
+			if err != nil {
+				return nil, nil, newEncodeError(st, fi.Name, i, err)
+			}
+			record = append(record, val)
+			strs = append(strs, isStringType(mv.Type()))
+			continue
+		}
 
-				record = append(record, fv.String())
+		fv, err := fieldValue(sv, fi)
+		if err != nil {
+			return nil, nil, newEncodeError(st, fi.Name, i, err)
+		}
+		if nilVal, ok := enc.nilValueOf(fi); ok && fv.Kind() == reflect.Ptr && fv.IsNil() {
+			record = append(record, nilVal)
+			strs = append(strs, false)
+			continue
+		}
+		if start := len(buf); fi.ArrayLen == 0 && len(fi.Converters) == 0 {
+			var ok bool
+			if buf, ok = appendValue(buf, fv, fi); ok {
+				spans = append(spans, valueSpan{i: len(record), start: start, end: len(buf)})
+				record = append(record, "")
+				strs = append(strs, false)
 				continue
 			}
 		}
+		if record, err = enc.appendField(record, fv, fi); err != nil {
+			return nil, nil, newEncodeError(st, fi.Name, i, err)
+		}
+		for len(strs) < len(record) {
+			strs = append(strs, isStringType(fv.Type()))
+		}
+	}
+
+	if len(spans) > 0 {
+		row := string(buf)
+		for _, sp := range spans {
+			record[sp.i] = row[sp.start:sp.end]
+		}
+	}
+
+	enc.record, enc.strs, enc.buf, enc.spans = record, strs, buf, spans
+	return record, strs, nil
+}
+
+// EncodeChan encodes the rows received from ch (a channel of structs, pointers to structs, or maps if a Schema is set)
+// until it's closed, the header row is written straight away. Rows are flushed to the underlying io.Writer whenever
+// there are no more rows waiting in ch, so output isn't held back while the producer is idle. If ctx is done before ch
+// is closed the rows written so far are flushed and ctx.Err() is returned.
+func (enc *Encoder) EncodeChan(ctx context.Context, ch interface{}) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	c := enc.startCall()
+	defer enc.endCall(c)
+
+	cv := reflect.ValueOf(ch)
+	if cv.Kind() != reflect.Chan || cv.Type().ChanDir()&reflect.RecvDir == 0 {
+		return fmt.Errorf("expected receive channel, got %T", ch)
+	}
+	st := cv.Type().Elem()
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	fis, err := enc.begin(st, enc.schema)
+	if err != nil {
+		return err
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: cv},
+	}
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			enc.flush() // nolint: errcheck
+			return err
+		}
+
+		v, ok := cv.TryRecv()
+		if !v.IsValid() {
+			// nothing waiting, flush before blocking
+			if err := enc.flush(); err != nil {
+				return err
+			}
+			var chosen int
+			if chosen, v, ok = reflect.Select(cases); chosen == 0 {
+				return ctx.Err()
+			}
+		}
+		if !ok {
+			// closed
+			return enc.flush()
+		}
 
-		if err := enc.csvWriter.Write(record); err != nil {
+		if err := enc.encodeRow(st, fis, i, v); err != nil {
 			return err
 		}
 	}
+}
+
+// appendField appends the csv values of the struct field fv to record, array fields are either spread across
+// consecutive columns or joined into a single value.
+func (enc *Encoder) appendField(record []string, fv reflect.Value, fi fieldInfo) ([]string, error) {
+	if fi.ArrayLen == 0 {
+		val, err := marshalField(fv, fi)
+		if err != nil {
+			return nil, err
+		}
+		return enc.appendCell(record, val, fi)
+	}
+
+	if fi.ArrayDelim == "" {
+		for k := 0; k < fi.ArrayLen; k++ {
+			val, err := marshalField(fv.Index(k), fi)
+			if err != nil {
+				return nil, err
+			}
+			if record, err = enc.appendCell(record, val, fi); err != nil {
+				return nil, err
+			}
+		}
+		return record, nil
+	}
+
+	vals := make([]string, fi.ArrayLen)
+	for k := range vals {
+		var err error
+		if vals[k], err = marshalField(fv.Index(k), fi); err != nil {
+			return nil, err
+		}
+	}
+	return enc.appendCell(record, strings.Join(vals, fi.ArrayDelim), fi)
+}
+
+// nilValueOf returns the value written for fi when it's a nil pointer, false is returned if it's an empty value.
+func (enc *Encoder) nilValueOf(fi fieldInfo) (string, bool) {
+	switch {
+	case fi.NilValue != nil:
+		return *fi.NilValue, true
+	case enc.nilValue != nil:
+		return *enc.nilValue, true
+	}
+	return "", false
+}
+
+// appendCell applies the converters of fi to the csv value val and appends it to record.
+func (enc *Encoder) appendCell(record []string, val string, fi fieldInfo) ([]string, error) {
+	if len(fi.Converters) > 0 {
+		var err error
+		if val, err = applyConverters(enc.converters, fi.Converters, val); err != nil {
+			return nil, err
+		}
+	}
+	return append(record, val), nil
+}
+
+// marshalField converts the value of the struct field (or map value) fv to a csv value.
+func marshalField(fv reflect.Value, fi fieldInfo) (string, error) { // nolint: gocyclo
+	var m Marshaler
+	if impl := marshalerOf(fv.Type()); impl.value {
+		m = fv.Interface().(Marshaler)
+	} else if impl.ptr && fv.CanAddr() {
+		m = fv.Addr().Interface().(Marshaler)
+	}
+	if m != nil {
+		b, err := m.MarshalCSV()
+		if err != nil {
+			return "", errors.Wrap(err, "MarshalCSV()")
+		}
+		return string(b), nil
+	}
+
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return "", nil
+		}
+
+		// dereference
+		fv = fv.Elem()
+	}
+
+	if isNetType(fv.Type()) {
+		return formatNet(fv), nil
+	}
+
+	if fi.Char {
+		return formatChar(fv)
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), fi.Base), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), fi.Base), nil
+	case reflect.Float32, reflect.Float64:
+		if fi.Percent {
+			return formatPercent(fv.Float(), fi.Format, fi.TrimZeros, fi.PercentWhole), nil
+		}
+		return formatFloat(fv.Float(), fi.Format, fi.TrimZeros), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Struct:
+		if fv.Type().String() == timeType {
+			t := fv.Interface().(time.Time)
+			if fi.Location != nil {
+				t = t.In(fi.Location)
+			}
+			format := fi.Format
+			if format == "" || format == formatAuto {
+				// map values of a different type to the schema column, or the layout is only detected when decoding
+				format = time.RFC3339
+			}
+			return formatTime(t, format), nil
+		}
+		if fv.Type() == dateType {
+			d := fv.Interface().(Date)
+			if d.IsZero() {
+				// symmetrical with unmarshalling, empty values leave the field as the zero value
+				return "", nil
+			}
+			format := fi.Format
+			if format == "" || format == formatAuto {
+				format = dateLayout
+			}
+			return d.Format(format), nil
+		}
+
+		return fv.String(), nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeBytes(fv.Bytes(), fi.Format)
+		}
+	}
+
+	return "", fmt.Errorf("unsupported type %s", fv.Type())
+}
+
+// WriteRecord writes a single raw record (and the BOM if WriteBOM is set and it hasn't been written yet), records are
+// buffered so Flush must be called after the last one.
+func (enc *Encoder) WriteRecord(record []string) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	c := enc.startCall()
+	defer enc.endCall(c)
+
+	if err := enc.writeBOMOnce(); err != nil {
+		return err
+	}
+
+	if err := enc.write(record, nil); err != nil {
+		return errors.Wrap(err, "unable to write record")
+	}
+	return errors.Wrap(enc.endRow(), "unable to write record")
+}
+
+// Flush writes any records buffered by WriteRecord to the underlying io.Writer.
+func (enc *Encoder) Flush() error {
+	return enc.flush()
+}
+
+// Sentinel errors that can be used with errors.Is to check the kind of error returned by Decode/Encode.
+var (
+	// ErrNotSlicePtr is returned when the value to decode into (or encode from) isn't a pointer to a slice.
+	ErrNotSlicePtr = errors.New("pointer to slice required")
+	// ErrMissingColumn is returned when a column required by the struct isn't in the csv data.
+	ErrMissingColumn = errors.New("missing columns in csv data")
+	// ErrTypeConversion is returned when a value can't be converted to the type of a struct field.
+	ErrTypeConversion = errors.New("type conversion failed")
+	// ErrBadTimeLayout is returned when a time value can't be parsed with the layout of a struct field.
+	ErrBadTimeLayout = errors.New("time value doesn't match layout")
+	// ErrNotStructPtr is returned when the value to decode a single row into isn't a pointer to a struct.
+	ErrNotStructPtr = errors.New("pointer to struct required")
+	// ErrNeedsQuotes is returned when a value needs quoting but the quote mode is QuoteNever.
+	ErrNeedsQuotes = errors.New("value needs quoting")
+	// ErrDuplicateColumn is returned when the header row has duplicate column names and the policy is
+	// DuplicateHeadersError.
+	ErrDuplicateColumn = errors.New("duplicate columns in csv data")
+	// ErrEmptyValue is returned when a value in a required column is empty, or a value for a non pointer field is empty
+	// in strict empty mode.
+	ErrEmptyValue = errors.New("empty value")
+	// ErrShortRow is wrapped by errors for rows with fewer fields than the header row (or first row), see OnShortRow.
+	ErrShortRow = errors.New("not enough columns in row")
+	// ErrHeaderMismatch is returned by DecodeAll when the header rows of the readers don't have the same columns, and
+	// by Decode when the header row doesn't match the struct with ExactHeader.
+	ErrHeaderMismatch = errors.New("header row doesn't match")
+	// ErrValidation is wrapped by errors for values that fail the validation rules of their column, see ValidationError.
+	ErrValidation = errors.New("invalid value")
+	// ErrFieldTooLarge is wrapped by errors for values longer than the MaxFieldBytes limit.
+	ErrFieldTooLarge = errors.New("value too large")
+	// ErrTooManyRows is wrapped by errors for csv data with more rows than the MaxRows limit.
+	ErrTooManyRows = errors.New("too many rows")
+	// ErrInvalidTag is wrapped by errors for struct tags that can't be used, eg a csvplusBase tag that isn't a base.
+	ErrInvalidTag = errors.New("invalid struct tag")
+)
+
+// EncodeError is returned by Encode when a row can't be marshalled or written.
+type EncodeError struct {
+	Type   reflect.Type // the struct type being encoded
+	Field  string       // name of the struct field, empty if the error isn't specific to a field
+	Row    int          // index of the row in the slice
+	RawErr error
+}
+
+func newEncodeError(st reflect.Type, field string, row int, err error) EncodeError {
+	return EncodeError{
+		Type:   st,
+		Field:  field,
+		Row:    row,
+		RawErr: err,
+	}
+}
+
+func (ee EncodeError) Error() string {
+	if ee.Field == "" {
+		return fmt.Sprintf("type: %s, row: %d, err: %s", ee.Type, ee.Row, ee.RawErr)
+	}
+	return fmt.Sprintf("type: %s, field: %s, row: %d, err: %s", ee.Type, ee.Field, ee.Row, ee.RawErr)
+}
 
-	enc.csvWriter.Flush()
-	return enc.csvWriter.Error()
+// Unwrap returns the underlying error.
+func (ee EncodeError) Unwrap() error {
+	return ee.RawErr
 }
 
-type UnmarhsalError struct {
-	Column string
+// UnmarshalError is returned by Decode when a row can't be read or unmarshalled.
+type UnmarshalError struct {
+	Column string // column name, or col idx n if there's no header row
+	Field  string // name of the struct field
 	Row    int
+	Line   int   // physical line in the input the row starts on, rows can span lines if they contain quoted newlines
+	Offset int64 // byte offset in the input where reading the row started
 	Value  string
 	RawErr error
+	Code   ErrorCode // the kind of error, eg TYPE_CONVERSION, empty if it isn't one of the kinds with a code
+	kind   error
 }
 
-func newUnmarshalError(colName string, colIndex, row int, value string, err error) UnmarhsalError {
+// UnmarhsalError is the original (misspelled) name of UnmarshalError.
+//
+// Deprecated: use UnmarshalError.
+type UnmarhsalError = UnmarshalError
+
+func newUnmarshalError(fi fieldInfo, row int, value string, err, kind error) UnmarshalError {
+	colName := fi.ColName
 	if colName == "" {
 		// no header row, we only have index
-		colName = fmt.Sprintf("col idx %d", colIndex)
+		colName = fmt.Sprintf("col idx %d", fi.ColIndex)
 	}
-	return UnmarhsalError{
+	return UnmarshalError{
 		Column: colName,
+		Field:  fi.Name,
 		Row:    row,
 		Value:  value,
 		RawErr: err,
+		Code:   codeOf(kind),
+		kind:   kind,
 	}
 }
 
-func (um UnmarhsalError) Error() string {
-	return fmt.Sprintf("col: %s, row: %d, val: %s, err: %s", um.Column, um.Row, um.Value, um.RawErr.Error())
+func (um UnmarshalError) Error() string {
+	var b strings.Builder
+	if um.Column != "" {
+		fmt.Fprintf(&b, "col: %s, ", um.Column)
+	}
+	if um.Field != "" {
+		fmt.Fprintf(&b, "field: %s, ", um.Field)
+	}
+	fmt.Fprintf(&b, "row: %d, ", um.Row)
+	if um.Line > 0 {
+		fmt.Fprintf(&b, "line: %d, ", um.Line)
+	}
+	if um.Column != "" {
+		fmt.Fprintf(&b, "val: %s, ", um.Value)
+	}
+	fmt.Fprintf(&b, "err: %s", um.RawErr)
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (um UnmarshalError) Unwrap() error {
+	return um.RawErr
+}
+
+// Is reports whether the error is of the kind target (eg ErrTypeConversion).
+func (um UnmarshalError) Is(target error) bool {
+	return um.kind != nil && um.kind == target
 }
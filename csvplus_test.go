@@ -2,11 +2,20 @@ package csvplus_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	"github.com/j0hnsmith/csvplus"
 )
@@ -168,6 +177,93 @@ func ExampleMarshal() {
 
 var benchData []byte
 
+func BenchmarkUnmarshal_Large(b *testing.B) {
+	type Item struct {
+		First  string  `csvplus:"first"`
+		Second int     `csvplus:"second"`
+		Third  float64 `csvplus:"third"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("first,second,third\n")
+	for r := 0; r < 100000; r++ {
+		fmt.Fprintf(&sb, "row%d,%d,%d.5\n", r, r, r)
+	}
+	data := []byte(sb.String())
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var items []Item
+		if err := csvplus.Unmarshal(data, &items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncode_Numbers(b *testing.B) {
+	type Item struct {
+		A int
+		B float64
+		C time.Time
+		D string
+		E *int64
+	}
+	items := make([]Item, 10000)
+	for i := range items {
+		v := int64(i)
+		items[i] = Item{i * 1000, float64(i) / 3, time.Unix(int64(i), 0).UTC(), "x", &v}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := csvplus.NewEncoder(io.Discard).Encode(&items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder_SkipUnmappedColumns(b *testing.B) {
+	type Item struct {
+		First  int     `csvplus:"c3"`
+		Second string  `csvplus:"c10"`
+		Third  float64 `csvplus:"c20"`
+	}
+
+	// 150 columns, only 3 of which are used
+	var sb strings.Builder
+	for r := 0; r <= 1000; r++ {
+		for c := 0; c < 150; c++ {
+			if c > 0 {
+				sb.WriteByte(',')
+			}
+			if r == 0 {
+				fmt.Fprintf(&sb, "c%d", c)
+			} else {
+				fmt.Fprintf(&sb, "%d", r*c)
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	data := []byte(sb.String())
+
+	for _, skip := range []bool{false, true} {
+		b.Run(fmt.Sprintf("skip=%t", skip), func(b *testing.B) {
+			var items []Item
+			for n := 0; n < b.N; n++ {
+				items = nil
+				err := csvplus.NewDecoder(bytes.NewReader(data)).SkipUnmappedColumns(skip).Decode(&items)
+				if err != nil {
+					panic(err)
+				}
+			}
+			benchItems = items
+		})
+	}
+}
+
 func BenchmarkMarshal(b *testing.B) {
 	type Item struct {
 		First  string `csvplus:"first"`
@@ -306,6 +402,172 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 			}
 		})
 
+		t.Run("int base", func(t *testing.T) {
+			type Item struct {
+				First  int    `csvplusBase:"16"`
+				Second uint8  `csvplusBase:"2"`
+				Third  *int64 `csvplusBase:"8"`
+			}
+			data := []byte("First,Second,Third\n0xFF,101,-0o17\n-1a,0b11,")
+			var items []Item
+			err := csvplus.Unmarshal(data, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if items[0].First != 255 {
+				t.Errorf("expected 255, got: %d", items[0].First)
+			}
+			if items[0].Second != 5 {
+				t.Errorf("expected 5, got: %d", items[0].Second)
+			}
+			if *items[0].Third != -15 {
+				t.Errorf("expected -15, got: %d", *items[0].Third)
+			}
+			if items[1].First != -26 {
+				t.Errorf("expected -26, got: %d", items[1].First)
+			}
+			if items[1].Second != 3 {
+				t.Errorf("expected 3, got: %d", items[1].Second)
+			}
+		})
+
+		t.Run("invalid int base", func(t *testing.T) {
+			type Item struct {
+				First int `csvplusBase:"hex"`
+			}
+			var items []Item
+			err := csvplus.Unmarshal([]byte("First\n10"), &items)
+			if !errors.Is(err, csvplus.ErrInvalidTag) {
+				t.Errorf("expected ErrInvalidTag, got: %v", err)
+			}
+			if code := csvplus.ErrorCodeOf(err); code != csvplus.CodeInvalidTag {
+				t.Errorf("expected %s, got: %s", csvplus.CodeInvalidTag, code)
+			}
+			if _, err := csvplus.Marshal(&[]Item{{10}}); !errors.Is(err, csvplus.ErrInvalidTag) {
+				t.Errorf("expected ErrInvalidTag, got: %v", err)
+			}
+		})
+
+		t.Run("currency", func(t *testing.T) {
+			var tests = []struct {
+				Name           string
+				Data           []byte
+				Expected       float64
+				ExpectedSymbol string
+			}{
+				{"dollars", []byte("Price\n\"$1,234.50\""), 1234.5, "$"},
+				{"euros", []byte("Price\n\"€9,99\""), 9.99, "€"},
+				{"euro grouping", []byte("Price\n\"1.234,50 EUR\""), 1234.5, "EUR"},
+				{"grouping only", []byte("Price\n\"£1,234,567\""), 1234567, "£"},
+				{"negative", []byte("Price\n($5.00)"), -5, "$"},
+				{"no symbol", []byte("Price\n12.5"), 12.5, ""},
+				{"fraction", []byte("Price\n$0.125"), 0.125, "$"},
+				{"no integer part", []byte("Price\n\".500\""), 0.5, ""},
+				{"leading sign", []byte("Price\n-$5.00"), -5, "$"},
+				{"trailing sign", []byte("Price\n5.00 USD-"), -5, "USD"},
+				{"spaced letters", []byte("Price\n\"kr 12,50\""), 12.5, "kr"},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.Name, func(t *testing.T) {
+					type Item struct {
+						Price  float64 `csvplus:"Price,currency=Symbol"`
+						Symbol string  `csvplus:"-"`
+					}
+					var items []Item
+					err := csvplus.Unmarshal(tt.Data, &items)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if items[0].Price != tt.Expected {
+						t.Errorf("expected %v, got: %v", tt.Expected, items[0].Price)
+					}
+					if items[0].Symbol != tt.ExpectedSymbol {
+						t.Errorf("expected %s, got: %s", tt.ExpectedSymbol, items[0].Symbol)
+					}
+				})
+			}
+		})
+
+		t.Run("currency error", func(t *testing.T) {
+			type Item struct {
+				Price float64 `csvplus:"Price,currency"`
+			}
+			for _, v := range []string{"free", "1-2", "(5", "-(5)", "-5-", "12abc", "1e5", "$5 EUR", "1.500", "$1.234"} {
+				var items []Item
+				err := csvplus.Unmarshal([]byte("Price\n"+v), &items)
+				if err == nil {
+					t.Errorf("%s: expected error, got: %v", v, items[0].Price)
+				}
+			}
+		})
+
+		t.Run("currency field error", func(t *testing.T) {
+			type Item struct {
+				Price  float64 `csvplus:"Price,currency=Symbol"`
+				Symbol int     `csvplus:"-"`
+			}
+			var items []Item
+			err := csvplus.Unmarshal([]byte("Price\n$5"), &items)
+			if !errors.Is(err, csvplus.ErrInvalidTag) {
+				t.Errorf("expected ErrInvalidTag, got: %v", err)
+			}
+		})
+
+		t.Run("currency decimal", func(t *testing.T) {
+			type Item struct {
+				Grouped float64 `csvplus:"Grouped,currency"`
+				KWD     float64 `csvplus:"KWD,currency" csvplusDecimal:"."`
+				EUR     float64 `csvplus:"EUR,currency" csvplusDecimal:","`
+			}
+			// a single separator followed by 3 digits is resolved by the grouping separators or a csvplusDecimal tag
+			var items []Item
+			data := "Grouped,KWD,EUR\n1.234,KWD 1.234,\"1.234\""
+			err := csvplus.NewDecoder(strings.NewReader(data)).GroupingSeparators(".").Decode(&items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if items[0].Grouped != 1234 {
+				t.Errorf("expected 1234, got: %v", items[0].Grouped)
+			}
+			if items[0].KWD != 1.234 {
+				t.Errorf("expected 1.234, got: %v", items[0].KWD)
+			}
+			if items[0].EUR != 1234 {
+				t.Errorf("expected 1234, got: %v", items[0].EUR)
+			}
+
+			err = csvplus.Unmarshal([]byte("Grouped,KWD,EUR\n1,\"1.234,5\",1"), &items)
+			if err == nil {
+				t.Error("expected error for a grouping separator after the decimal separator")
+			}
+		})
+
+		t.Run("percent", func(t *testing.T) {
+			type Item struct {
+				Rate   float64  `csvplus:"rate,percent"`
+				Points *float32 `csvplus:"points,percent=whole"`
+			}
+			data := []byte("rate,points\n45%,12.5%\n0.5 %,")
+			var items []Item
+			err := csvplus.Unmarshal(data, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if items[0].Rate != 0.45 {
+				t.Errorf("expected 0.45, got: %v", items[0].Rate)
+			}
+			if *items[0].Points != 12.5 {
+				t.Errorf("expected 12.5, got: %v", *items[0].Points)
+			}
+			if items[1].Rate != 0.005 {
+				t.Errorf("expected 0.005, got: %v", items[1].Rate)
+			}
+			if items[1].Points != nil {
+				t.Errorf("expected nil, got: %v", items[1].Points)
+			}
+		})
+
 		t.Run("bool", func(t *testing.T) {
 			var tests = []struct {
 				Name     string
@@ -398,6 +660,45 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 				}
 			})
 
+			t.Run("named layouts", func(t *testing.T) {
+				type Item struct {
+					First  time.Time     `csvplusFormat:"time.ANSIC"`
+					Second time.Time     `csvplusFormat:"time.Kitchen"`
+					Third  *time.Time    `csvplusFormat:"time.DateTime"`
+					Fourth csvplus.Date  `csvplusFormat:"time.DateOnly"`
+					Fifth  *csvplus.Date `csvplusFormat:"time.RFC1123"`
+				}
+
+				data := []byte("First,Second,Third,Fourth,Fifth\n" +
+					"Mon Jan  2 15:04:05 2006,3:04PM,2006-01-02 15:04:05,2006-01-02,\"Mon, 02 Jan 2006 00:00:00 UTC\"\n")
+				var items []Item
+				err := csvplus.Unmarshal(data, &items)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+				if !items[0].First.Equal(expected) || !items[0].Third.Equal(expected) {
+					t.Errorf("expected %v, got %v and %v", expected, items[0].First, *items[0].Third)
+				}
+				if items[0].Second.Hour() != 15 || items[0].Second.Minute() != 4 {
+					t.Errorf("expected 15:04, got %v", items[0].Second)
+				}
+				if items[0].Fourth.String() != "2006-01-02" || items[0].Fifth.String() != "2006-01-02" {
+					t.Errorf("expected 2006-01-02, got %v and %v", items[0].Fourth, items[0].Fifth)
+				}
+
+				out, err := csvplus.Marshal(&items)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(out) != string(data) {
+					t.Errorf("expected: %s, got: %s", data, out)
+				}
+				if err := csvplus.CheckType(reflect.TypeOf(Item{})); err != nil {
+					t.Errorf("unexpected CheckType error: %v", err)
+				}
+			})
+
 			t.Run("RFC3339 is default", func(t *testing.T) {
 				type Item struct {
 					First time.Time
@@ -437,6 +738,67 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 				}
 			})
 
+			t.Run("csvplusTZ", func(t *testing.T) {
+				type Item struct {
+					First  time.Time  `csvplusFormat:"2006-01-02 15:04" csvplusTZ:"America/New_York"`
+					Second *time.Time `csvplusFormat:"2006-01-02 15:04"`
+				}
+
+				data := []byte("First,Second\n2020-01-02 10:30,2020-01-02 10:30")
+				var items []Item
+				err := csvplus.NewDecoder(bytes.NewReader(data)).Location(time.FixedZone("UTC+2", 2*60*60)).Decode(&items)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected := time.Date(2020, 1, 2, 15, 30, 0, 0, time.UTC)
+				if !items[0].First.Equal(expected) {
+					t.Errorf("expected %v, got %v", expected, items[0].First)
+				}
+				expected = time.Date(2020, 1, 2, 8, 30, 0, 0, time.UTC)
+				if !items[0].Second.Equal(expected) {
+					t.Errorf("expected %v, got %v", expected, items[0].Second)
+				}
+			})
+
+			t.Run("invalid csvplusTZ", func(t *testing.T) {
+				type Item struct {
+					First time.Time `csvplusTZ:"Not/AZone"`
+				}
+
+				var items []Item
+				err := csvplus.Unmarshal([]byte("First\n2020-01-02T10:30:00Z"), &items)
+				if err == nil {
+					t.Fatal("expected error")
+				}
+			})
+
+			t.Run("unix epoch formats", func(t *testing.T) {
+				type Item struct {
+					First  time.Time  `csvplusFormat:"unix"`
+					Second time.Time  `csvplusFormat:"unixmilli"`
+					Third  *time.Time `csvplusFormat:"unixnano"`
+				}
+
+				data := []byte("First,Second,Third\n1577836800,1577836800123,1577836800123456789")
+				var items []Item
+				err := csvplus.Unmarshal(data, &items)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+				if items[0].First != expected {
+					t.Errorf("expected %v, got %v", expected, items[0].First)
+				}
+				expected = time.Date(2020, 1, 1, 0, 0, 0, 123000000, time.UTC)
+				if items[0].Second != expected {
+					t.Errorf("expected %v, got %v", expected, items[0].Second)
+				}
+				expected = time.Date(2020, 1, 1, 0, 0, 0, 123456789, time.UTC)
+				if *items[0].Third != expected {
+					t.Errorf("expected %v, got %v", expected, *items[0].Third)
+				}
+			})
+
 			t.Run("invalid format", func(t *testing.T) {
 				type Item struct {
 					First time.Time `csvplusFormat:"invalid format"`
@@ -448,12 +810,36 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 				data := []byte(fmt.Sprintf("First\n%s", dts))
 				var items []Item
 				err := csvplus.Unmarshal(data, &items)
-				expectedContent := "cannot parse \"2023-03\" as \"invalid format\""
+				expectedContent := fmt.Sprintf("cannot parse \"%s\" as \"invalid format\"", dts)
 				if !strings.Contains(err.Error(), expectedContent) {
 					t.Errorf("wrong error prefix, expected: '%s', got: %s", expectedContent, err.Error())
 				}
 			})
 		})
+
+		t.Run("Date", func(t *testing.T) {
+			type Item struct {
+				First  csvplus.Date
+				Second *csvplus.Date `csvplusFormat:"02/01/2006"`
+			}
+
+			data := []byte("First,Second\n2020-01-31,31/01/2020\n2020-02-01,")
+			var items []Item
+			err := csvplus.Unmarshal(data, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := csvplus.Date{Year: 2020, Month: time.January, Day: 31}
+			if items[0].First != expected {
+				t.Errorf("expected %v, got %v", expected, items[0].First)
+			}
+			if *items[0].Second != expected {
+				t.Errorf("expected %v, got %v", expected, *items[0].Second)
+			}
+			if items[1].Second != nil {
+				t.Errorf("expected nil, got %v", items[1].Second)
+			}
+		})
 	})
 
 	t.Run("header row", func(t *testing.T) {
@@ -555,6 +941,53 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 			}
 		})
 
+		t.Run("tag aliases", func(t *testing.T) {
+			type Item struct {
+				Email string `csvplus:"email|e-mail|email_address"`
+			}
+			var tests = []struct {
+				Name string
+				Data []byte
+			}{
+				{"first alias", []byte("email\na@example.com")},
+				{"second alias", []byte("e-mail\na@example.com")},
+				{"third alias", []byte("email_address\na@example.com")},
+				{"priority order", []byte("email_address,email\nb@example.com,a@example.com")},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.Name, func(t *testing.T) {
+					var items []Item
+					err := csvplus.Unmarshal(tt.Data, &items)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if items[0].Email != "a@example.com" {
+						t.Errorf("expected a@example.com, got: %s", items[0].Email)
+					}
+				})
+			}
+		})
+
+		t.Run("csvplusIndex ignores header names", func(t *testing.T) {
+			type Item struct {
+				First  string `csvplus:"first" csvplusIndex:"1"`
+				Second string `csvplusIndex:"0"`
+			}
+			data := []byte("unreliable,names\na,b")
+			var items []Item
+			err := csvplus.Unmarshal(data, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if items[0].First != "b" {
+				t.Errorf("expected 'b', got: %s", items[0].First)
+			}
+			if items[0].Second != "a" {
+				t.Errorf("expected 'a', got: %s", items[0].Second)
+			}
+		})
+
 		t.Run("skipped field -", func(t *testing.T) {
 			type Item struct {
 				First  string `csvplus:"-"`
@@ -578,6 +1011,35 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 		})
 	})
 
+	t.Run("error position", func(t *testing.T) {
+		type Item struct {
+			First  string
+			Second int
+		}
+		data := []byte("First,Second\n\"multi\nline\",1\nb,not int")
+		var items []Item
+		err := csvplus.Unmarshal(data, &items)
+		ue, ok := err.(csvplus.UnmarshalError)
+		if !ok {
+			t.Fatalf("expected UnmarshalError, got: %v", err)
+		}
+		if ue.Column != "Second" {
+			t.Errorf("expected column Second, got: %s", ue.Column)
+		}
+		if ue.Field != "Second" {
+			t.Errorf("expected field Second, got: %s", ue.Field)
+		}
+		if ue.Row != 2 {
+			t.Errorf("expected row 2, got: %d", ue.Row)
+		}
+		if ue.Line != 4 {
+			t.Errorf("expected line 4, got: %d", ue.Line)
+		}
+		if ue.Offset != 28 {
+			t.Errorf("expected offset 28, got: %d", ue.Offset)
+		}
+	})
+
 	t.Run("column naming errors", func(t *testing.T) {
 		t.Run("duplicate col name", func(t *testing.T) {
 			// duplicate name so we don't expect the data to be set in either column
@@ -604,37 +1066,130 @@ func TestUnmarshal(t *testing.T) { // nolint: gocyclo
 	})
 }
 
-func TestUnmarshalWithoutHeader(t *testing.T) { // nolint: gocyclo
-	t.Run("works", func(t *testing.T) {
-		type Item struct {
-			First  string `csvplus:"-"`
-			Second int
-			Third  bool
+func TestUnmarshalError(t *testing.T) {
+	t.Run("read error", func(t *testing.T) {
+		var items []struct{ First string }
+		err := csvplus.Unmarshal([]byte("First\na\"b"), &items)
+		var ue csvplus.UnmarshalError
+		if !errors.As(err, &ue) {
+			t.Fatalf("expected UnmarshalError, got: %v", err)
+		}
+		if ue.Row != 1 {
+			t.Errorf("expected row 1, got: %d", ue.Row)
 		}
+		var pe *csv.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("expected wrapped *csv.ParseError, got: %v", err)
+		}
+	})
 
-		data := []byte("1,true\n2,false")
-		var items []Item
-		err := csvplus.UnmarshalWithoutHeader(data, &items)
-		if err != nil {
-			t.Fatal(err)
+	t.Run("deprecated name", func(t *testing.T) {
+		var items []struct{ First int }
+		err := csvplus.Unmarshal([]byte("First\na"), &items)
+		if _, ok := err.(csvplus.UnmarhsalError); !ok {
+			t.Errorf("expected UnmarhsalError, got: %T", err)
 		}
-		if len(items) != 2 {
-			t.Error()
+	})
+}
+
+func TestUnmarshalErrorKinds(t *testing.T) {
+	t.Run("not slice pointer", func(t *testing.T) {
+		var items []struct{ First string }
+		err := csvplus.Unmarshal([]byte("First\na"), items)
+		if !errors.Is(err, csvplus.ErrNotSlicePtr) {
+			t.Errorf("expected ErrNotSlicePtr, got: %v", err)
 		}
-		if items[0].First != "" {
-			t.Errorf("expected empty string, got: %v", items[0].First)
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		var items []struct {
+			First string `csvplus:"first"`
 		}
-		if items[0].Second != 1 {
-			t.Errorf("expected 1, got: %v", items[0].First)
+		err := csvplus.NewDecoder(strings.NewReader("second\na")).RequireColumns(true).Decode(&items)
+		if !errors.Is(err, csvplus.ErrMissingColumn) {
+			t.Errorf("expected ErrMissingColumn, got: %v", err)
 		}
-		if items[0].Third != true {
-			t.Errorf("expected true, got: %v", items[1].First)
+	})
+
+	t.Run("type conversion", func(t *testing.T) {
+		var items []struct{ First int8 }
+		err := csvplus.Unmarshal([]byte("First\n1000"), &items)
+		if !errors.Is(err, csvplus.ErrTypeConversion) {
+			t.Errorf("expected ErrTypeConversion, got: %v", err)
 		}
-		if items[1].First != "" {
-			t.Errorf("expected empty string, got: %v", items[1].First)
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("expected wrapped strconv.ErrRange, got: %v", err)
 		}
-		if items[1].Second != 2 {
-			t.Errorf("expected 2, got: %v", items[1].First)
+		if errors.Is(err, csvplus.ErrBadTimeLayout) {
+			t.Error("didn't expect ErrBadTimeLayout")
+		}
+	})
+
+	t.Run("bad time layout", func(t *testing.T) {
+		var items []struct{ First time.Time }
+		err := csvplus.Unmarshal([]byte("First\n2020-01"), &items)
+		if !errors.Is(err, csvplus.ErrBadTimeLayout) {
+			t.Errorf("expected ErrBadTimeLayout, got: %v", err)
+		}
+		var pe *time.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("expected wrapped *time.ParseError, got: %v", err)
+		}
+	})
+}
+
+func TestUnmarshalSameTypeDifferentHeaders(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	for _, data := range [][]byte{
+		[]byte("First,Second\na,1"),
+		[]byte("Second,First\n1,a"),
+		[]byte("First,Second\na,1"),
+	} {
+		var items []Item
+		err := csvplus.Unmarshal(data, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items[0].First != "a" || items[0].Second != 1 {
+			t.Errorf("expected {a 1}, got: %v", items[0])
+		}
+	}
+}
+
+func TestUnmarshalWithoutHeader(t *testing.T) { // nolint: gocyclo
+	t.Run("works", func(t *testing.T) {
+		type Item struct {
+			First  string `csvplus:"-"`
+			Second int
+			Third  bool
+		}
+
+		data := []byte("1,true\n2,false")
+		var items []Item
+		err := csvplus.UnmarshalWithoutHeader(data, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 2 {
+			t.Error()
+		}
+		if items[0].First != "" {
+			t.Errorf("expected empty string, got: %v", items[0].First)
+		}
+		if items[0].Second != 1 {
+			t.Errorf("expected 1, got: %v", items[0].First)
+		}
+		if items[0].Third != true {
+			t.Errorf("expected true, got: %v", items[1].First)
+		}
+		if items[1].First != "" {
+			t.Errorf("expected empty string, got: %v", items[1].First)
+		}
+		if items[1].Second != 2 {
+			t.Errorf("expected 2, got: %v", items[1].First)
 		}
 		if items[1].Third != false {
 			t.Errorf("expected false, got: %v", items[1].First)
@@ -669,6 +1224,26 @@ func TestUnmarshalWithoutHeader(t *testing.T) { // nolint: gocyclo
 			t.Errorf("expected 2, got: %v", items[1].First)
 		}
 	})
+	t.Run("csvplusIndex", func(t *testing.T) {
+		type Item struct {
+			First string `csvplusIndex:"2"`
+			Third int    `csvplus:"third" csvplusIndex:"0"`
+		}
+
+		data := []byte("1,foo,a\n2,bar,b\n")
+		var items []Item
+		err := csvplus.UnmarshalWithoutHeader(data, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items[0].First != "a" {
+			t.Errorf("expected a, got: %v", items[0].First)
+		}
+		if items[1].Third != 2 {
+			t.Errorf("expected 2, got: %v", items[1].Third)
+		}
+	})
+
 	t.Run("extra fields in struct", func(t *testing.T) {
 		type Item struct {
 			First  string
@@ -682,6 +1257,13 @@ func TestUnmarshalWithoutHeader(t *testing.T) { // nolint: gocyclo
 		if err == nil {
 			t.Fatal("expected not enough columns in csv data error")
 		}
+		var ue csvplus.UnmarshalError
+		if !errors.As(err, &ue) {
+			t.Fatalf("expected UnmarshalError, got: %v", err)
+		}
+		if ue.Field != "Third" {
+			t.Errorf("expected field Third, got: %s", ue.Field)
+		}
 	})
 }
 
@@ -715,6 +1297,90 @@ func TestUnmarshalReader(t *testing.T) {
 	}
 }
 
+func ExampleDecoder_MapHeader() {
+	type Item struct {
+		FirstName string `csvplus:"first_name"`
+		LastName  string `csvplus:"last_name"`
+	}
+
+	data := []byte("First Name ,LAST NAME\nRob,Pike")
+
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).MapHeader(func(colName string) string {
+		return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(colName)), " ", "_")
+	})
+
+	var items []Item
+	err := dec.Decode(&items)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%+v\n", items[0])
+	// Output:
+	// {FirstName:Rob LastName:Pike}
+}
+
+func ExampleDecoder_Comment() {
+	type Item struct {
+		Name  string `csvplus:"name"`
+		Score int    `csvplus:"score"`
+	}
+
+	data := []byte("# exported 1999-11-01\nname;score\n# Ken didn't play\nRob;7\nRuss;9")
+
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).Comment('#').Comma(';')
+
+	var items []Item
+	err := dec.Decode(&items)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%+v\n", items)
+	// Output:
+	// [{Name:Rob Score:7} {Name:Russ Score:9}]
+}
+
+// sheetReader is an example RecordReader, it could wrap the rows of a sheet in an xlsx workbook.
+type sheetReader struct {
+	rows [][]string
+}
+
+func (sr *sheetReader) Read() ([]string, error) {
+	if len(sr.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := sr.rows[0]
+	sr.rows = sr.rows[1:]
+	return row, nil
+}
+
+func ExampleNewRecordDecoder() {
+	type Item struct {
+		Name      string     `csvplus:"name"`
+		Timestamp *time.Time `csvplus:"when" csvplusFormat:"2006-01"`
+	}
+
+	// eg the rows from a sheet in an xlsx workbook
+	rr := &sheetReader{rows: [][]string{
+		{"name", "when"},
+		{"Rob", "1999-11"},
+		{"Russ", ""},
+	}}
+
+	var items []Item
+	err := csvplus.NewRecordDecoder(rr).Decode(&items)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("{%s %s}\n", items[0].Name, items[0].Timestamp)
+	fmt.Printf("{%s %+v}\n", items[1].Name, items[1].Timestamp)
+	// Output:
+	// {Rob 1999-11-01 00:00:00 +0000 UTC}
+	// {Russ <nil>}
+}
+
 func ExampleDecoder_SetCSVReader() {
 	type Item struct {
 		Name      string     `csvplus:"name"`
@@ -745,275 +1411,2346 @@ func ExampleDecoder_SetCSVReader() {
 	// {Russ <nil>}
 }
 
-func TestMarshal(t *testing.T) { // nolint: gocyclo
-	t.Run("no tags", func(t *testing.T) {
-		type Item struct {
-			First  string
-			Second int
+func TestDecoder_RequireColumns(t *testing.T) {
+	type Item struct {
+		First  string `csvplus:"first"`
+		Second int    `csvplus:"second"`
+		Third  *bool  `csvplus:"third"`
+		Forth  string
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		data := []byte("first\na")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).RequireColumns(true).Decode(&items)
+		if err == nil {
+			t.Fatal("expected error")
 		}
-		items := []Item{
-			{
-				"a",
-				1,
-			},
-			{
-				"b",
-				2,
-			},
+		expectedContent := "missing columns in csv data: second, third"
+		if !strings.Contains(err.Error(), expectedContent) {
+			t.Errorf("wrong error, expected: '%s', got: %s", expectedContent, err.Error())
 		}
-		data, err := csvplus.Marshal(&items)
+	})
+
+	t.Run("all present", func(t *testing.T) {
+		data := []byte("first,second,third\na,1,true")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).RequireColumns(true).Decode(&items)
 		if err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("First,Second\na,1\nb,2\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if len(items) != 1 {
+			t.Errorf("expected len of %d, got: %d", 1, len(items))
 		}
 	})
+}
 
-	t.Run("col tags work", func(t *testing.T) {
-		type Item struct {
-			First  string `csvplus:"first"`
-			Second int    `csvplus:"second"`
-		}
-		items := []Item{
-			{
-				"a",
-				1,
-			},
-			{
-				"b",
-				2,
-			},
-		}
-		data, err := csvplus.Marshal(&items)
+func TestDecoder_LooseHeaderMatching(t *testing.T) {
+	type Item struct {
+		CreatedAt string
+		UserID    int    `csvplus:"user_id"`
+		LastName  string `csvplus:"LastName"`
+	}
+	data := []byte("Created At,USER-ID,last_name\n2020-01-01,7,Pike")
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).LooseHeaderMatching(true).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].CreatedAt != "2020-01-01" {
+		t.Errorf("expected 2020-01-01, got: %s", items[0].CreatedAt)
+	}
+	if items[0].UserID != 7 {
+		t.Errorf("expected 7, got: %d", items[0].UserID)
+	}
+	if items[0].LastName != "Pike" {
+		t.Errorf("expected Pike, got: %s", items[0].LastName)
+	}
+}
+
+func TestDecoder_DuplicateHeaders(t *testing.T) {
+	type Item struct {
+		Name  string `csvplus:"name"`
+		Name2 string `csvplus:"name_2"`
+		Age   int    `csvplus:"age"`
+	}
+	data := []byte("name,age,name\nRob,1,Bob\n")
+
+	tests := []struct {
+		policy   csvplus.DuplicateHeaders
+		expected Item
+	}{
+		{csvplus.DuplicateHeadersLast, Item{Name: "Bob", Age: 1}},
+		{csvplus.DuplicateHeadersFirst, Item{Name: "Rob", Age: 1}},
+		{csvplus.DuplicateHeadersSuffix, Item{Name: "Rob", Name2: "Bob", Age: 1}},
+	}
+	for _, tt := range tests {
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).DuplicateHeaders(tt.policy).Decode(&items)
 		if err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("first,second\na,1\nb,2\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if len(items) != 1 || items[0] != tt.expected {
+			t.Errorf("policy %d: expected %+v, got: %+v", tt.policy, tt.expected, items)
 		}
-	})
+	}
 
-	t.Run("skip field", func(t *testing.T) {
-		type Item struct {
-			First  string `csvplus:"-"`
-			Second int    `csvplus:"second"`
-		}
-		items := []Item{
-			{
-				"a",
-				1,
-			},
-			{
-				"b",
-				2,
-			},
-		}
-		data, err := csvplus.Marshal(&items)
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).DuplicateHeaders(csvplus.DuplicateHeadersError).Decode(&items)
+	if !errors.Is(err, csvplus.ErrDuplicateColumn) || !strings.Contains(err.Error(), "name") {
+		t.Errorf("expected ErrDuplicateColumn for name, got: %v", err)
+	}
+}
+
+func TestDecoder_HeaderFieldMap(t *testing.T) {
+	type Item struct {
+		Name   string     `csvplus:"name"`
+		Age    int        `csvplus:"age"`
+		Coords [2]float64 `csvplus:"coords"`
+		Other  string
+	}
+	data := []byte("Name,age,unused,coords[0],coords[1]\nRob,42,x,1.5,2\n")
+
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).MapHeader(strings.ToLower)
+	if dec.Header() != nil || dec.FieldMap() != nil {
+		t.Error("expected nil header and field map before decoding")
+	}
+	var items []Item
+	if err := dec.Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"name", "age", "unused", "coords[0]", "coords[1]"}; !reflect.DeepEqual(dec.Header(), expected) {
+		t.Errorf("expected header %q, got: %q", expected, dec.Header())
+	}
+	expected := map[string]string{"name": "Name", "age": "Age", "coords[0]": "Coords", "coords[1]": "Coords"}
+	if !reflect.DeepEqual(dec.FieldMap(), expected) {
+		t.Errorf("expected field map %v, got: %v", expected, dec.FieldMap())
+	}
+
+	dec = csvplus.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Header()[0] != "Name" {
+		t.Errorf("expected header from ReadRecord, got: %q", dec.Header())
+	}
+}
+
+func TestDecoder_SkipRows(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	data := []byte("Sales export\n\nGenerated:,2020-01-01,by admin\nFirst,Second\na,1\nb,2")
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).SkipRows(2).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected len of %d, got: %d", 2, len(items))
+	}
+	if items[1].First != "b" {
+		t.Errorf("expected 'b', got: %s", items[1].First)
+	}
+	if items[1].Second != 2 {
+		t.Errorf("expected 2, got: %d", items[1].Second)
+	}
+}
+
+func TestDecoder_SkipFooterRows(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+
+	t.Run("works", func(t *testing.T) {
+		data := []byte("First,Second\na,1\nb,2\nTOTAL,3\nexported by,admin,2020-01-01")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).SkipFooterRows(2).Decode(&items)
 		if err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("second\n1\n2\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if len(items) != 2 {
+			t.Fatalf("expected len of %d, got: %d", 2, len(items))
+		}
+		if items[1].First != "b" {
+			t.Errorf("expected 'b', got: %s", items[1].First)
 		}
 	})
 
-	t.Run("time.Time format", func(t *testing.T) {
-		type Item struct {
-			First time.Time `csvplusFormat:"2006-01"`
+	t.Run("wrong number of fields in data row", func(t *testing.T) {
+		data := []byte("First,Second\na,1\nb\nTOTAL,3")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).SkipFooterRows(1).Decode(&items)
+		if err == nil {
+			t.Fatal("expected error")
 		}
+	})
+}
 
-		tm, _ := time.Parse("2006-01", "2010-01")
+func TestDecoder_StopAt(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	data := []byte("First,Second\na,1\nb,2\nTOTAL\nc,3")
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).StopAt(func(record []string) bool {
+		return record[0] == "TOTAL"
+	}).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected len of %d, got: %d", 2, len(items))
+	}
+	if items[1].First != "b" {
+		t.Errorf("expected 'b', got: %s", items[1].First)
+	}
+}
 
-		items := []Item{
-			{
-				tm,
-			},
-		}
-		data, err := csvplus.Marshal(&items)
-		if err != nil {
-			t.Fatal(err)
-		}
-		expectedData := []byte("First\n2010-01\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
-		}
+func TestDecoder_StrictEmpty(t *testing.T) {
+	type Item struct {
+		Name   string
+		Age    int
+		Score  *float64
+		Active bool `csvplus:"Active,strictempty"`
+	}
+	data := []byte("Name,Age,Score,Active\n,1,,true\nRob,,2.5,false\n")
+
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).StrictEmpty(true).Decode(&items)
+	var ue csvplus.UnmarshalError
+	if !errors.Is(err, csvplus.ErrEmptyValue) || !errors.As(err, &ue) || ue.Row != 2 || ue.Field != "Age" {
+		t.Errorf("expected ErrEmptyValue for Age on row 2, got: %v", err)
+	}
+
+	// only the field with the strictempty tag option is strict by default
+	items = nil
+	if err := csvplus.Unmarshal(data, &items); err != nil {
+		t.Fatal(err)
+	}
+	err = csvplus.Unmarshal([]byte("Name,Age,Score,Active\nRob,1,,\n"), &items)
+	if !errors.As(err, &ue) || ue.Field != "Active" || !errors.Is(err, csvplus.ErrEmptyValue) {
+		t.Errorf("expected ErrEmptyValue for Active, got: %v", err)
+	}
+}
+
+func TestDecoder_Filter(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	// rows with a bad Second value are filtered out before they're converted
+	data := []byte("First,Second\na,1\nskip,x\nb,2\nc,3\n")
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).Filter(func(record []string) bool {
+		return record[0] != "skip"
+	}).FilterDecoded(func(v interface{}) bool {
+		return v.(*Item).Second != 2
+	}).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Item{{"a", 1}, {"c", 3}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %v, got: %v", expected, items)
+	}
+
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).Filter(func(record []string) bool {
+		return record[0] == "c"
 	})
+	var item Item
+	if err := dec.DecodeOneStruct(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item != (Item{"c", 3}) {
+		t.Errorf("expected c, got: %v", item)
+	}
+}
 
-	t.Run("pointer field", func(t *testing.T) {
-		type Item struct {
-			First *bool
-		}
+func TestDecoder_OnError(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	data := []byte("First,Second\na,1\nb,not int\nc\nd,4")
 
-		yes := true
-		items := []Item{
-			{
-				&yes,
-			},
-			{},
-		}
-		data, err := csvplus.Marshal(&items)
+	t.Run("skip", func(t *testing.T) {
+		var badRows []int
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).OnError(func(row int, record []string, err error) error {
+			badRows = append(badRows, row)
+			return nil
+		}).Decode(&items)
 		if err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("First\ntrue\n\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if len(items) != 2 {
+			t.Fatalf("expected len of %d, got: %d", 2, len(items))
+		}
+		if items[1].First != "d" {
+			t.Errorf("expected 'd', got: %s", items[1].First)
+		}
+		if fmt.Sprint(badRows) != "[2 3]" {
+			t.Errorf("expected bad rows [2 3], got: %v", badRows)
 		}
 	})
 
-	t.Run("uint", func(t *testing.T) {
-		type Item struct {
-			First uint
+	t.Run("abort", func(t *testing.T) {
+		abortErr := fmt.Errorf("abort")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).OnError(func(row int, record []string, err error) error {
+			return abortErr
+		}).Decode(&items)
+		if err != abortErr {
+			t.Fatalf("expected abort error, got: %v", err)
 		}
+		if len(items) != 1 {
+			t.Errorf("expected len of %d, got: %d", 1, len(items))
+		}
+	})
+}
 
-		items := []Item{
-			{
-				10,
-			},
-			{},
+func TestDecoder_DirtyData(t *testing.T) {
+	type Item struct {
+		First  string
+		Second string
+		Third  *int
+	}
+	data := "First,Second,Third\na \"b\",c,1\nd,e\nf,g,2,extra\n"
+
+	t.Run("strict", func(t *testing.T) {
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader(data)).Decode(&items)
+		var pe *csv.ParseError
+		if !errors.As(err, &pe) || pe.Err != csv.ErrBareQuote {
+			t.Errorf("expected ErrBareQuote, got: %v", err)
 		}
-		data, err := csvplus.Marshal(&items)
+	})
+
+	t.Run("tolerant", func(t *testing.T) {
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader(data)).
+			AllowLazyQuotes(true).
+			PadShortRows(true).
+			TruncateLongRows(true).
+			Decode(&items)
 		if err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("First\n10\n0\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if len(items) != 3 {
+			t.Fatalf("expected len of %d, got: %d", 3, len(items))
+		}
+		if items[0].First != `a "b"` || items[1].Third != nil || items[2].Second != "g" || *items[2].Third != 2 {
+			t.Errorf("unexpected items: %+v", items)
 		}
 	})
 
-	t.Run("float", func(t *testing.T) {
-		type Item struct {
-			First float64
+	t.Run("pad only", func(t *testing.T) {
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader(data)).AllowLazyQuotes(true).PadShortRows(true).Decode(&items)
+		var pe *csv.ParseError
+		if !errors.As(err, &pe) || pe.Err != csv.ErrFieldCount || pe.Line != 4 {
+			t.Errorf("expected ErrFieldCount on line 4, got: %v", err)
 		}
+	})
+}
 
-		items := []Item{
-			{
-				10.05,
-			},
-			{},
-		}
-		data, err := csvplus.Marshal(&items)
-		if err != nil {
-			t.Fatal(err)
+func TestDecoder_SkipUnmappedColumns(t *testing.T) {
+	type Item struct {
+		B string `csvplus:"b"`
+		D *int   `csvplus:"d"`
+	}
+	data := "a,b,c,d,e,f\r\n" +
+		"1,x,2,3,4,5\r\n" +
+		"\n" +
+		"#,comment,,,,\n" +
+		"1,\"multi\nline\",\"q,\"\"\",4,5,6\n" +
+		"1,y,\"c\ncc\",6,7,8\n" +
+		"1,z,2,bad,4,5\n" +
+		"1,short\n" +
+		"1, sp,2,\"oops\"x,4,5\n" +
+		"1,w,2,7,4,\"\n" +
+		"end\",x\n" +
+		"1,last,2,8,4,5\r"
+
+	decode := func(skip bool, opts func(*csvplus.Decoder)) ([]Item, []string) {
+		var errs []string
+		dec := csvplus.NewDecoder(strings.NewReader(data)).SkipUnmappedColumns(skip).
+			OnError(func(row int, record []string, err error) error {
+				errs = append(errs, err.Error())
+				return nil
+			})
+		if opts != nil {
+			opts(dec)
 		}
-		expectedData := []byte("First\n10.05\n0\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		var items []Item
+		if err := dec.Decode(&items); err != nil {
+			errs = append(errs, err.Error())
 		}
+		return items, errs
+	}
+
+	tests := map[string]func(*csvplus.Decoder){
+		"default":            nil,
+		"comment":            func(dec *csvplus.Decoder) { dec.Comment('#') },
+		"trim leading space": func(dec *csvplus.Decoder) { dec.TrimLeadingSpace(true) },
+		"lazy quotes":        func(dec *csvplus.Decoder) { dec.AllowLazyQuotes(true).PadShortRows(true) },
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			expectedItems, expectedErrs := decode(false, opts)
+			items, errs := decode(true, opts)
+			if !reflect.DeepEqual(items, expectedItems) {
+				t.Errorf("expected %+v, got: %+v", expectedItems, items)
+			}
+			if !reflect.DeepEqual(errs, expectedErrs) {
+				t.Errorf("expected errors %q, got: %q", expectedErrs, errs)
+			}
+		})
+	}
+}
+
+func TestDecoder_SizeHint(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	data := []byte("First,Second\na,1\nb,2")
+	items := []Item{{"z", 0}}
+	err := csvplus.NewDecoder(bytes.NewReader(data)).SizeHint(10).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected len of %d, got: %d", 3, len(items))
+	}
+	if cap(items) != 11 {
+		t.Errorf("expected cap of %d, got: %d", 11, cap(items))
+	}
+	if items[0].First != "z" || items[2].First != "b" {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	// the lines are counted first
+	items = nil
+	if err := csvplus.UnmarshalWithSizeHint(data, &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || cap(items) != 3 {
+		t.Errorf("expected len 2 and cap 3, got: %d and %d", len(items), cap(items))
+	}
+}
+
+func TestDecoder_Converter(t *testing.T) {
+	type Item struct {
+		Code   string `csvplusConv:"trim,upper"`
+		Amount int    `csvplusConv:"failing"`
+	}
+	dec := csvplus.NewDecoder(strings.NewReader("Code,Amount\n\" ab \",\"1,0\"\n"))
+	dec.Converter("trim", func(s string) (string, error) { return strings.TrimSpace(s), nil })
+	dec.Converter("upper", func(s string) (string, error) { return strings.ToUpper(s), nil })
+	dec.Converter("failing", func(s string) (string, error) {
+		return "", fmt.Errorf("unexpected value %s", s)
 	})
 
-	t.Run("MarshalCSV pointer", func(t *testing.T) {
-		type Item struct {
-			First *YesNoBool
-		}
+	var items []Item
+	err := dec.Decode(&items)
+	if !errors.Is(err, csvplus.ErrTypeConversion) {
+		t.Fatalf("expected ErrTypeConversion, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "converter failing: unexpected value 1,0") {
+		t.Errorf("unexpected error: %v", err)
+	}
 
-		yes := YesNoBool(true)
-		items := []Item{
-			{
-				&yes,
-			},
-			{},
+	t.Run("applied in order", func(t *testing.T) {
+		var items []struct {
+			Code string `csvplusConv:"trim,upper"`
 		}
-		data, err := csvplus.Marshal(&items)
-		if err != nil {
+		dec := csvplus.NewDecoder(strings.NewReader("Code\n\" ab \"\n"))
+		dec.Converter("trim", func(s string) (string, error) { return strings.TrimSpace(s), nil })
+		dec.Converter("upper", func(s string) (string, error) { return strings.ToUpper(s), nil })
+		if err := dec.Decode(&items); err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("First\nyes\nno\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if items[0].Code != "AB" {
+			t.Errorf("expected AB, got: %q", items[0].Code)
 		}
 	})
 
-	t.Run("MarshalCSV non pointer", func(t *testing.T) {
-		type Item struct {
-			First YesNoBool
+	t.Run("unknown converter", func(t *testing.T) {
+		var items []struct {
+			Code string `csvplusConv:"upper"`
 		}
-
-		yes := YesNoBool(true)
-		no := YesNoBool(false)
-		items := []Item{
-			{yes},
-			{no},
+		err := csvplus.Unmarshal([]byte("Code\nab"), &items)
+		if err == nil || !strings.Contains(err.Error(), "unknown converter upper") {
+			t.Errorf("expected unknown converter error, got: %v", err)
 		}
-		data, err := csvplus.Marshal(&items)
+	})
+}
+
+// hookedItem implements BeforeUnmarshaler, AfterUnmarshaler and BeforeMarshaler.
+type hookedItem struct {
+	Name     string
+	Quantity int
+	Price    float64
+	Total    float64
+	Columns  int `csvplus:"-"`
+}
+
+func (hi *hookedItem) BeforeUnmarshalCSV(record []string) error {
+	hi.Columns = len(record)
+	return nil
+}
+
+func (hi *hookedItem) AfterUnmarshalCSV() error {
+	if hi.Quantity < 0 {
+		return fmt.Errorf("negative quantity %d", hi.Quantity)
+	}
+	hi.Name = strings.TrimSpace(hi.Name)
+	return nil
+}
+
+func (hi *hookedItem) BeforeMarshalCSV() error {
+	hi.Total = float64(hi.Quantity) * hi.Price
+	return nil
+}
+
+func TestRowHooks(t *testing.T) {
+	t.Run("unmarshal", func(t *testing.T) {
+		data := []byte("Name,Quantity,Price\n\" a \",2,1.5\nb,-1,1")
+		var items []hookedItem
+		var rowErrs []error
+		err := csvplus.NewDecoder(bytes.NewReader(data)).OnError(func(row int, record []string, err error) error {
+			rowErrs = append(rowErrs, err)
+			return nil
+		}).Decode(&items)
 		if err != nil {
 			t.Fatal(err)
 		}
-		expectedData := []byte("First\nyes\nno\n")
-		if string(data) != string(expectedData) {
-			t.Errorf("expected: %s, got: %s", expectedData, data)
+		if len(items) != 1 {
+			t.Fatalf("expected len of %d, got: %d", 1, len(items))
 		}
-	})
-
-	t.Run("string pointer fails", func(t *testing.T) {
-		a := "not a pointer to a slice"
-		_, err := csvplus.Marshal(&a)
-		if err == nil {
-			t.Error("expected error")
+		if items[0].Name != "a" || items[0].Columns != 3 {
+			t.Errorf("unexpected item: %+v", items[0])
 		}
-		expectedPrefix := "expected slice"
-		if !strings.HasPrefix(err.Error(), expectedPrefix) {
-			t.Errorf("wrong error prefix, expected: '%s', got: %s", expectedPrefix, err.Error())
+		if len(rowErrs) != 1 || !strings.Contains(rowErrs[0].Error(), "AfterUnmarshalCSV(): negative quantity -1") {
+			t.Errorf("unexpected row errors: %v", rowErrs)
+		}
+		var ue csvplus.UnmarshalError
+		if !errors.As(rowErrs[0], &ue) || ue.Row != 2 || ue.Line != 3 {
+			t.Errorf("expected UnmarshalError for row 2 on line 3, got: %#v", rowErrs[0])
 		}
 	})
 
-	t.Run("slice value fails", func(t *testing.T) {
-		var items []string
-		_, err := csvplus.Marshal(items)
-		if err == nil {
-			t.Error("expected error")
+	t.Run("marshal", func(t *testing.T) {
+		items := []hookedItem{{Name: "a", Quantity: 2, Price: 1.5}}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
 		}
-		expectedPrefix := "non pointer"
-		if !strings.HasPrefix(err.Error(), expectedPrefix) {
-			t.Errorf("wrong error prefix, expected: '%s', got: %s", expectedPrefix, err.Error())
+		expectedData := "Name,Quantity,Price,Total\na,2,1.5,3\n"
+		if expectedData != string(data) {
+			t.Errorf("incorrect output, expected: %q, got: %q", expectedData, string(data))
 		}
 	})
 }
 
-func TestMarshalReader(t *testing.T) {
+func TestUnmarshalMarshalPointerElements(t *testing.T) {
 	type Item struct {
 		First  string
 		Second int
 	}
-	items := []Item{
-		{"a", 1},
-		{"b", 2},
-	}
-	var buf bytes.Buffer
-	err := csvplus.MarshalWriter(&items, &buf)
+	data := []byte("First,Second\na,1\nb,2\n")
+
+	var items []*Item
+	err := csvplus.Unmarshal(data, &items)
 	if err != nil {
 		t.Fatal(err)
 	}
-	expectedData := "First,Second\na,1\nb,2\n"
+	if len(items) != 2 {
+		t.Fatalf("expected len of %d, got: %d", 2, len(items))
+	}
+	if *items[1] != (Item{"b", 2}) {
+		t.Errorf("unexpected item: %+v", *items[1])
+	}
 
-	if expectedData != buf.String() {
-		t.Errorf("incorrect output, expected: %s, got: %s", expectedData, buf.String())
+	b, err := csvplus.Marshal(&items)
+	if err != nil {
+		t.Fatal(err)
 	}
+	if string(b) != string(data) {
+		t.Errorf("incorrect output, expected: %q, got: %q", data, b)
+	}
+
+	t.Run("nil element", func(t *testing.T) {
+		items := []*Item{{"a", 1}, nil}
+		_, err := csvplus.Marshal(&items)
+		var ee csvplus.EncodeError
+		if !errors.As(err, &ee) || ee.Row != 1 {
+			t.Errorf("expected EncodeError for row 1, got: %v", err)
+		}
+	})
 }
 
-func TestMarshalWithoutHeader(t *testing.T) {
-	type Item struct {
-		First  string `csvplus:"-"`
-		Second int
-		Third  bool
-	}
-	items := []Item{
-		{"a", 1, true},
-		{"b", 2, false},
+func TestUnmarshalMarshalArrayFields(t *testing.T) {
+	type Point struct {
+		Name   string     `csvplus:"name"`
+		Coords [3]float64 `csvplus:"coords"`
+		RGB    [3]uint8   `csvplus:"rgb,delim=;"`
 	}
+	data := []byte("name,coords[0],coords[1],coords[2],rgb\na,1.5,2,-3,255;0;10\n")
 
-	data, err := csvplus.MarshalWithoutHeader(&items)
+	var points []Point
+	err := csvplus.Unmarshal(data, &points)
 	if err != nil {
 		t.Fatal(err)
 	}
+	expected := Point{"a", [3]float64{1.5, 2, -3}, [3]uint8{255, 0, 10}}
+	if len(points) != 1 || points[0] != expected {
+		t.Fatalf("expected %v, got: %v", expected, points)
+	}
 
-	expectedData := "1,true\n2,false\n"
-	if string(data) != expectedData {
-		t.Errorf("incorrect output, expected: %s, got: %s", expectedData, data)
+	b, err := csvplus.Marshal(&points)
+	if err != nil {
+		t.Fatal(err)
 	}
+	if string(b) != string(data) {
+		t.Errorf("incorrect output, expected: %q, got: %q", data, b)
+	}
+
+	t.Run("without header", func(t *testing.T) {
+		var points []struct {
+			Coords [2]int
+			Name   string
+		}
+		err := csvplus.UnmarshalWithoutHeader([]byte("1,2,a\n"), &points)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if points[0].Coords != [2]int{1, 2} || points[0].Name != "a" {
+			t.Errorf("unexpected point: %v", points[0])
+		}
+	})
+
+	t.Run("wrong number of values", func(t *testing.T) {
+		var points []Point
+		err := csvplus.Unmarshal([]byte("name,coords[0],coords[1],coords[2],rgb\na,1,2,3,2;3\n"), &points)
+		if !errors.Is(err, csvplus.ErrTypeConversion) {
+			t.Errorf("expected ErrTypeConversion, got: %v", err)
+		}
+	})
+}
+
+func TestUnmarshalSingle(t *testing.T) {
+	type Config struct {
+		Name    string `csvplus:"name"`
+		Retries int    `csvplus:"retries"`
+	}
+
+	var cfg Config
+	err := csvplus.UnmarshalSingle([]byte("name,retries\nsync,3\n"), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != (Config{"sync", 3}) {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	t.Run("no data row", func(t *testing.T) {
+		var cfg Config
+		err := csvplus.UnmarshalSingle([]byte("name,retries\n"), &cfg)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("not struct pointer", func(t *testing.T) {
+		var cfgs []Config
+		err := csvplus.UnmarshalSingle([]byte("name,retries\nsync,3\n"), &cfgs)
+		if !errors.Is(err, csvplus.ErrNotStructPtr) {
+			t.Errorf("expected ErrNotStructPtr, got: %v", err)
+		}
+	})
+}
+
+func TestDecoder_DecodeOneStruct(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	data := []byte("First,Second\na,1\nb,x\n,3\n")
+
+	var rowErrs []int
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).OnError(func(row int, record []string, err error) error {
+		rowErrs = append(rowErrs, row)
+		return nil
+	})
+
+	var items []Item
+	for {
+		var item Item
+		err := dec.DecodeOneStruct(&item)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+
+	expected := []Item{{"a", 1}, {"", 3}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %v, got: %v", expected, items)
+	}
+	if !reflect.DeepEqual(rowErrs, []int{2}) {
+		t.Errorf("expected error for row 2, got: %v", rowErrs)
+	}
+}
+
+func TestDecoder_DecodeBatch(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	data := []byte("First,Second\na,1\nb,2\nc,3\nd,4\ne,5\n")
+	dec := csvplus.NewDecoder(bytes.NewReader(data))
+
+	var batches [][]Item
+	var items []Item
+	for {
+		err := dec.DecodeBatch(2, &items)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, append([]Item(nil), items...))
+	}
+
+	expected := [][]Item{{{"a", 1}, {"b", 2}}, {{"c", 3}, {"d", 4}}, {{"e", 5}}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("expected %v, got: %v", expected, batches)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected empty slice after io.EOF, got: %v", items)
+	}
+}
+
+func TestUnmarshalMarshalCharFields(t *testing.T) {
+	type Item struct {
+		Grade  byte  `csvplus:"grade,char"`
+		Symbol rune  `csvplus:"symbol,char"`
+		Flag   *rune `csvplus:"flag,char"`
+		Code   byte  `csvplus:"code"`
+	}
+	data := []byte("grade,symbol,flag,code\nA,€,,65\n")
+
+	var items []Item
+	err := csvplus.Unmarshal(data, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Grade != 'A' || items[0].Symbol != '€' || items[0].Flag != nil || items[0].Code != 65 {
+		t.Errorf("unexpected item: %+v", items[0])
+	}
+
+	b, err := csvplus.Marshal(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(data) {
+		t.Errorf("incorrect output, expected: %q, got: %q", data, b)
+	}
+
+	for _, record := range []string{"AB,x,,1", "€,x,,1"} {
+		err := csvplus.Unmarshal([]byte("grade,symbol,flag,code\n"+record), &items)
+		if !errors.Is(err, csvplus.ErrTypeConversion) {
+			t.Errorf("%s: expected ErrTypeConversion, got: %v", record, err)
+		}
+	}
+}
+
+func TestUnmarshalMarshalBytesFields(t *testing.T) {
+	type Item struct {
+		Raw    []byte
+		Base64 []byte  `csvplusFormat:"base64"`
+		URL    []byte  `csvplusFormat:"base64url"`
+		Hex    *[]byte `csvplusFormat:"hex"`
+	}
+	data := []byte("Raw,Base64,URL,Hex\nab,/+8=,_-8=,deadbeef\n")
+
+	var items []Item
+	err := csvplus.Unmarshal(data, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := items[0]
+	if string(item.Raw) != "ab" || !bytes.Equal(item.Base64, []byte{0xff, 0xef}) || !bytes.Equal(item.URL, []byte{0xff, 0xef}) {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if item.Hex == nil || !bytes.Equal(*item.Hex, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("unexpected Hex: %v", item.Hex)
+	}
+
+	b, err := csvplus.Marshal(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(data) {
+		t.Errorf("incorrect output, expected: %q, got: %q", data, b)
+	}
+
+	err = csvplus.Unmarshal([]byte("Raw,Base64,URL,Hex\nab,!!,,xyz\n"), &items)
+	if !errors.Is(err, csvplus.ErrTypeConversion) {
+		t.Errorf("expected ErrTypeConversion, got: %v", err)
+	}
+}
+
+func TestUnmarshalMarshalNetFields(t *testing.T) {
+	type Rule struct {
+		Source  net.IP
+		Dest    netip.Addr
+		Network netip.Prefix
+		Gateway *netip.Addr
+	}
+	data := []byte("Source,Dest,Network,Gateway\n10.0.0.1,2001:db8::1,192.168.0.0/16,\n")
+
+	var rules []Rule
+	err := csvplus.Unmarshal(data, &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := rules[0]
+	if !rule.Source.Equal(net.IPv4(10, 0, 0, 1)) || rule.Dest != netip.MustParseAddr("2001:db8::1") ||
+		rule.Network != netip.MustParsePrefix("192.168.0.0/16") || rule.Gateway != nil {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+
+	b, err := csvplus.Marshal(&rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(data) {
+		t.Errorf("incorrect output, expected: %q, got: %q", data, b)
+	}
+
+	err = csvplus.Unmarshal([]byte("Source,Dest,Network,Gateway\n10.0.0.300,,,\n"), &rules)
+	if !errors.Is(err, csvplus.ErrTypeConversion) {
+		t.Errorf("expected ErrTypeConversion, got: %v", err)
+	}
+}
+
+func TestDecoder_BOM(t *testing.T) {
+	type Item struct {
+		Name  string `csvplus:"name"`
+		Score int    `csvplus:"score"`
+	}
+
+	utf16Data := func(order binary.ByteOrder, bom []byte, s string) []byte {
+		data := append([]byte(nil), bom...)
+		for _, u := range utf16.Encode([]rune(s)) {
+			b := make([]byte, 2)
+			order.PutUint16(b, u)
+			data = append(data, b...)
+		}
+		return data
+	}
+
+	var tests = []struct {
+		Name string
+		Data []byte
+	}{
+		{"UTF-8", append([]byte{0xEF, 0xBB, 0xBF}, "name,score\nRenée 😀,7"...)},
+		{"UTF-16LE", utf16Data(binary.LittleEndian, []byte{0xFF, 0xFE}, "name,score\nRenée 😀,7")},
+		{"UTF-16BE", utf16Data(binary.BigEndian, []byte{0xFE, 0xFF}, "name,score\nRenée 😀,7")},
+		{"no BOM", []byte("name,score\nRenée 😀,7")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var items []Item
+			err := csvplus.Unmarshal(tt.Data, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("expected len of %d, got: %d", 1, len(items))
+			}
+			if items[0].Name != "Renée 😀" {
+				t.Errorf("expected 'Renée 😀', got: %s", items[0].Name)
+			}
+			if items[0].Score != 7 {
+				t.Errorf("expected 7, got: %d", items[0].Score)
+			}
+		})
+	}
+}
+
+func TestDecoder_Charset(t *testing.T) {
+	type Item struct {
+		Name string `csvplus:"name"`
+	}
+
+	t.Run("iso-8859-1", func(t *testing.T) {
+		data := []byte("name\nRen\xe9e")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).Charset("iso-8859-1").Decode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items[0].Name != "Renée" {
+			t.Errorf("expected 'Renée', got: %s", items[0].Name)
+		}
+	})
+
+	t.Run("BOM", func(t *testing.T) {
+		data := []byte("\xef\xbb\xbfname\nRen\xe9e")
+		var items []Item
+		err := csvplus.NewDecoder(bytes.NewReader(data)).Charset("windows-1252").Decode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].Name != "Renée" {
+			t.Errorf("expected 'Renée', got: %v", items)
+		}
+	})
+
+	t.Run("unknown charset", func(t *testing.T) {
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader("name\na")).Charset("not-a-charset").Decode(&items)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestDecoder_GroupingSeparators(t *testing.T) {
+	type Item struct {
+		First  int
+		Second float64
+		Third  uint
+	}
+	data := []byte("First,Second,Third\n\"1,234,567\",1 234.5,1 000")
+	var items []Item
+	err := csvplus.NewDecoder(bytes.NewReader(data)).GroupingSeparators(", ").Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].First != 1234567 {
+		t.Errorf("expected 1234567, got: %d", items[0].First)
+	}
+	if items[0].Second != 1234.5 {
+		t.Errorf("expected 1234.5, got: %f", items[0].Second)
+	}
+	if items[0].Third != 1000 {
+		t.Errorf("expected 1000, got: %d", items[0].Third)
+	}
+}
+
+func TestDecoder_ReadRecord(t *testing.T) {
+	data := []byte("Sales export\nFirst,Second\na,1\nb,2\nTotal,3")
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).SkipRows(1).SkipFooterRows(1)
+
+	var records [][]string
+	for {
+		record, err := dec.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, append([]string(nil), record...))
+	}
+
+	expected := [][]string{{"First", "Second"}, {"a", "1"}, {"b", "2"}}
+	if !reflect.DeepEqual(expected, records) {
+		t.Errorf("expected %v, got: %v", expected, records)
+	}
+}
+
+func TestMarshal(t *testing.T) { // nolint: gocyclo
+	t.Run("no tags", func(t *testing.T) {
+		type Item struct {
+			First  string
+			Second int
+		}
+		items := []Item{
+			{
+				"a",
+				1,
+			},
+			{
+				"b",
+				2,
+			},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First,Second\na,1\nb,2\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("col tags work", func(t *testing.T) {
+		type Item struct {
+			First  string `csvplus:"first"`
+			Second int    `csvplus:"second"`
+		}
+		items := []Item{
+			{
+				"a",
+				1,
+			},
+			{
+				"b",
+				2,
+			},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("first,second\na,1\nb,2\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("tag aliases use first alias", func(t *testing.T) {
+		type Item struct {
+			Email string `csvplus:"email|e-mail"`
+		}
+		items := []Item{
+			{"a@example.com"},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("email\na@example.com\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("skip field", func(t *testing.T) {
+		type Item struct {
+			First  string `csvplus:"-"`
+			Second int    `csvplus:"second"`
+		}
+		items := []Item{
+			{
+				"a",
+				1,
+			},
+			{
+				"b",
+				2,
+			},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("second\n1\n2\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("time.Time format", func(t *testing.T) {
+		type Item struct {
+			First time.Time `csvplusFormat:"2006-01"`
+		}
+
+		tm, _ := time.Parse("2006-01", "2010-01")
+
+		items := []Item{
+			{
+				tm,
+			},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\n2010-01\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("time.Time csvplusTZ", func(t *testing.T) {
+		type Item struct {
+			First time.Time `csvplusFormat:"2006-01-02 15:04" csvplusTZ:"America/New_York"`
+		}
+
+		items := []Item{
+			{time.Date(2020, 1, 2, 15, 30, 0, 0, time.UTC)},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\n2020-01-02 10:30\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("time.Time unix epoch formats", func(t *testing.T) {
+		type Item struct {
+			First  time.Time `csvplusFormat:"unix"`
+			Second time.Time `csvplusFormat:"unixmilli"`
+			Third  time.Time `csvplusFormat:"unixnano"`
+		}
+
+		tm := time.Date(2020, 1, 1, 0, 0, 0, 123456789, time.UTC)
+		items := []Item{
+			{tm, tm, tm},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First,Second,Third\n1577836800,1577836800123,1577836800123456789\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("Date", func(t *testing.T) {
+		type Item struct {
+			First  csvplus.Date
+			Second *csvplus.Date `csvplusFormat:"02/01/2006"`
+		}
+
+		d := csvplus.DateOf(time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC))
+		items := []Item{
+			{d, &d},
+			{},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First,Second\n2020-01-31,31/01/2020\n,\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("pointer field", func(t *testing.T) {
+		type Item struct {
+			First *bool
+		}
+
+		yes := true
+		items := []Item{
+			{
+				&yes,
+			},
+			{},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\ntrue\n\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("uint", func(t *testing.T) {
+		type Item struct {
+			First uint
+		}
+
+		items := []Item{
+			{
+				10,
+			},
+			{},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\n10\n0\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("int base", func(t *testing.T) {
+		type Item struct {
+			First  int   `csvplusBase:"16"`
+			Second uint8 `csvplusBase:"2"`
+		}
+
+		items := []Item{
+			{255, 5},
+			{-26, 0},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First,Second\nff,101\n-1a,0\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("float", func(t *testing.T) {
+		type Item struct {
+			First float64
+		}
+
+		items := []Item{
+			{
+				10.05,
+			},
+			{},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\n10.05\n0\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("float format", func(t *testing.T) {
+		type Item struct {
+			First  float64  `csvplusFormat:"%.2f"`
+			Second *float32 `csvplusFormat:"%.2f" csvplusTrimZeros:"true"`
+		}
+
+		f := float32(3.5)
+		items := []Item{
+			{10.5, &f},
+			{1, nil},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First,Second\n10.50,3.5\n1.00,\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("percent", func(t *testing.T) {
+		type Item struct {
+			Rate   float64 `csvplus:"rate,percent"`
+			Points float64 `csvplus:"points,percent=whole" csvplusFormat:"%.1f"`
+		}
+
+		items := []Item{
+			{0.45, 12.5},
+			{0.005, 100},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("rate,points\n45%,12.5%\n0.5%,100.0%\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("MarshalCSV pointer", func(t *testing.T) {
+		type Item struct {
+			First *YesNoBool
+		}
+
+		yes := YesNoBool(true)
+		items := []Item{
+			{
+				&yes,
+			},
+			{},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\nyes\nno\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("MarshalCSV non pointer", func(t *testing.T) {
+		type Item struct {
+			First YesNoBool
+		}
+
+		yes := YesNoBool(true)
+		no := YesNoBool(false)
+		items := []Item{
+			{yes},
+			{no},
+		}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := []byte("First\nyes\nno\n")
+		if string(data) != string(expectedData) {
+			t.Errorf("expected: %s, got: %s", expectedData, data)
+		}
+	})
+
+	t.Run("string pointer fails", func(t *testing.T) {
+		a := "not a pointer to a slice"
+		_, err := csvplus.Marshal(&a)
+		if err == nil {
+			t.Error("expected error")
+		}
+		expectedPrefix := "expected slice"
+		if !strings.HasPrefix(err.Error(), expectedPrefix) {
+			t.Errorf("wrong error prefix, expected: '%s', got: %s", expectedPrefix, err.Error())
+		}
+	})
+
+	t.Run("slice value fails", func(t *testing.T) {
+		var items []string
+		_, err := csvplus.Marshal(items)
+		if err == nil {
+			t.Error("expected error")
+		}
+		expectedPrefix := "non pointer"
+		if !strings.HasPrefix(err.Error(), expectedPrefix) {
+			t.Errorf("wrong error prefix, expected: '%s', got: %s", expectedPrefix, err.Error())
+		}
+	})
+}
+
+// failingMarshaler is a field that always fails to marshal, it's used in tests.
+type failingMarshaler struct{}
+
+var errMarshal = errors.New("marshal failed")
+
+func (failingMarshaler) MarshalCSV() ([]byte, error) {
+	return nil, errMarshal
+}
+
+func TestEncodeError(t *testing.T) {
+	t.Run("MarshalCSV error", func(t *testing.T) {
+		type Item struct {
+			First  string
+			Second failingMarshaler
+		}
+		items := []Item{{First: "a"}}
+		_, err := csvplus.Marshal(&items)
+		var ee csvplus.EncodeError
+		if !errors.As(err, &ee) {
+			t.Fatalf("expected EncodeError, got: %v", err)
+		}
+		if ee.Field != "Second" {
+			t.Errorf("expected field Second, got: %s", ee.Field)
+		}
+		if ee.Row != 0 {
+			t.Errorf("expected row 0, got: %d", ee.Row)
+		}
+		if ee.Type.Name() != "Item" {
+			t.Errorf("expected type Item, got: %s", ee.Type)
+		}
+		if !errors.Is(err, errMarshal) {
+			t.Errorf("expected wrapped marshal error, got: %v", err)
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		type Item struct {
+			First map[string]string
+		}
+		items := []Item{{}}
+		_, err := csvplus.Marshal(&items)
+		var ee csvplus.EncodeError
+		if !errors.As(err, &ee) {
+			t.Fatalf("expected EncodeError, got: %v", err)
+		}
+		if ee.Field != "First" {
+			t.Errorf("expected field First, got: %s", ee.Field)
+		}
+	})
+}
+
+func TestEncoder_CommaUseCRLF(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	items := []Item{
+		{"a", 1},
+		{"b", 2},
+	}
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).Comma(';').UseCRLF(true).Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "First;Second\r\na;1\r\nb;2\r\n"
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+	}
+}
+
+func TestEncoder_Converter(t *testing.T) {
+	type Item struct {
+		Name  string  `csvplusConv:"upper"`
+		Price float64 `csvplusFormat:"%.2f" csvplusConv:"decimalcomma"`
+	}
+	items := []Item{{"a", 1.5}}
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).
+		Converter("upper", func(s string) (string, error) { return strings.ToUpper(s), nil }).
+		Converter("decimalcomma", func(s string) (string, error) { return strings.Replace(s, ".", ",", 1), nil }).
+		Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "Name,Price\nA,\"1,50\"\n"
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+	}
+}
+
+func TestEncoder_Quote(t *testing.T) {
+	type Item struct {
+		Name  string
+		Count int
+		Tags  [2]string
+	}
+	items := []Item{{"a", 1, [2]string{"x", "y\"z"}}, {"b,c", 2, [2]string{"", ""}}}
+
+	tests := []struct {
+		mode     csvplus.QuoteMode
+		expected string
+	}{
+		{csvplus.QuoteMinimal, "Name,Count,Tags[0],Tags[1]\na,1,x,\"y\"\"z\"\n\"b,c\",2,,\n"},
+		{csvplus.QuoteAll, "\"Name\",\"Count\",\"Tags[0]\",\"Tags[1]\"\n\"a\",\"1\",\"x\",\"y\"\"z\"\n\"b,c\",\"2\",\"\",\"\"\n"},
+		{csvplus.QuoteStrings, "\"Name\",\"Count\",\"Tags[0]\",\"Tags[1]\"\n\"a\",1,\"x\",\"y\"\"z\"\n\"b,c\",2,\"\",\"\"\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).Quote(tt.mode).Encode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.expected != buf.String() {
+			t.Errorf("mode %d: incorrect output, expected: %q, got: %q", tt.mode, tt.expected, buf.String())
+		}
+	}
+
+	t.Run("never", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).Quote(csvplus.QuoteNever).Encode(&items)
+		if !errors.Is(err, csvplus.ErrNeedsQuotes) {
+			t.Errorf("expected ErrNeedsQuotes, got: %v", err)
+		}
+
+		buf.Reset()
+		items := []Item{{"a b", 1, [2]string{"x", "y"}}}
+		err = csvplus.NewEncoder(&buf).Quote(csvplus.QuoteNever).Comma('\t').UseCRLF(true).Encode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "Name\tCount\tTags[0]\tTags[1]\r\na b\t1\tx\ty\r\n"
+		if expected != buf.String() {
+			t.Errorf("incorrect output, expected: %q, got: %q", expected, buf.String())
+		}
+
+		// none of a record that can't be written is written
+		buf.Reset()
+		enc := csvplus.NewEncoder(&buf).Quote(csvplus.QuoteNever)
+		for _, record := range [][]string{{"a", "b"}, {"x", "y"}} {
+			if err := enc.WriteRecord(record); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.WriteRecord([]string{"ok", "bad,val"}); !errors.Is(err, csvplus.ErrNeedsQuotes) {
+			t.Errorf("expected ErrNeedsQuotes, got: %v", err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "a,b\nx,y\n"; expected != buf.String() {
+			t.Errorf("incorrect output, expected: %q, got: %q", expected, buf.String())
+		}
+	})
+
+	t.Run("SetCSVWriter", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).SetCSVWriter(csv.NewWriter(&buf)).Quote(csvplus.QuoteAll).Encode(&items)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestMarshalColumnOrder(t *testing.T) {
+	type Item struct {
+		ID     int     `csvplus:"id,order=0"`
+		Name   string  `csvplus:"name"`
+		Amount float64 `csvplus:"amount,order=2"`
+		Total  float64 `csvplus:"total,order=1"`
+		Notes  string  `csvplus:"notes"`
+	}
+	items := []Item{{1, "a", 2.5, 5, "x"}}
+	data, err := csvplus.Marshal(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "id,total,amount,name,notes\n1,5,2.5,a,x\n"
+	if expectedData != string(data) {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, data)
+	}
+
+	var decoded []Item
+	if err := csvplus.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded[0] != items[0] {
+		t.Errorf("expected %v, got: %v", items[0], decoded[0])
+	}
+}
+
+func TestEncoder_SelectColumns(t *testing.T) {
+	type Item struct {
+		ID    int     `csvplus:"id"`
+		Name  string  `csvplus:"name"`
+		Total float64 `csvplus:"total"`
+		Notes string
+	}
+	items := []Item{{1, "a", 2.5, "x"}}
+
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).SelectColumns("total", "Notes", "id").Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "total,Notes,id\n2.5,x,1\n"
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+	}
+
+	err = csvplus.NewEncoder(&buf).SelectColumns("id", "missing").Encode(&items)
+	if err == nil || !strings.Contains(err.Error(), "unknown columns: missing") {
+		t.Errorf("expected unknown columns error, got: %v", err)
+	}
+}
+
+func TestEncoder_MapHeader(t *testing.T) {
+	type Item struct {
+		ID     int     `csvplus:"id"`
+		Amount float64 `csvplus:"amount"`
+		Notes  string
+	}
+	items := []Item{{1, 2.5, "x"}}
+
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).MapHeader(func(field, tag string) string {
+		if field == "Amount" {
+			return tag + " (USD)"
+		}
+		return strings.ToUpper(tag)
+	}).Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "ID,amount (USD),NOTES\n1,2.5,x\n"
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+	}
+}
+
+func TestEncoder_EncodeChan(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		ch := make(chan Item)
+		go func() {
+			ch <- Item{"a", 1}
+			ch <- Item{"b", 2}
+			close(ch)
+		}()
+
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).EncodeChan(context.Background(), ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := "First,Second\na,1\nb,2\n"
+		if expectedData != buf.String() {
+			t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan *Item, 1)
+		ch <- &Item{"a", 1}
+
+		var buf bytes.Buffer
+		done := make(chan error)
+		go func() {
+			done <- csvplus.NewEncoder(&buf).EncodeChan(ctx, ch)
+		}()
+		// wait for the buffered row to be received
+		for len(ch) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+
+		if err := <-done; err != context.Canceled {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+		expectedData := "First,Second\na,1\n"
+		if expectedData != buf.String() {
+			t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+		}
+	})
+
+	t.Run("not a channel", func(t *testing.T) {
+		err := csvplus.NewEncoder(io.Discard).EncodeChan(context.Background(), []Item{})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestEncoder_WriteRecord(t *testing.T) {
+	var buf bytes.Buffer
+	enc := csvplus.NewEncoder(&buf).Comma('\t').WriteBOM(true)
+	for _, record := range [][]string{{"First", "Second"}, {"a b", "1"}} {
+		if err := enc.WriteRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedData := "\xef\xbb\xbfFirst\tSecond\na b\t1\n"
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+	}
+}
+
+func TestEncoder_WriteBOM(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	items := []Item{
+		{"Renée"},
+	}
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).WriteBOM(true).Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "\ufeffName\nRenée\n"
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+	}
+
+	var decoded []Item
+	err = csvplus.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded[0].Name != "Renée" {
+		t.Errorf("expected 'Renée', got: %s", decoded[0].Name)
+	}
+}
+
+func TestEncoder_Charset(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+
+	t.Run("windows-1252", func(t *testing.T) {
+		items := []Item{
+			{"Renée"},
+		}
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).Comma(';').Charset("windows-1252").Encode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedData := "Name\nRen\xe9e\n"
+		if expectedData != buf.String() {
+			t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+		}
+	})
+
+	t.Run("unsupported character", func(t *testing.T) {
+		items := []Item{
+			{"😀"},
+		}
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).Charset("iso-8859-1").Encode(&items)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("BOM", func(t *testing.T) {
+		items := []Item{
+			{"Renée"},
+		}
+		var buf bytes.Buffer
+		err := csvplus.NewEncoder(&buf).WriteBOM(true).Charset("windows-1252").Encode(&items)
+		if err == nil || !strings.Contains(err.Error(), "BOM") {
+			t.Fatalf("expected BOM error, got: %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected no output, got: %q", buf.String())
+		}
+
+		buf.Reset()
+		err = csvplus.NewEncoder(&buf).WriteBOM(true).Charset("utf-8").Encode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectedData := "\ufeffName\nRenée\n"; expectedData != buf.String() {
+			t.Errorf("incorrect output, expected: %q, got: %q", expectedData, buf.String())
+		}
+	})
+}
+
+func TestMarshalReader(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	items := []Item{
+		{"a", 1},
+		{"b", 2},
+	}
+	var buf bytes.Buffer
+	err := csvplus.MarshalWriter(&items, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedData := "First,Second\na,1\nb,2\n"
+
+	if expectedData != buf.String() {
+		t.Errorf("incorrect output, expected: %s, got: %s", expectedData, buf.String())
+	}
+}
+
+func TestMarshalWithoutHeader(t *testing.T) {
+	type Item struct {
+		First  string `csvplus:"-"`
+		Second int
+		Third  bool
+	}
+	items := []Item{
+		{"a", 1, true},
+		{"b", 2, false},
+	}
+
+	data, err := csvplus.MarshalWithoutHeader(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedData := "1,true\n2,false\n"
+	if string(data) != expectedData {
+		t.Errorf("incorrect output, expected: %s, got: %s", expectedData, data)
+	}
+}
+
+func TestUnmarshal_AutoFormat(t *testing.T) {
+	type Item struct {
+		When time.Time     `csvplus:"when" csvplusFormat:"auto"`
+		Day  *csvplus.Date `csvplus:"day" csvplusFormat:"auto"`
+	}
+
+	t.Run("detected", func(t *testing.T) {
+		data := []byte("when,day\n2020-01-02 15:04:05,02/01/2020\n2020-01-03 09:30:00,\n2020-01-04 10:00:00,13/01/2020\n")
+		var items []Item
+		if err := csvplus.Unmarshal(data, &items); err != nil {
+			t.Fatal(err)
+		}
+		expected := time.Date(2020, 1, 4, 10, 0, 0, 0, time.UTC)
+		if !items[2].When.Equal(expected) {
+			t.Errorf("expected %s, got: %s", expected, items[2].When)
+		}
+		// 13/01/2020 rules out the US layout that 02/01/2020 also matches
+		if items[2].Day == nil || items[2].Day.String() != "2020-01-13" {
+			t.Errorf("expected 2020-01-13, got: %v", items[2].Day)
+		}
+		if items[1].Day != nil {
+			t.Errorf("expected nil day, got: %v", items[1].Day)
+		}
+	})
+
+	t.Run("inconsistent", func(t *testing.T) {
+		data := []byte("when,day\n2020-01-02 15:04:05,2020-01-02\n2020-01-03 09:30:00,03/01/2020\n")
+		var items []Item
+		err := csvplus.Unmarshal(data, &items)
+		if !errors.Is(err, csvplus.ErrBadTimeLayout) {
+			t.Errorf("expected ErrBadTimeLayout, got: %v", err)
+		}
+		var ue csvplus.UnmarshalError
+		if errors.As(err, &ue) && ue.Row != 2 {
+			t.Errorf("expected row 2, got: %d", ue.Row)
+		}
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		d := csvplus.DateOf(time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC))
+		items := []Item{{When: time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC), Day: &d}}
+		data, err := csvplus.Marshal(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "when,day\n2020-01-02T15:04:05Z,2020-01-31\n"
+		if string(data) != expected {
+			t.Errorf("expected: %s, got: %s", expected, data)
+		}
+	})
+
+	if err := csvplus.CheckType(reflect.TypeOf(Item{})); err != nil {
+		t.Errorf("unexpected CheckType error: %v", err)
+	}
+}
+
+func TestTagName(t *testing.T) {
+	type Item struct {
+		ID      int     `csv:"id"`
+		Name    string  `csv:"name,omitempty"`
+		Price   float64 `csvplus:"price,currency"`
+		Ignored string  `csv:"-"`
+	}
+	data := "id,name,price,Ignored\n1,Rob,$1.50,x\n"
+
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader(data)).TagName("csv").RequireColumns(true).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Item{ID: 1, Name: "Rob", Price: 1.5}
+	if len(items) != 1 || items[0] != expected {
+		t.Errorf("expected %v, got: %v", expected, items)
+	}
+
+	var buf bytes.Buffer
+	if err := csvplus.NewEncoder(&buf).TagName("csv").Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,name,price\n1,Rob,1.5\n"; buf.String() != expected {
+		t.Errorf("expected: %s, got: %s", expected, buf.String())
+	}
+
+	// without TagName the csv tags are ignored
+	buf.Reset()
+	if err := csvplus.NewEncoder(&buf).Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "ID,Name,price,Ignored\n1,Rob,1.5,\n"; buf.String() != expected {
+		t.Errorf("expected: %s, got: %s", expected, buf.String())
+	}
+}
+
+func TestJSONTags(t *testing.T) {
+	type Item struct {
+		ID      int    `json:"id"`
+		Name    string `json:"full_name,omitempty"`
+		Email   string `json:"email" csvplus:"e-mail"`
+		Secret  string `json:"-"`
+		Created string `json:",omitempty"`
+	}
+	data := "id,full_name,e-mail,Secret,Created\n1,Rob,rob@example.com,x,today\n"
+
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader(data)).JSONTags(true).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Item{ID: 1, Name: "Rob", Email: "rob@example.com", Created: "today"}
+	if len(items) != 1 || items[0] != expected {
+		t.Errorf("expected %v, got: %v", expected, items)
+	}
+
+	var buf bytes.Buffer
+	if err := csvplus.NewEncoder(&buf).JSONTags(true).Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,full_name,e-mail,Created\n1,Rob,rob@example.com,today\n"; buf.String() != expected {
+		t.Errorf("expected: %s, got: %s", expected, buf.String())
+	}
+
+	// json tags are only used when enabled
+	items = nil
+	if err := csvplus.Unmarshal([]byte(data), &items); err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Name != "" || items[0].Secret != "x" {
+		t.Errorf("expected json tags to be ignored, got: %v", items[0])
+	}
+}
+
+type computedItem struct {
+	Price    float64 `csvplus:"price"`
+	Quantity int     `csvplus:"quantity"`
+}
+
+func (i computedItem) CSVTotal() string {
+	return strconv.FormatFloat(i.Price*float64(i.Quantity), 'f', 2, 64)
+}
+
+func (i *computedItem) CSVCheck() (string, error) {
+	if i.Quantity < 0 {
+		return "", errors.New("negative quantity")
+	}
+	return "ok", nil
+}
+
+// not computed columns
+func (i computedItem) CSV() string            { return "" }
+func (i computedItem) CSVlower() string       { return "" }
+func (i computedItem) CSVArg(s string) string { return s }
+func (i computedItem) CSVInt() int            { return 0 }
+
+func TestMarshal_ComputedColumns(t *testing.T) {
+	items := []computedItem{{Price: 1.5, Quantity: 3}}
+
+	// computed columns are only written when enabled
+	data, err := csvplus.Marshal(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "price,quantity\n1.5,3\n"; string(data) != expected {
+		t.Errorf("expected: %s, got: %s", expected, data)
+	}
+
+	var buf bytes.Buffer
+	if err := csvplus.NewEncoder(&buf).ComputedColumns(true).Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	data = buf.Bytes()
+	if expected := "price,quantity,Check,Total\n1.5,3,ok,4.50\n"; string(data) != expected {
+		t.Errorf("expected: %s, got: %s", expected, data)
+	}
+
+	// computed columns are ignored when decoding
+	var decoded []computedItem
+	if err := csvplus.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0] != items[0] {
+		t.Errorf("expected %v, got: %v", items, decoded)
+	}
+
+	buf.Reset()
+	if err := csvplus.NewEncoder(&buf).ComputedColumns(true).SelectColumns("Total", "price").Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Total,price\n4.50,1.5\n"; buf.String() != expected {
+		t.Errorf("expected: %s, got: %s", expected, buf.String())
+	}
+
+	items[0].Quantity = -1
+	err = csvplus.NewEncoder(io.Discard).ComputedColumns(true).Encode(&items)
+	if err == nil || !strings.Contains(err.Error(), "negative quantity") {
+		t.Errorf("expected negative quantity error, got: %v", err)
+	}
+}
+
+func TestEncoder_NilValue(t *testing.T) {
+	type Item struct {
+		Name  *string `csvplus:"name"`
+		Count *int    `csvplus:"count" csvplusNilVal:"-"`
+		Score *float64
+	}
+	name := "Rob"
+	items := []Item{{Name: &name}, {}}
+
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).NilValue("NULL").Quote(csvplus.QuoteStrings).Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\"name\",\"count\",\"Score\"\n\"Rob\",-,NULL\nNULL,-,NULL\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %s, got: %s", expected, buf.String())
+	}
+
+	// the tag is used without NilValue
+	data, err := csvplus.Marshal(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "name,count,Score\nRob,-,\n,-,\n"; string(data) != expected {
+		t.Errorf("expected: %s, got: %s", expected, data)
+	}
+
+	t.Run("schema", func(t *testing.T) {
+		schema := csvplus.NewSchema(csvplus.Column("id", 0), csvplus.Column("name", nil).Default("unknown"))
+		rows := []map[string]interface{}{{"id": 1}, {"name": "Rob"}}
+		var buf bytes.Buffer
+		if err := csvplus.NewEncoder(&buf).Schema(schema).NilValue("NULL").Encode(&rows); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "id,name\n1,unknown\nNULL,Rob\n"; buf.String() != expected {
+			t.Errorf("expected: %s, got: %s", expected, buf.String())
+		}
+	})
+}
+
+func TestDecoder_BoolWords(t *testing.T) {
+	type Item struct {
+		A bool
+		B *bool
+		C bool
+	}
+	data := "A,B,C\nyes,N,On\nY,no,off\ntrue,,0\n"
+
+	var items []Item
+	if err := csvplus.NewDecoder(strings.NewReader(data)).BoolWords(true).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ a, b, c bool }{{true, false, true}, {true, false, false}, {true, false, false}}
+	for i, e := range expected {
+		item := items[i]
+		if item.A != e.a || (item.B != nil && *item.B != e.b) || item.C != e.c {
+			t.Errorf("row %d: expected %v, got: %+v", i, e, item)
+		}
+	}
+	if items[2].B != nil {
+		t.Errorf("expected nil B, got: %v", *items[2].B)
+	}
+
+	err := csvplus.NewDecoder(strings.NewReader("A\nyes\n")).Decode(&items)
+	if !errors.Is(err, csvplus.ErrTypeConversion) {
+		t.Errorf("expected ErrTypeConversion without BoolWords, got: %v", err)
+	}
+	err = csvplus.NewDecoder(strings.NewReader("A\nmaybe\n")).BoolWords(true).Decode(&items)
+	if !errors.Is(err, csvplus.ErrTypeConversion) {
+		t.Errorf("expected ErrTypeConversion, got: %v", err)
+	}
+}
+
+func TestDecoder_OnShortRow(t *testing.T) {
+	type Item struct {
+		A string
+		B string
+		C *int
+	}
+	data := "A,B,C\na,b,1\nshort,x\nc,d,2\n"
+
+	tests := map[string]struct {
+		fieldsPerRecord int
+		policy          csvplus.ShortRow
+		expected        []string
+	}{
+		"pad":                {0, csvplus.ShortRowPad, []string{"a", "short", "c"}},
+		"skip":               {0, csvplus.ShortRowSkip, []string{"a", "c"}},
+		"pad variable":       {-1, csvplus.ShortRowPad, []string{"a", "short", "c"}},
+		"skip variable":      {-1, csvplus.ShortRowSkip, []string{"a", "c"}},
+		"error":              {0, csvplus.ShortRowError, nil},
+		"error variable len": {-1, csvplus.ShortRowError, nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var items []Item
+			err := csvplus.NewDecoder(strings.NewReader(data)).
+				FieldsPerRecord(tt.fieldsPerRecord).
+				OnShortRow(tt.policy).
+				Decode(&items)
+			if tt.expected == nil {
+				if !errors.Is(err, csvplus.ErrShortRow) {
+					t.Errorf("expected ErrShortRow, got: %v", err)
+				}
+				var ue csvplus.UnmarshalError
+				if errors.As(err, &ue) && ue.Row != 2 {
+					t.Errorf("expected row 2, got: %d", ue.Row)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.A)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got: %v", tt.expected, got)
+			}
+			if tt.policy == csvplus.ShortRowPad && items[1].C != nil {
+				t.Errorf("expected padded C to be nil, got: %v", *items[1].C)
+			}
+		})
+	}
+}
+
+func TestDecoder_DropDuplicates(t *testing.T) {
+	type Item struct {
+		ID   string
+		Name string
+	}
+	data := "ID,Name\n1,a\n2,b\n1,a\n1,c\n2,b\n"
+
+	tests := map[string]struct {
+		columns  []string
+		header   bool
+		expected []string
+		dropped  int
+	}{
+		"whole row":     {nil, true, []string{"1a", "2b", "1c"}, 2},
+		"key column":    {[]string{"ID"}, true, []string{"1a", "2b"}, 3},
+		"index columns": {[]string{"1"}, false, []string{"1a", "2b", "1c"}, 2},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var items []Item
+			dec := csvplus.NewDecoder(strings.NewReader(data)).DropDuplicates(tt.columns...)
+			if !tt.header {
+				dec = csvplus.NewDecoder(strings.NewReader(strings.SplitN(data, "\n", 2)[1])).
+					UseHeader(false).DropDuplicates(tt.columns...)
+			}
+			if err := dec.Decode(&items); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.ID+item.Name)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			if dec.Duplicates() != tt.dropped {
+				t.Errorf("expected %d duplicates, got %d", tt.dropped, dec.Duplicates())
+			}
+		})
+	}
+
+	t.Run("missing column", func(t *testing.T) {
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader(data)).DropDuplicates("nope").Decode(&items)
+		if !errors.Is(err, csvplus.ErrMissingColumn) {
+			t.Errorf("expected ErrMissingColumn, got: %v", err)
+		}
+	})
+}
+
+func TestDecoder_Limits(t *testing.T) {
+	type Item struct {
+		A string
+		B string
+	}
+
+	t.Run("max field bytes", func(t *testing.T) {
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader("A,B\nshort,value\ntoo long,x\n")).MaxFieldBytes(5).Decode(&items)
+		if !errors.Is(err, csvplus.ErrFieldTooLarge) {
+			t.Fatalf("expected ErrFieldTooLarge, got: %v", err)
+		}
+		var ue csvplus.UnmarshalError
+		if !errors.As(err, &ue) || ue.Row != 2 {
+			t.Errorf("expected row 2, got: %v", err)
+		}
+		if len(items) != 1 {
+			t.Errorf("expected 1 item, got: %v", items)
+		}
+	})
+
+	t.Run("unterminated quote", func(t *testing.T) {
+		// the huge value is detected before it's all read
+		r := io.MultiReader(strings.NewReader("A,B\n\"a"), infiniteReader{})
+		var items []Item
+		err := csvplus.NewDecoder(r).MaxFieldBytes(10).Decode(&items)
+		if !errors.Is(err, csvplus.ErrFieldTooLarge) {
+			t.Fatalf("expected ErrFieldTooLarge, got: %v", err)
+		}
+	})
+
+	t.Run("max rows", func(t *testing.T) {
+		data := "A,B\n1,2\n3,4\n5,6\n"
+		var items []Item
+		if err := csvplus.NewDecoder(strings.NewReader(data)).MaxRows(3).Decode(&items); err != nil {
+			t.Fatal(err)
+		}
+		items = nil
+		err := csvplus.NewDecoder(strings.NewReader(data)).MaxRows(2).Decode(&items)
+		if !errors.Is(err, csvplus.ErrTooManyRows) {
+			t.Fatalf("expected ErrTooManyRows, got: %v", err)
+		}
+		if len(items) != 2 {
+			t.Errorf("expected 2 items, got: %v", items)
+		}
+	})
+}
+
+// infiniteReader returns an endless stream of x.
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestDecoder_DecodeGrowsSlice(t *testing.T) {
+	type Item struct {
+		A int
+		B string
+	}
+	var sb strings.Builder
+	sb.WriteString("A,B\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, "%d,", i)
+		if i%2 == 0 {
+			sb.WriteString("x")
+		}
+		sb.WriteString("\n")
+	}
+
+	// existing elements are kept, elements of rows removed by FilterDecoded don't leak into later rows
+	items := []Item{{A: -1, B: "existing"}}
+	err := csvplus.NewDecoder(strings.NewReader(sb.String())).
+		FilterDecoded(func(v interface{}) bool { return v.(*Item).A%3 != 0 }).
+		Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].B != "existing" {
+		t.Errorf("expected the existing element to be kept, got: %+v", items[0])
+	}
+	expected := 1
+	for i := 0; i < 1000; i++ {
+		if i%3 != 0 {
+			expected++
+		}
+	}
+	if len(items) != expected {
+		t.Fatalf("expected %d items, got %d", expected, len(items))
+	}
+	for _, item := range items[1:] {
+		if item.A%3 == 0 || (item.A%2 == 0) != (item.B == "x") {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	}
+}
+
+func TestDecoder_ReuseElements(t *testing.T) {
+	type Item struct {
+		A int
+		B *string
+	}
+	first := "A,B\n1,x\n2,y\n3,z\n"
+	second := "A,B\n4,\n5,w\n"
+
+	items := make([]*Item, 0, 4)
+	if err := csvplus.NewDecoder(strings.NewReader(first)).ReuseElements(true).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	firstRow := items[0]
+	if err := csvplus.NewDecoder(strings.NewReader(second)).ReuseElements(true).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected the slice to be overwritten, got %d items", len(items))
+	}
+	if items[0] != firstRow {
+		t.Error("expected the struct to be reused")
+	}
+	if items[0].A != 4 || items[0].B != nil || items[1].A != 5 || *items[1].B != "w" {
+		t.Errorf("unexpected items: %+v, %+v", items[0], items[1])
+	}
+
+	t.Run("maps", func(t *testing.T) {
+		schema := csvplus.NewSchema(csvplus.Column("A", 0), csvplus.Column("B", nil))
+		var rows []map[string]interface{}
+		dec := csvplus.NewDecoder(strings.NewReader(first)).Schema(schema).ReuseElements(true)
+		if err := dec.Decode(&rows); err != nil {
+			t.Fatal(err)
+		}
+		schema = csvplus.NewSchema(csvplus.Column("A", 0))
+		dec = csvplus.NewDecoder(strings.NewReader(second)).Schema(schema).ReuseElements(true)
+		if err := dec.Decode(&rows); err != nil {
+			t.Fatal(err)
+		}
+		expected := []map[string]interface{}{{"A": 4}, {"A": 5}}
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected %v, got %v", expected, rows)
+		}
+	})
+}
+
+func TestEncoder_WriteHeader(t *testing.T) {
+	type Item struct {
+		A int    `csvplus:"a"`
+		B string `csvplus:"b"`
+	}
+
+	var buf bytes.Buffer
+	enc := csvplus.NewEncoder(&buf).UseHeader(false)
+	if err := enc.WriteHeader(Item{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, items := range [][]Item{{{1, "x"}}, {{2, "y"}}} {
+		items := items
+		if err := enc.Encode(&items); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := "a,b\n1,x\n2,y\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	t.Run("header only", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := csvplus.NewEncoder(&buf).WriteHeader(&[]*Item{}); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "a,b\n"; buf.String() != expected {
+			t.Errorf("expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("omit empty header", func(t *testing.T) {
+		var buf bytes.Buffer
+		var items []Item
+		if err := csvplus.NewEncoder(&buf).OmitEmptyHeader(true).WriteBOM(true).Encode(&items); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected no output, got %q", buf.String())
+		}
+		items = append(items, Item{1, "x"})
+		if err := csvplus.NewEncoder(&buf).OmitEmptyHeader(true).Encode(&items); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "a,b\n1,x\n"; buf.String() != expected {
+			t.Errorf("expected %q, got %q", expected, buf.String())
+		}
+	})
 }
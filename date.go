@@ -0,0 +1,56 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// dateLayout is the default layout for Date fields.
+const dateLayout = "2006-01-02"
+
+var dateType = reflect.TypeOf(Date{})
+
+// Date is a calendar date without a time of day or location, use it instead of time.Time for date only columns to avoid
+// midnight/time zone pitfalls. Date fields are unmarshalled/marshalled using the layout from a csvplusFormat tag,
+// defaulting to 2006-01-02. A zero Date is marshalled as an empty value.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the Date of t in t's location.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{Year: y, Month: m, Day: d}
+}
+
+// ParseDate parses s using the time.Parse layout, any time or location information in the layout is ignored.
+func ParseDate(layout, s string) (Date, error) {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// In returns the time.Time at midnight at the start of d in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Format formats d using the time.Format layout.
+func (d Date) Format(layout string) string {
+	return d.In(time.UTC).Format(layout)
+}
+
+// IsZero reports whether d is the zero value.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// String returns d in 2006-01-02 format.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
@@ -0,0 +1,98 @@
+package csvplus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DropDuplicates sets whether data rows that duplicate an earlier row are skipped without being converted, rows are
+// compared by the values of the named columns (the column index, eg "0", if there's no header row) or by all their
+// values if no columns are given. Rows skipped by Filter aren't compared. The key of every row is kept in memory until
+// the Decoder is no longer used, Duplicates returns the number of rows skipped. Decode returns an error wrapping
+// ErrMissingColumn if a column isn't in the header row.
+func (dec *Decoder) DropDuplicates(columns ...string) *Decoder {
+	dec.dedupe = &dedupe{
+		columns: columns,
+		seen:    make(map[string]bool),
+	}
+	return dec
+}
+
+// Duplicates returns the number of rows that have been skipped by DropDuplicates.
+func (dec *Decoder) Duplicates() int {
+	if dec.dedupe == nil {
+		return 0
+	}
+	return dec.dedupe.dropped
+}
+
+// dedupe tracks the rows seen by a Decoder that drops duplicate rows.
+type dedupe struct {
+	columns []string
+	indices []int // indices of columns, nil until the first row is checked
+	seen    map[string]bool
+	dropped int
+	b       strings.Builder
+}
+
+// isDuplicate reports whether record duplicates an earlier row, header is the header row (nil if there isn't one).
+func (dd *dedupe) isDuplicate(record, header []string) (bool, error) {
+	if dd.indices == nil && len(dd.columns) > 0 {
+		indices, err := columnIndices(dd.columns, header)
+		if err != nil {
+			return false, err
+		}
+		dd.indices = indices
+	}
+
+	dd.b.Reset()
+	if len(dd.columns) == 0 {
+		for _, v := range record {
+			dd.b.WriteString(v)
+			dd.b.WriteByte(0)
+		}
+	} else {
+		for _, i := range dd.indices {
+			if i < len(record) {
+				dd.b.WriteString(record[i])
+			}
+			dd.b.WriteByte(0)
+		}
+	}
+
+	key := dd.b.String()
+	if dd.seen[key] {
+		dd.dropped++
+		return true, nil
+	}
+	dd.seen[key] = true
+	return false, nil
+}
+
+// columnIndices returns the indices in header of the columns called names, without a header row names are indices.
+func columnIndices(names, header []string) ([]int, error) {
+	positions := make(map[string]int, len(header))
+	for i := len(header) - 1; i >= 0; i-- {
+		positions[header[i]] = i
+	}
+
+	indices := make([]int, len(names))
+	var missing []string
+	for k, name := range names {
+		i, found := positions[name]
+		if header == nil {
+			var err error
+			i, err = strconv.Atoi(name)
+			found = err == nil && i >= 0
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+		indices[k] = i
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingColumn, strings.Join(missing, ", "))
+	}
+	return indices, nil
+}
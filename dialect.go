@@ -0,0 +1,51 @@
+package csvplus
+
+// A Dialect bundles the conventions of a csv format so they can be set on a Decoder or Encoder in one call, eg
+// NewEncoder(w).Dialect(DialectExcel). Options set after Dialect override it.
+type Dialect struct {
+	Comma   rune      // the field delimiter
+	Quote   QuoteMode // which values are quoted, only used by Encoders
+	UseCRLF bool      // lines end with \r\n rather than \n, only used by Encoders (both are read)
+	// Null is the value of nil pointer fields (eg \N), it's written by Encoders and decoded as an empty value. An
+	// empty Null means nil pointers are empty values.
+	Null string
+	BOM  bool // a UTF-8 byte order mark is written before the csv data, only used by Encoders (it's always stripped)
+}
+
+// Dialect presets.
+var (
+	// DialectRFC4180 is the format described by RFC 4180, values are only quoted if they need it and lines end with
+	// \r\n.
+	DialectRFC4180 = Dialect{Comma: ',', Quote: QuoteMinimal, UseCRLF: true}
+	// DialectExcel is the format Excel reads and writes, it's RFC 4180 with a BOM so non ASCII characters are displayed
+	// correctly.
+	DialectExcel = Dialect{Comma: ',', Quote: QuoteMinimal, UseCRLF: true, BOM: true}
+	// DialectUnix is the format written by many Unix tools, every value is quoted and lines end with \n.
+	DialectUnix = Dialect{Comma: ',', Quote: QuoteAll}
+	// DialectPostgres is the csv format of the PostgreSQL COPY command, nil values are unquoted empty values (read as
+	// NULL by COPY) and strings are quoted so empty strings aren't.
+	DialectPostgres = Dialect{Comma: ',', Quote: QuoteStrings}
+)
+
+// Dialect sets the delimiter and null value of d, the other conventions only affect Encoders.
+func (dec *Decoder) Dialect(d Dialect) *Decoder {
+	dec.csvReader.Comma = d.Comma
+	if d.Null != "" {
+		dec.NilValue(d.Null)
+	} else {
+		dec.nilValue = nil
+	}
+	return dec
+}
+
+// Dialect sets the delimiter, quote mode, line terminator, null value and BOM of d, quote modes other than QuoteMinimal
+// can't be used with SetCSVWriter.
+func (enc *Encoder) Dialect(d Dialect) *Encoder {
+	enc.Comma(d.Comma).UseCRLF(d.UseCRLF).Quote(d.Quote).WriteBOM(d.BOM)
+	if d.Null != "" {
+		enc.NilValue(d.Null)
+	} else {
+		enc.nilValue = nil
+	}
+	return enc
+}
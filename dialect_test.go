@@ -0,0 +1,65 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDialect(t *testing.T) {
+	type Item struct {
+		Name  string `csvplus:"name"`
+		Notes string `csvplus:"notes"`
+		Qty   *int   `csvplus:"qty"`
+	}
+	qty := 2
+	items := []Item{{"a, b", "", &qty}, {"c", "d", nil}}
+
+	tests := []struct {
+		name    string
+		dialect csvplus.Dialect
+		want    string
+	}{
+		{"RFC4180", csvplus.DialectRFC4180, "name,notes,qty\r\n\"a, b\",,2\r\nc,d,\r\n"},
+		{"Excel", csvplus.DialectExcel, "\ufeffname,notes,qty\r\n\"a, b\",,2\r\nc,d,\r\n"},
+		{"Unix", csvplus.DialectUnix, "\"name\",\"notes\",\"qty\"\n\"a, b\",\"\",\"2\"\n\"c\",\"d\",\"\"\n"},
+		{"Postgres", csvplus.DialectPostgres, "\"name\",\"notes\",\"qty\"\n\"a, b\",\"\",2\n\"c\",\"d\",\n"},
+		{"custom", csvplus.Dialect{Comma: '\t', Null: `\N`}, "name\tnotes\tqty\na, b\t\t2\nc\td\t\\N\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := csvplus.NewEncoder(&buf).Dialect(tt.dialect).Encode(&items); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, buf.String())
+			}
+
+			var decoded []Item
+			if err := csvplus.NewDecoder(&buf).Dialect(tt.dialect).Decode(&decoded); err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded) != 2 || decoded[0].Name != "a, b" || *decoded[0].Qty != 2 || decoded[1].Qty != nil {
+				t.Errorf("unexpected items %+v", decoded)
+			}
+		})
+	}
+}
+
+func TestDecoder_NilValue(t *testing.T) {
+	type Item struct {
+		Name string `csvplus:"name"`
+		Qty  *int   `csvplus:"qty"`
+	}
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader("name,qty\nNULL,NULL\nb,1\n")).NilValue("NULL").Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Name != "" || items[0].Qty != nil || *items[1].Qty != 1 {
+		t.Errorf("unexpected items %+v", items)
+	}
+}
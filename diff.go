@@ -0,0 +1,154 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DiffResult is the difference between two versions of csv data, see Diff. Rows are pointers to structs of the type
+// passed to Diff.
+type DiffResult struct {
+	Added   []interface{} // rows only in the new data, in the order they're in the new data
+	Removed []interface{} // rows only in the old data, in the order they're in the old data
+	Changed []RowDiff     // rows in both with different values, in the order they're in the new data
+}
+
+// RowDiff is a row whose values are different in the old and new data.
+type RowDiff struct {
+	Key    string
+	Old    interface{}
+	New    interface{}
+	Fields []FieldDiff
+}
+
+// FieldDiff is a field whose (marshalled) value is different in the old and new data.
+type FieldDiff struct {
+	Field  string // name of the struct field
+	Column string
+	Old    string
+	New    string
+}
+
+// Diff decodes the csv data oldData and newData into structs of the type of v (a struct, or pointer to a struct) and
+// compares the rows that have the same value in the key column. Values are compared after they're marshalled so
+// they're compared the same way they're written (eg with the csvplusFormat layout). Each key must only be used once in
+// each version of the data.
+func Diff(oldData, newData []byte, v interface{}, key string) (DiffResult, error) {
+	st := reflect.TypeOf(v)
+	if st != nil && st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st == nil || st.Kind() != reflect.Struct {
+		return DiffResult{}, fmt.Errorf("expected struct or pointer to struct, got %T", v)
+	}
+	fis, err := defaultEncRegister.encodeFields(st)
+	if err != nil {
+		return DiffResult{}, err
+	}
+	keyIndex := -1
+	for i, fi := range fis {
+		if fi.ColName == key {
+			keyIndex = i
+		}
+	}
+	if keyIndex < 0 {
+		return DiffResult{}, fmt.Errorf("%w: %s", ErrMissingColumn, key)
+	}
+
+	oldRows, err := diffRows(oldData, st, fis, keyIndex)
+	if err != nil {
+		return DiffResult{}, errors.Wrap(err, "old data")
+	}
+	newRows, err := diffRows(newData, st, fis, keyIndex)
+	if err != nil {
+		return DiffResult{}, errors.Wrap(err, "new data")
+	}
+
+	oldByKey := make(map[string]diffRow, len(oldRows))
+	for _, row := range oldRows {
+		oldByKey[row.key] = row
+	}
+	newKeys := make(map[string]bool, len(newRows))
+
+	var res DiffResult
+	for _, row := range newRows {
+		newKeys[row.key] = true
+		oldRow, found := oldByKey[row.key]
+		if !found {
+			res.Added = append(res.Added, row.v)
+			continue
+		}
+
+		var fields []FieldDiff
+		for i, fi := range fis {
+			if oldRow.values[i] != row.values[i] {
+				fields = append(fields, FieldDiff{Field: fi.Name, Column: fi.ColName, Old: oldRow.values[i], New: row.values[i]})
+			}
+		}
+		if len(fields) > 0 {
+			res.Changed = append(res.Changed, RowDiff{Key: row.key, Old: oldRow.v, New: row.v, Fields: fields})
+		}
+	}
+	for _, row := range oldRows {
+		if !newKeys[row.key] {
+			res.Removed = append(res.Removed, row.v)
+		}
+	}
+	return res, nil
+}
+
+// diffRow is a decoded row and its marshalled values.
+type diffRow struct {
+	key    string
+	v      interface{}
+	values []string // value of each field, array elements are joined with commas
+}
+
+// diffRows decodes data into structs of type st and marshals the fields in fis, the value of fis[keyIndex] is the key
+// of each row.
+func diffRows(data []byte, st reflect.Type, fis []fieldInfo, keyIndex int) ([]diffRow, error) {
+	rv := reflect.New(reflect.SliceOf(st))
+	if err := Unmarshal(data, rv.Interface()); err != nil {
+		return nil, err
+	}
+
+	enc := &Encoder{}
+	slice := rv.Elem()
+	rows := make([]diffRow, slice.Len())
+	seen := make(map[string]bool, len(rows))
+	for i := range rows {
+		sv := slice.Index(i)
+		values := make([]string, len(fis))
+		for j, fi := range fis {
+			var err error
+			if values[j], err = enc.fieldString(sv, fi); err != nil {
+				return nil, newEncodeError(st, fi.Name, i, err)
+			}
+		}
+
+		key := values[keyIndex]
+		if seen[key] {
+			return nil, errors.Errorf("row %d: duplicate key %s", i+1, key)
+		}
+		seen[key] = true
+		rows[i] = diffRow{key: key, v: sv.Addr().Interface(), values: values}
+	}
+	return rows, nil
+}
+
+// fieldString returns the marshalled value of the field of the addressable struct sv that fi is for, array elements are
+// joined with commas.
+func (enc *Encoder) fieldString(sv reflect.Value, fi fieldInfo) (string, error) {
+	fv, err := fieldValue(sv, fi)
+	if err != nil {
+		return "", err
+	}
+	vals, err := enc.appendField(nil, fv, fi)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(vals, ","), nil
+}
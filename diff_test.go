@@ -0,0 +1,63 @@
+package csvplus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDiff(t *testing.T) {
+	type Item struct {
+		SKU   string  `csvplus:"sku"`
+		Name  string  `csvplus:"name"`
+		Price float64 `csvplus:"price"`
+	}
+	oldData := []byte("sku,name,price\na1,Apple,1.5\nb2,Banana,0.25\nc3,Cherry,3\n")
+	newData := []byte("sku,price,name\nc3,3.00,Cherry\nd4,2,Date\na1,1.75,Green apple\n")
+
+	res, err := csvplus.Diff(oldData, newData, Item{}, "sku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Added) != 1 || *res.Added[0].(*Item) != (Item{"d4", "Date", 2}) {
+		t.Errorf("unexpected added rows: %v", res.Added)
+	}
+	if len(res.Removed) != 1 || res.Removed[0].(*Item).SKU != "b2" {
+		t.Errorf("unexpected removed rows: %v", res.Removed)
+	}
+	if len(res.Changed) != 1 {
+		t.Fatalf("expected 1 changed row, got: %v", res.Changed)
+	}
+	changed := res.Changed[0]
+	if changed.Key != "a1" || changed.Old.(*Item).Price != 1.5 || changed.New.(*Item).Price != 1.75 {
+		t.Errorf("unexpected changed row: %+v", changed)
+	}
+	expected := []csvplus.FieldDiff{
+		{Field: "Name", Column: "name", Old: "Apple", New: "Green apple"},
+		{Field: "Price", Column: "price", Old: "1.5", New: "1.75"},
+	}
+	if len(changed.Fields) != len(expected) {
+		t.Fatalf("expected %v, got: %v", expected, changed.Fields)
+	}
+	for i := range expected {
+		if changed.Fields[i] != expected[i] {
+			t.Errorf("expected %v, got: %v", expected[i], changed.Fields[i])
+		}
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := csvplus.Diff(oldData, newData, &Item{}, "id")
+		if !errors.Is(err, csvplus.ErrMissingColumn) {
+			t.Errorf("expected ErrMissingColumn, got: %v", err)
+		}
+	})
+
+	t.Run("duplicate key", func(t *testing.T) {
+		_, err := csvplus.Diff(oldData, []byte("sku,name\na1,x\na1,y\n"), Item{}, "sku")
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package csvplus
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// valueSpan is the position in Encoder.buf of the value of record[i].
+type valueSpan struct {
+	i, start, end int
+}
+
+// marshalerImpl records whether a type implements Marshaler, or only a pointer to it does.
+type marshalerImpl struct {
+	value, ptr bool
+}
+
+// marshalerTypes caches marshalerImpls by type, reflect.Type.Implements is slow enough to dominate encoding.
+var marshalerTypes sync.Map
+
+// marshalerOf returns whether t (or a pointer to it) implements Marshaler.
+func marshalerOf(t reflect.Type) marshalerImpl {
+	if impl, ok := marshalerTypes.Load(t); ok {
+		return impl.(marshalerImpl)
+	}
+	impl := marshalerImpl{value: t.Implements(csvMarshalerType)}
+	impl.ptr = !impl.value && reflect.PtrTo(t).Implements(csvMarshalerType)
+	marshalerTypes.Store(t, impl)
+	return impl
+}
+
+// appendValue appends the csv value of the field fv to dst for the field types whose values would otherwise be
+// allocated as separate strings (numbers and times), false is returned (with dst unchanged) for other fields. The
+// value is the same as marshalField returns.
+func appendValue(dst []byte, fv reflect.Value, fi fieldInfo) ([]byte, bool) {
+	if fi.Char {
+		return dst, false
+	}
+	if impl := marshalerOf(fv.Type()); impl.value || impl.ptr {
+		return dst, false
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return dst, true
+		}
+		fv = fv.Elem()
+		if impl := marshalerOf(fv.Type()); impl.value || impl.ptr {
+			return dst, false
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(dst, fv.Int(), fi.Base), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(dst, fv.Uint(), fi.Base), true
+	case reflect.Float32, reflect.Float64:
+		if fi.Format != "" || fi.Percent {
+			return dst, false
+		}
+		return strconv.AppendFloat(dst, fv.Float(), 'f', -1, 64), true
+	case reflect.Struct:
+		if fv.Type().String() != timeType {
+			return dst, false
+		}
+		t := fv.Interface().(time.Time)
+		if fi.Location != nil {
+			t = t.In(fi.Location)
+		}
+		format := fi.Format
+		if format == "" || format == formatAuto {
+			format = time.RFC3339
+		}
+		return appendTime(dst, t, format), true
+	}
+	return dst, false
+}
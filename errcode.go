@@ -0,0 +1,81 @@
+package csvplus
+
+import (
+	"encoding/csv"
+	"errors"
+)
+
+// ErrorCode is a stable, machine readable code for the kind of an error returned by Decode, eg for mapping failures
+// to API error responses or metric labels without parsing error strings.
+type ErrorCode string
+
+// Error codes, see ErrorCodeOf.
+const (
+	CodeUnknown         ErrorCode = "UNKNOWN"
+	CodeParse           ErrorCode = "PARSE_ERROR" // the csv data is malformed, eg a bare quote
+	CodeTypeConversion  ErrorCode = "TYPE_CONVERSION"
+	CodeMissingColumn   ErrorCode = "MISSING_COLUMN"
+	CodeBadTimeLayout   ErrorCode = "BAD_TIME_LAYOUT"
+	CodeRowTooShort     ErrorCode = "ROW_TOO_SHORT"
+	CodeEmptyValue      ErrorCode = "EMPTY_VALUE"
+	CodeDuplicateColumn ErrorCode = "DUPLICATE_COLUMN"
+	CodeHeaderMismatch  ErrorCode = "HEADER_MISMATCH"
+	CodeValidation      ErrorCode = "VALIDATION_FAILED"
+	CodeFieldTooLarge   ErrorCode = "FIELD_TOO_LARGE"
+	CodeTooManyRows     ErrorCode = "TOO_MANY_ROWS"
+	CodeNotSlicePtr     ErrorCode = "NOT_SLICE_POINTER"
+	CodeNotStructPtr    ErrorCode = "NOT_STRUCT_POINTER"
+	CodeInvalidTag      ErrorCode = "INVALID_TAG"
+)
+
+// errorCodes maps sentinel errors to their codes, in the order they're checked.
+var errorCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{ErrTypeConversion, CodeTypeConversion},
+	{ErrMissingColumn, CodeMissingColumn},
+	{ErrBadTimeLayout, CodeBadTimeLayout},
+	{ErrShortRow, CodeRowTooShort},
+	{ErrEmptyValue, CodeEmptyValue},
+	{ErrDuplicateColumn, CodeDuplicateColumn},
+	{ErrHeaderMismatch, CodeHeaderMismatch},
+	{ErrValidation, CodeValidation},
+	{ErrFieldTooLarge, CodeFieldTooLarge},
+	{ErrTooManyRows, CodeTooManyRows},
+	{ErrNotSlicePtr, CodeNotSlicePtr},
+	{ErrNotStructPtr, CodeNotStructPtr},
+	{ErrInvalidTag, CodeInvalidTag},
+}
+
+// codeOf returns the code of the sentinel error kind, "" if kind is nil or has no code.
+func codeOf(kind error) ErrorCode {
+	for _, ec := range errorCodes {
+		if kind == ec.err {
+			return ec.code
+		}
+	}
+	return ""
+}
+
+// ErrorCodeOf returns the code of err (which can be wrapped), the Code of an UnmarshalError or the code of the
+// sentinel error it wraps. CodeUnknown is returned for other errors and "" for nil.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var ue UnmarshalError
+	if errors.As(err, &ue) && ue.Code != "" {
+		return ue.Code
+	}
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return CodeParse
+	}
+	return CodeUnknown
+}
@@ -0,0 +1,61 @@
+package csvplus_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	type Item struct {
+		Name string    `csvplus:"name"`
+		Qty  int       `csvplus:"qty"`
+		When time.Time `csvplus:"when" csvplusFormat:"2006-01-02"`
+	}
+
+	tests := []struct {
+		name string
+		data string
+		code csvplus.ErrorCode
+	}{
+		{"type conversion", "name,qty,when\na,x,2020-01-02\n", csvplus.CodeTypeConversion},
+		{"bad time layout", "name,qty,when\na,1,02/01/2020\n", csvplus.CodeBadTimeLayout},
+		{"row too short", "name,qty,when\na,1\n", csvplus.CodeRowTooShort},
+		{"parse error", "name,qty,when\na,1,\"2020-01-02\n", csvplus.CodeParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []Item
+			err := csvplus.Unmarshal([]byte(tt.data), &items)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if code := csvplus.ErrorCodeOf(err); code != tt.code {
+				t.Errorf("expected code %s, got %s (%v)", tt.code, code, err)
+			}
+			var ue csvplus.UnmarshalError
+			if errors.As(err, &ue) && ue.Code != tt.code {
+				t.Errorf("expected UnmarshalError.Code %s, got %s", tt.code, ue.Code)
+			}
+		})
+	}
+
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader("name,qty\na,1\n")).RequireColumns(true).Decode(&items)
+	if code := csvplus.ErrorCodeOf(err); code != csvplus.CodeMissingColumn {
+		t.Errorf("expected %s, got %s (%v)", csvplus.CodeMissingColumn, code, err)
+	}
+	if code := csvplus.ErrorCodeOf(fmt.Errorf("wrapped: %w", csvplus.ErrTooManyRows)); code != csvplus.CodeTooManyRows {
+		t.Errorf("expected %s, got %s", csvplus.CodeTooManyRows, code)
+	}
+	if code := csvplus.ErrorCodeOf(errors.New("other")); code != csvplus.CodeUnknown {
+		t.Errorf("expected %s, got %s", csvplus.CodeUnknown, code)
+	}
+	if code := csvplus.ErrorCodeOf(nil); code != "" {
+		t.Errorf("expected no code for nil, got %s", code)
+	}
+}
@@ -0,0 +1,217 @@
+package csvplus
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// An EscapeReader reads csv data that escapes special characters with backslashes rather than by quoting them (or
+// doubling quotes), eg the output of MySQL's SELECT ... INTO OUTFILE, which encoding/csv can't parse. \0, \b, \n, \r,
+// \t and \Z are the NUL, backspace, newline, carriage return, tab and control-Z characters, a backslash before any other
+// character (eg the delimiter, a quote, a newline or another backslash) means the character itself. A value that's just
+// \N is NULL and is read as an empty value. Values can also be enclosed in quotes (a quote inside them is escaped or
+// doubled). Empty lines are skipped and \r\n line endings are read as \n. It implements RecordReader so can be used
+// with NewRecordDecoder, or use the Decoder's BackslashEscapes option.
+type EscapeReader struct {
+	Comma   rune // the field delimiter, defaults to , (MySQL uses tab by default)
+	Quote   rune // the character values can be enclosed in, defaults to ", 0 means values aren't enclosed
+	Comment rune // lines starting with Comment are skipped, 0 disables comments
+	// FieldsPerRecord has the same meaning as csv.Reader.FieldsPerRecord, records with the wrong number of fields are
+	// returned with a *csv.ParseError wrapping csv.ErrFieldCount.
+	FieldsPerRecord int
+
+	r      *bufio.Reader
+	field  []byte
+	line   int   // number of lines read
+	offset int64 // number of bytes read
+}
+
+// NewEscapeReader returns an EscapeReader that reads from r.
+func NewEscapeReader(r io.Reader) *EscapeReader {
+	return &EscapeReader{
+		Comma: ',',
+		Quote: '"',
+		r:     bufio.NewReader(r),
+	}
+}
+
+// Read reads the next record, io.EOF is returned when there are no more records.
+func (er *EscapeReader) Read() ([]string, error) {
+	record, _, err := er.read()
+	return record, err
+}
+
+// readRune reads the next rune and counts it.
+func (er *EscapeReader) readRune() (rune, error) {
+	r, size, err := er.r.ReadRune()
+	er.offset += int64(size)
+	if r == '\n' {
+		er.line++
+	}
+	return r, err
+}
+
+// peekRune returns the next rune without reading it.
+func (er *EscapeReader) peekRune() (rune, bool) {
+	r, _, err := er.r.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	er.r.UnreadRune() // nolint: errcheck
+	return r, true
+}
+
+// read reads the next record and returns it with the line it starts on.
+func (er *EscapeReader) read() ([]string, int, error) { // nolint: gocyclo
+	if (!validDelim(er.Comma) && er.Comma != '"') || er.Comma == er.Quote || er.Comma == er.Comment ||
+		er.Comma == '\\' || er.Quote == '\\' {
+		return nil, 0, errors.New("invalid field, quote or comment delimiter")
+	}
+
+	// skip empty lines and comments
+	var r rune
+	var err error
+	for {
+		if r, err = er.readRune(); err != nil {
+			return nil, 0, err
+		}
+		if r == '\r' {
+			if next, ok := er.peekRune(); ok && next == '\n' {
+				continue
+			}
+		}
+		if r == '\n' {
+			continue
+		}
+		if er.Comment != 0 && r == er.Comment {
+			for r != '\n' {
+				if r, err = er.readRune(); err != nil {
+					return nil, 0, err
+				}
+			}
+			continue
+		}
+		break
+	}
+	startLine := er.line + 1
+
+	var record []string
+	quoted := false // in a value enclosed in quotes
+	null := false   // the value so far is \N
+	cr := false     // the last character of the value is an unescaped \r
+	er.field = er.field[:0]
+	endField := func() {
+		if null {
+			er.field = er.field[:0]
+		}
+		record = append(record, string(er.field))
+		er.field, quoted, null, cr = er.field[:0], false, false, false
+	}
+
+	for first := true; ; first = false {
+		if !first {
+			if r, err = er.readRune(); err == io.EOF {
+				if quoted {
+					return record, startLine, &csv.ParseError{StartLine: startLine, Line: er.line + 1, Column: 1,
+						Err: csv.ErrQuote}
+				}
+				break
+			} else if err != nil {
+				return nil, startLine, err
+			}
+		}
+
+		switch {
+		case r == '\\':
+			e, err := er.readRune()
+			if err == io.EOF {
+				er.field = append(er.field, '\\')
+				continue
+			} else if err != nil {
+				return nil, startLine, err
+			}
+			null = e == 'N' && len(er.field) == 0 && !quoted
+			er.field = utf8.AppendRune(er.field, unescape(e))
+			cr = false
+			continue
+		case er.Quote != 0 && r == er.Quote && len(er.field) == 0 && !quoted && !null:
+			quoted = true
+			continue
+		case quoted && r == er.Quote:
+			if next, ok := er.peekRune(); ok && next == er.Quote {
+				er.readRune() // nolint: errcheck
+				er.field = utf8.AppendRune(er.field, er.Quote)
+				continue
+			}
+			quoted = false
+			continue
+		case !quoted && r == er.Comma:
+			endField()
+			continue
+		case !quoted && r == '\n':
+			if cr {
+				er.field = er.field[:len(er.field)-1]
+			}
+		default:
+			null, cr = false, r == '\r'
+			er.field = utf8.AppendRune(er.field, r)
+			continue
+		}
+		break
+	}
+	endField()
+
+	if er.FieldsPerRecord > 0 {
+		if len(record) != er.FieldsPerRecord {
+			return record, startLine, &csv.ParseError{StartLine: startLine, Line: startLine, Column: 1,
+				Err: csv.ErrFieldCount}
+		}
+	} else if er.FieldsPerRecord == 0 {
+		er.FieldsPerRecord = len(record)
+	}
+	return record, startLine, nil
+}
+
+// unescape returns the character the escape sequence \e stands for.
+func unescape(e rune) rune {
+	switch e {
+	case '0':
+		return 0
+	case 'b':
+		return '\b'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return 0x1a
+	}
+	return e
+}
+
+// BackslashEscapes sets whether the input escapes special characters with backslashes (eg MySQL's SELECT ... INTO
+// OUTFILE output), see EscapeReader. The Comma, Comment and FieldsPerRecord options are used, it has no effect if
+// SetCSVReader is used.
+func (dec *Decoder) BackslashEscapes(b bool) *Decoder {
+	dec.backslashEscapes = b
+	return dec
+}
+
+// readEscaped reads the next record with an EscapeReader, the options are read from the csv reader (and
+// FieldsPerRecord is set on it as it would be by its Read).
+func (dec *Decoder) readEscaped() ([]string, int, error) {
+	if dec.escape == nil {
+		dec.escape = NewEscapeReader(dec.src)
+	}
+	er, opts := dec.escape, dec.csvReader
+	er.Comma, er.Comment, er.FieldsPerRecord = opts.Comma, opts.Comment, opts.FieldsPerRecord
+	record, line, err := er.read()
+	opts.FieldsPerRecord = er.FieldsPerRecord
+	return record, line, err
+}
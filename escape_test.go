@@ -0,0 +1,100 @@
+package csvplus_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestEscapeReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		comma rune
+		quote rune
+		want  [][]string
+	}{
+		{
+			name:  "mysql defaults",
+			data:  "1\tline one\\\nline two\t\\N\n2\ttab\\there\\\\\t\\\\N\n",
+			comma: '\t',
+			want:  [][]string{{"1", "line one\nline two", ""}, {"2", "tab\there\\", "\\N"}},
+		},
+		{
+			name:  "escaped delimiter",
+			data:  "a\\,b,c\r\n\r\nd,\\0\\Z\n",
+			comma: ',',
+			want:  [][]string{{"a,b", "c"}, {"d", "\x00\x1a"}},
+		},
+		{
+			name:  "enclosed",
+			data:  "\"a, \\\"b\\\"\",\"c\"\"d\",\"e\nf\"\n\"\\N\",,\"\"\n",
+			comma: ',',
+			quote: '"',
+			want:  [][]string{{"a, \"b\"", "c\"d", "e\nf"}, {"N", "", ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			er := csvplus.NewEscapeReader(strings.NewReader(tt.data))
+			er.Comma, er.Quote = tt.comma, tt.quote
+			var got [][]string
+			for {
+				record, err := er.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	er := csvplus.NewEscapeReader(strings.NewReader("a,b\nc\n\"d\n"))
+	if _, err := er.Read(); err != nil {
+		t.Fatal(err)
+	}
+	var pe *csv.ParseError
+	if _, err := er.Read(); !errors.As(err, &pe) || pe.Err != csv.ErrFieldCount || pe.Line != 2 {
+		t.Errorf("expected a field count error on line 2, got %v", err)
+	}
+	if _, err := er.Read(); !errors.As(err, &pe) || pe.Err != csv.ErrQuote || pe.StartLine != 3 {
+		t.Errorf("expected a quote error starting on line 3, got %v", err)
+	}
+}
+
+func TestDecoder_BackslashEscapes(t *testing.T) {
+	type Item struct {
+		ID    int     `csvplus:"id"`
+		Notes string  `csvplus:"notes"`
+		Price *string `csvplus:"price"`
+	}
+	data := "id\tnotes\tprice\n1\tsays \\\"hi\\\"\\tthere\t\\N\n2\tline\\\nbreak\t9.99\n"
+
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader(data)).Comma('\t').BackslashEscapes(true).Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Notes != "says \"hi\"\tthere" || items[0].Price != nil ||
+		items[1].Notes != "line\nbreak" || *items[1].Price != "9.99" {
+		t.Errorf("unexpected items %+v", items)
+	}
+
+	// errors have the position of the row
+	err = csvplus.NewDecoder(strings.NewReader(data + "x\ty\tz\n")).Comma('\t').BackslashEscapes(true).Decode(&items)
+	var ue csvplus.UnmarshalError
+	if !errors.As(err, &ue) || ue.Row != 3 || ue.Line != 5 || ue.Offset != int64(len(data)) {
+		t.Errorf("expected an error for row 3 on line 5, got %+v", err)
+	}
+}
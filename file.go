@@ -0,0 +1,123 @@
+package csvplus
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// isGzip reports whether the file at path should be (de)compressed with gzip.
+func isGzip(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".gz")
+}
+
+// closeAll closes closers in reverse order (eg a gzip reader before the file it reads), the first error is returned
+// unless err isn't nil.
+func closeAll(err error, closers []io.Closer) error {
+	for i := len(closers) - 1; i >= 0; i-- {
+		if cerr := closers[i].Close(); err == nil && cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewFileDecoder returns a Decoder that reads the file at path, files with a .gz extension are decompressed. Close must
+// be called when it's no longer needed to close the file.
+func NewFileDecoder(path string) (*Decoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isGzip(path) {
+		dec := NewDecoder(f)
+		dec.closers = []io.Closer{f}
+		return dec, nil
+	}
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close() // nolint: errcheck
+		return nil, errors.Wrapf(err, "unable to read gzip file %s", path)
+	}
+	dec := NewDecoder(gzr)
+	dec.closers = []io.Closer{f, gzr}
+	return dec, nil
+}
+
+// Close closes the file of a Decoder returned by NewFileDecoder, it does nothing for other Decoders (the io.Reader
+// passed to NewDecoder isn't closed). It's safe to call more than once.
+func (dec *Decoder) Close() error {
+	closers := dec.closers
+	dec.closers = nil
+	return closeAll(nil, closers)
+}
+
+// NewFileEncoder returns an Encoder that writes to the file at path (which is created or truncated), files with a .gz
+// extension are compressed. Close must be called after the last call to Encode to flush the rows and close the file.
+func NewFileEncoder(path string) (*Encoder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isGzip(path) {
+		enc := NewEncoder(f)
+		enc.closers = []io.Closer{f}
+		return enc, nil
+	}
+
+	gzw := gzip.NewWriter(f)
+	enc := NewEncoder(gzw)
+	enc.closers = []io.Closer{f, gzw}
+	return enc, nil
+}
+
+// UnmarshalFile is the same as Unmarshal but reads the csv data from the file at path, files with a .gz extension are
+// decompressed.
+func UnmarshalFile(path string, v interface{}) error {
+	dec, err := NewFileDecoder(path)
+	if err != nil {
+		return err
+	}
+	defer dec.Close() // nolint: errcheck
+
+	return dec.Decode(v)
+}
+
+// UnmarshalGlob is the same as UnmarshalFile but reads every file that matches pattern (see filepath.Match, eg
+// data/2024-*.csv) in lexical order, the rows of all of them are appended to the slice v points to. Errors include the
+// path of the file, rows already decoded from earlier files are kept. An error is returned if no files match.
+func UnmarshalGlob(pattern string, v interface{}) error {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return errors.Wrapf(err, "invalid pattern %s", pattern)
+	}
+	if len(paths) == 0 {
+		return errors.Errorf("no files match %s", pattern)
+	}
+
+	for _, path := range paths {
+		if err := UnmarshalFile(path, v); err != nil {
+			return errors.Wrapf(err, "file %s", path)
+		}
+	}
+	return nil
+}
+
+// MarshalFile is the same as Marshal but writes the csv data to the file at path (which is created or truncated),
+// files with a .gz extension are compressed.
+func MarshalFile(path string, v interface{}) error {
+	enc, err := NewFileEncoder(path)
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode(v); err != nil {
+		enc.Close() // nolint: errcheck
+		return err
+	}
+	return enc.Close()
+}
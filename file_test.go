@@ -0,0 +1,164 @@
+package csvplus_test
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestMarshalFileUnmarshalFile(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	items := []Item{
+		{"a", 1},
+		{"b", 2},
+	}
+
+	for _, name := range []string{"items.csv", "items.csv.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			err := csvplus.MarshalFile(path, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded []Item
+			err = csvplus.UnmarshalFile(path, &decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded) != 2 {
+				t.Fatalf("expected len of %d, got: %d", 2, len(decoded))
+			}
+			if decoded[1] != items[1] {
+				t.Errorf("expected %v, got: %v", items[1], decoded[1])
+			}
+		})
+	}
+
+	t.Run("gzip compressed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "items.csv.gz")
+		err := csvplus.MarshalFile(path, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := gzip.NewReader(f); err != nil {
+			t.Errorf("expected gzip data, got: %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		var decoded []Item
+		err := csvplus.UnmarshalFile(filepath.Join(t.TempDir(), "missing.csv"), &decoded)
+		if !os.IsNotExist(err) {
+			t.Errorf("expected not exist error, got: %v", err)
+		}
+	})
+}
+
+func TestUnmarshalGlob(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+
+	dir := t.TempDir()
+	files := map[string][]Item{
+		"2024-01.csv":    {{"a", 1}},
+		"2024-02.csv.gz": {{"b", 2}, {"c", 3}},
+		"2023-12.csv":    {{"x", 0}},
+	}
+	for name, items := range files {
+		items := items
+		if err := csvplus.MarshalFile(filepath.Join(dir, name), &items); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var decoded []Item
+	if err := csvplus.UnmarshalGlob(filepath.Join(dir, "2024-*"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Item{{"a", 1}, {"b", 2}, {"c", 3}}
+	if len(decoded) != len(expected) {
+		t.Fatalf("expected %v, got: %v", expected, decoded)
+	}
+	for i := range expected {
+		if decoded[i] != expected[i] {
+			t.Errorf("expected %v, got: %v", expected[i], decoded[i])
+		}
+	}
+
+	bad := filepath.Join(dir, "2024-03.csv")
+	if err := os.WriteFile(bad, []byte("First,Second\nd,x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := csvplus.UnmarshalGlob(filepath.Join(dir, "2024-*"), &decoded)
+	if err == nil || !strings.Contains(err.Error(), bad) {
+		t.Errorf("expected error for %s, got: %v", bad, err)
+	}
+
+	if err := csvplus.UnmarshalGlob(filepath.Join(dir, "2025-*"), &decoded); err == nil {
+		t.Error("expected error when no files match")
+	}
+}
+
+func TestNewFileEncoderNewFileDecoder(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+
+	for _, name := range []string{"items.csv", "items.csv.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			enc, err := csvplus.NewFileEncoder(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the record written by WriteRecord is only flushed (and the gzip stream completed) by Close
+			if err := enc.Encode(&[]Item{{"a", 1}, {"b", 2}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.WriteRecord([]string{"c", "3"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Errorf("expected closing twice to be a no-op, got: %v", err)
+			}
+
+			dec, err := csvplus.NewFileDecoder(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded []Item
+			if err := dec.Decode(&decoded); err != nil {
+				t.Fatal(err)
+			}
+			if err := dec.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded) != 3 || decoded[2] != (Item{"c", 3}) {
+				t.Errorf("unexpected items %v", decoded)
+			}
+		})
+	}
+
+	if _, err := csvplus.NewFileDecoder(filepath.Join(t.TempDir(), "missing.csv")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package csvplus
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// commonInitialisms are words that are upper cased in generated field names.
+var commonInitialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "IP": true, "UUID": true, "HTTP": true, "API": true, "SKU": true,
+}
+
+// GenerateStruct reads the header row and up to sampleRows data rows from the csv data in r and returns the source of
+// a Go struct type called name with a csvplus tagged field per column. Field types (int, float64, bool, time.Time,
+// csvplus.Date or string) are inferred from the sample, columns with empty values get pointer types and time layouts
+// are detected and added as csvplusFormat tags. The generated code may need the time and csvplus packages importing.
+func GenerateStruct(r io.Reader, name string, sampleRows int) ([]byte, error) {
+	csvReader := csv.NewReader(newBOMReader(r))
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read header row")
+	}
+
+	columns := make([][]string, len(header))
+	for row := 0; row < sampleRows; row++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading csv reader")
+		}
+		for i, v := range record {
+			columns[i] = append(columns[i], v)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s struct {\n", name)
+	fieldNames := make(map[string]int)
+	for i, colName := range header {
+		fieldName := goFieldName(colName, i)
+		if fieldNames[fieldName]++; fieldNames[fieldName] > 1 {
+			fieldName = fmt.Sprintf("%s%d", fieldName, fieldNames[fieldName])
+		}
+
+		ct := inferColumnType(columns[i])
+		var typ, format string
+		switch ct.kind {
+		case kindBool:
+			typ = "bool"
+		case kindInt:
+			typ = "int"
+		case kindFloat:
+			typ = "float64"
+		case kindDate:
+			typ = "csvplus.Date"
+			if ct.layout != dateLayout {
+				format = ct.layout
+			}
+		case kindTime:
+			typ = "time.Time"
+			format = ct.layout
+		default:
+			typ = "string"
+		}
+		if ct.nullable && ct.kind != kindString {
+			typ = "*" + typ
+		}
+
+		fmt.Fprintf(&buf, "\t%s %s `csvplus:%q", fieldName, typ, colName)
+		if format != "" {
+			fmt.Fprintf(&buf, " csvplusFormat:%q", format)
+		}
+		buf.WriteString("`\n")
+	}
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// goFieldName converts a column name (eg "first name", "user_id") to an exported Go identifier (FirstName, UserID).
+func goFieldName(colName string, colIndex int) string {
+	words := strings.FieldsFunc(colName, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+
+	name := b.String()
+	if name == "" {
+		return fmt.Sprintf("Col%d", colIndex)
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "Col" + name
+	}
+	return name
+}
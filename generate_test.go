@@ -0,0 +1,32 @@
+package csvplus_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func ExampleGenerateStruct() {
+	data := "id,first name,score,ratio,active,joined,last_seen,notes\n" +
+		"1,Rob,7,0.5,true,1999-11-01,2020-01-02 15:04:05,\n" +
+		"2,Russ,,1,false,2001-03-14,2021-06-07 08:09:10,hello\n"
+
+	src, err := csvplus.GenerateStruct(strings.NewReader(data), "Item", 100)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Print(string(src))
+	// Output:
+	// type Item struct {
+	// 	ID        int          `csvplus:"id"`
+	// 	FirstName string       `csvplus:"first name"`
+	// 	Score     *int         `csvplus:"score"`
+	// 	Ratio     float64      `csvplus:"ratio"`
+	// 	Active    bool         `csvplus:"active"`
+	// 	Joined    csvplus.Date `csvplus:"joined"`
+	// 	LastSeen  time.Time    `csvplus:"last_seen" csvplusFormat:"2006-01-02 15:04:05"`
+	// 	Notes     string       `csvplus:"notes"`
+	// }
+}
@@ -0,0 +1,263 @@
+package csvplus
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DuplicateHeaders is the policy for mapping header rows that have more than one column with the same name.
+type DuplicateHeaders int
+
+const (
+	// DuplicateHeadersLast maps fields to the last of the columns with the same name, it's the default.
+	DuplicateHeadersLast DuplicateHeaders = iota
+	// DuplicateHeadersFirst maps fields to the first of the columns with the same name.
+	DuplicateHeadersFirst
+	// DuplicateHeadersError makes Decode return an error wrapping ErrDuplicateColumn with the duplicate names.
+	DuplicateHeadersError
+	// DuplicateHeadersSuffix renames the second and later columns with the same name by adding a suffix (eg name,
+	// name_2, name_3), the suffixed names can be used in tags.
+	DuplicateHeadersSuffix
+)
+
+// DuplicateHeaders sets the policy for header rows with duplicate column names, names are compared after MapHeader has
+// been applied.
+func (dec *Decoder) DuplicateHeaders(p DuplicateHeaders) *Decoder {
+	dec.duplicateHeaders = p
+	return dec
+}
+
+// duplicateColumns returns the names that are used by more than one column of header, in the order they're first
+// duplicated.
+func duplicateColumns(header []string) []string {
+	seen := make(map[string]int, len(header))
+	var dups []string
+	for _, colName := range header {
+		seen[colName]++
+		if seen[colName] == 2 {
+			dups = append(dups, colName)
+		}
+	}
+	return dups
+}
+
+// suffixDuplicates renames the second and later columns of header with the same name to name_2, name_3 etc, suffixes
+// already used by other columns are skipped.
+func suffixDuplicates(header []string) {
+	used := make(map[string]bool, len(header))
+	for _, colName := range header {
+		used[colName] = true
+	}
+
+	counts := make(map[string]int, len(header))
+	for i, colName := range header {
+		counts[colName]++
+		if counts[colName] == 1 {
+			continue
+		}
+		for {
+			name := fmt.Sprintf("%s_%d", colName, counts[colName])
+			if !used[name] {
+				header[i] = name
+				used[name] = true
+				break
+			}
+			counts[colName]++
+		}
+	}
+}
+
+// Report is the result of checking a header row against a struct, see ValidateHeader.
+type Report struct {
+	Missing    []string // columns from csvplus tags that aren't in the header row
+	Extra      []string // columns in the header row that aren't mapped to a field
+	Duplicates []string // column names used more than once in the header row
+}
+
+// OK reports whether there are no missing, extra or duplicate columns.
+func (r Report) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Duplicates) == 0
+}
+
+// ValidateHeader reads just the header row of data and checks it against the struct v (or the struct type of the
+// slice v points to) without decoding any rows, eg as a pre-flight check before a long import.
+func ValidateHeader(data []byte, v interface{}) (Report, error) {
+	return NewDecoder(bytes.NewReader(data)).ValidateHeader(v)
+}
+
+// ValidateHeader is the same as the ValidateHeader function but uses the options of the Decoder (eg Comma, SkipRows,
+// MapHeader or LooseHeaderMatching), it reads the header row so must be called before anything else.
+func (dec *Decoder) ValidateHeader(v interface{}) (Report, error) {
+	if dec.withoutHeader {
+		return Report{}, errors.New("there's no header row to validate")
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return Report{}, fmt.Errorf("expected struct, got %T", v)
+	}
+
+	if _, err := dec.ReadRecord(); err != nil {
+		return Report{}, errors.Wrap(err, "unable to read header row")
+	}
+	// missing columns are reported rather than being an error
+	requireColumns := dec.requireColumns
+	dec.requireColumns = false
+	err := dec.mapColumns(t, dec.header)
+	dec.requireColumns = requireColumns
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Missing:    missingColumns(t, dec.fis, dec.tags),
+		Duplicates: duplicateColumns(dec.header),
+	}
+	fieldMap := dec.FieldMap()
+	for _, colName := range dec.header {
+		if _, mapped := fieldMap[colName]; !mapped {
+			report.Extra = append(report.Extra, colName)
+		}
+	}
+	return report, nil
+}
+
+// ExactHeader sets whether Decode fails (with an error wrapping ErrHeaderMismatch) unless the header row has exactly
+// the columns of the struct, in the same order as they're marshalled (or the columns of the Schema in order), eg to
+// enforce a schema contract with a data supplier. The error describes the differences line by line like a diff, with
+// - for expected columns that are missing and + for unexpected columns. Names are compared after MapHeader has been
+// applied, aliases (eg email|e-mail) match by their first name.
+func (dec *Decoder) ExactHeader(b bool) *Decoder {
+	dec.exactHeader = b
+	return dec
+}
+
+// checkExactHeader returns an error wrapping ErrHeaderMismatch if header isn't exactly the columns of rowType.
+func (dec *Decoder) checkExactHeader(rowType reflect.Type, header []string) error {
+	var expected []string
+	if dec.schema != nil {
+		expected = dec.schema.Header()
+	} else {
+		fis, err := tagEncRegister(dec.tags).encodeFields(rowType)
+		if err != nil {
+			return err
+		}
+		stored := fis[:0:0]
+		for _, fi := range fis {
+			if fi.Method == "" {
+				stored = append(stored, fi)
+			}
+		}
+		expected = headerRow(stored, nil)
+	}
+
+	if len(expected) == len(header) {
+		same := true
+		for i := range expected {
+			same = same && expected[i] == header[i]
+		}
+		if same {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w:\n%s", ErrHeaderMismatch, headerDiff(expected, header))
+}
+
+// headerDiff describes the differences between the expected and actual header rows with a line per column, columns
+// in both are prefixed with a space, missing columns with - and unexpected columns with +.
+func headerDiff(expected, got []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of expected[i:] and got[j:]
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			switch {
+			case expected[i] == got[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(expected) || j < len(got) {
+		switch {
+		case i < len(expected) && j < len(got) && expected[i] == got[j]:
+			lines = append(lines, "  "+expected[i])
+			i++
+			j++
+		case j == len(got) || i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+expected[i])
+			i++
+		default:
+			lines = append(lines, "+ "+got[j])
+			j++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DetectHeader sets whether the first row is checked to decide if it's a header row, rather than that being set by
+// UseHeader, eg for files that are uploaded both with and without headers. It's a header row if any of its values (after
+// MapHeader has been applied) are the names of columns of the struct (or Schema), or if it can't be unmarshalled as a
+// data row (eg the header of a number column isn't a number). It has no effect with Scan or ReadRecord as the first row
+// is read before the type of the rows is known.
+func (dec *Decoder) DetectHeader(b bool) *Decoder {
+	dec.detectHeader = b
+	return dec
+}
+
+// isHeader reports whether record, the first row, is a header row for rowType, see DetectHeader.
+func (dec *Decoder) isHeader(rowType reflect.Type, record []string) bool {
+	header := make([]string, len(record))
+	for i, colName := range record {
+		if dec.mapHeader != nil {
+			colName = dec.mapHeader(colName)
+		}
+		header[i] = colName
+	}
+	mo := matchOptions{
+		loose:    dec.looseHeaders,
+		firstDup: dec.duplicateHeaders == DuplicateHeadersFirst,
+		tags:     dec.tags,
+	}
+	// getFieldInfo is used rather than the cache so data rows aren't cached
+	fieldInfo := func(header []string, mo matchOptions) ([]fieldInfo, error) {
+		if dec.schema != nil {
+			return dec.schema.fieldInfo(rowType, header, mo)
+		}
+		return getFieldInfo(rowType, header, mo)
+	}
+
+	fis, err := fieldInfo(header, mo)
+	if err != nil {
+		return false
+	}
+	for _, fi := range fis {
+		if !fi.SkipField && fi.ColName != "" {
+			return true
+		}
+	}
+
+	mo.withoutHeader = true
+	if fis, err = fieldInfo(record, mo); err != nil {
+		return false
+	}
+	s := reflect.New(rowType).Elem()
+	if rowType == mapType {
+		s.Set(reflect.MakeMapWithSize(mapType, len(fis)))
+	}
+	return dec.unmarshalRecord(0, record, s, fis) != nil
+}
@@ -0,0 +1,143 @@
+package csvplus_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestValidateHeader(t *testing.T) {
+	type Item struct {
+		ID    int    `csvplus:"id"`
+		Name  string `csvplus:"name"`
+		Email string `csvplus:"email|e-mail"`
+		Notes string
+	}
+
+	report, err := csvplus.ValidateHeader([]byte("id,e-mail,notes,extra,extra\n1,not read\n"), &[]Item{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := csvplus.Report{
+		Missing:    []string{"name"},
+		Extra:      []string{"extra", "extra"},
+		Duplicates: []string{"extra"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, report)
+	}
+	if report.OK() {
+		t.Error("expected report not to be OK")
+	}
+
+	report, err = csvplus.ValidateHeader([]byte("name,id,email,Notes\n"), Item{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("expected OK report, got: %+v", report)
+	}
+
+	if _, err := csvplus.ValidateHeader([]byte("id\n"), 1); err == nil {
+		t.Error("expected error for non struct")
+	}
+}
+
+func TestDecoder_ExactHeader(t *testing.T) {
+	type Item struct {
+		ID    int    `csvplus:"id"`
+		Name  string `csvplus:"name"`
+		Email string `csvplus:"email"`
+	}
+
+	tests := map[string]struct {
+		header string
+		diff   string
+	}{
+		"exact":     {"id,name,email", ""},
+		"reordered": {"id,email,name", "  id\n- name\n  email\n+ name"},
+		"drifted":   {"id,full_name,email,phone", "  id\n- name\n+ full_name\n  email\n+ phone"},
+		"missing":   {"id,name", "  id\n  name\n- email"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var items []Item
+			err := csvplus.NewDecoder(strings.NewReader(tt.header + "\n")).ExactHeader(true).Decode(&items)
+			if tt.diff == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, csvplus.ErrHeaderMismatch) {
+				t.Fatalf("expected ErrHeaderMismatch, got: %v", err)
+			}
+			if !strings.HasSuffix(err.Error(), ":\n"+tt.diff) {
+				t.Errorf("expected diff\n%s\ngot:\n%s", tt.diff, err)
+			}
+		})
+	}
+
+	t.Run("schema", func(t *testing.T) {
+		schema := csvplus.NewSchema(csvplus.Column("a", nil), csvplus.Column("b", nil))
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("b,a\n")).Schema(schema).ExactHeader(true).Decode(&rows)
+		if !errors.Is(err, csvplus.ErrHeaderMismatch) {
+			t.Errorf("expected ErrHeaderMismatch, got: %v", err)
+		}
+	})
+}
+
+func TestDecoder_DetectHeader(t *testing.T) {
+	// fields are only mapped by position without a header row if they don't have tags
+	type Item struct {
+		Name  string
+		Price float64
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"header", "Name,Price\nwidget,1.5\ngadget,10\n"},
+		{"no header", "widget,1.5\ngadget,10\n"},
+		{"unknown header", "product,cost\nwidget,1.5\ngadget,10\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []Item
+			err := csvplus.NewDecoder(strings.NewReader(tt.data)).DetectHeader(true).Decode(&items)
+			if tt.name == "unknown header" {
+				// the header row is skipped but none of its columns are mapped
+				if err != nil || len(items) != 2 || items[0] != (Item{}) {
+					t.Errorf("unexpected items %v (%v)", items, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []Item{{"widget", 1.5}, {"gadget", 10}}
+			if !reflect.DeepEqual(items, want) {
+				t.Errorf("expected %v, got %v", want, items)
+			}
+		})
+	}
+
+	t.Run("schema", func(t *testing.T) {
+		schema := csvplus.NewSchema().Add(csvplus.Column("name", "")).Add(csvplus.Column("price", 0.0))
+		for _, data := range []string{"name,price\nwidget,1.5\n", "widget,1.5\n"} {
+			var rows []map[string]interface{}
+			err := csvplus.NewDecoder(strings.NewReader(data)).Schema(schema).DetectHeader(true).Decode(&rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 || rows[0]["name"] != "widget" || rows[0]["price"] != 1.5 {
+				t.Errorf("unexpected rows %v for %q", rows, data)
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package csvplus
+
+import (
+	"mime"
+	"net/http"
+)
+
+// ServeCSV writes the slice pointed to by v to w as csv data, the Content-Type header is set to text/csv and the
+// Content-Disposition header makes browsers download it as filename. Rows are streamed as they're encoded so an error
+// part way through can't change the response status, the error is returned for logging.
+func ServeCSV(w http.ResponseWriter, v interface{}, filename string) error {
+	h := w.Header()
+	h.Set("Content-Type", "text/csv; charset=utf-8")
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,33 @@
+package csvplus_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestServeCSV(t *testing.T) {
+	type Item struct {
+		First  string
+		Second int
+	}
+	items := []Item{{"a", 1}, {"b", 2}}
+
+	rec := httptest.NewRecorder()
+	if err := csvplus.ServeCSV(rec, &items, "items export.csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	expectedCD := `attachment; filename="items export.csv"`
+	if cd := rec.Header().Get("Content-Disposition"); cd != expectedCD {
+		t.Errorf("expected Content-Disposition %s, got: %s", expectedCD, cd)
+	}
+	expected := "First,Second\na,1\nb,2\n"
+	if rec.Body.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, rec.Body.String())
+	}
+}
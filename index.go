@@ -0,0 +1,132 @@
+package csvplus
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// An Index records the positions of the rows of csv data so a Decoder can Seek to any row without reading the rows
+// before it, eg to paginate over a large file. Create one with Decoder.BuildIndex.
+type Index struct {
+	positions []recordPos // positions[n] is the position of row n, row 0 is the header row if there is one
+	end       recordPos
+}
+
+// Len returns the number of rows in the index, including the header row if there is one.
+func (idx *Index) Len() int {
+	return len(idx.positions)
+}
+
+// BuildIndex reads the rest of the input and returns an Index of the positions of its rows, rows are numbered the same
+// way as UnmarshalError.Row (the header row, if there is one, is row 0). The Decoder can't be used to decode
+// afterwards, the Index is used with other Decoders of the same data (with the same options).
+func (dec *Decoder) BuildIndex() (*Index, error) {
+	if dec.recordReader != nil {
+		return nil, errors.New("unable to index records from a RecordReader")
+	}
+
+	idx := &Index{}
+	for {
+		_, err := dec.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if _, isParseErr := err.(*csv.ParseError); err != nil && !isParseErr {
+			return nil, err
+		}
+		// rows that can't be parsed are still rows
+		idx.positions = append(idx.positions, dec.pos)
+	}
+	idx.end = recordPos{offset: dec.inputOffset()}
+	return idx, nil
+}
+
+// Index sets the index used by Seek, it must have been built from the same data using the same options.
+func (dec *Decoder) Index(idx *Index) *Decoder {
+	dec.index = idx
+	return dec
+}
+
+// Seek moves the Decoder to row n so it's the next row decoded, rows are numbered the same way as UnmarshalError.Row
+// (the header row, if there is one, is row 0 and is still used to map columns to fields). Seeking to Index.Len()
+// moves to the end of the data. The input passed to NewDecoder must implement io.Seeker, Seek isn't supported for
+// input with a Charset or UTF-16 BOM, or when SetCSVReader is used.
+func (dec *Decoder) Seek(n int) error {
+	if dec.err != nil {
+		return dec.err
+	}
+	if dec.index == nil {
+		return errors.New("Seek requires an Index")
+	}
+	if n < 0 || n > dec.index.Len() {
+		return fmt.Errorf("row %d out of range, the index has %d rows", n, dec.index.Len())
+	}
+
+	if n == 0 && dec.headerPassed && !dec.withoutHeader {
+		// the header row has already been used
+		n = 1
+	}
+	if !dec.headerPassed && !dec.withoutHeader && n > 0 {
+		if err := dec.seekTo(dec.index.positions[0]); err != nil {
+			return err
+		}
+		record, _, err := dec.read()
+		if err != nil {
+			return errors.Wrap(err, "unable to read header row")
+		}
+		dec.setHeader(record)
+		dec.headerPassed = true
+		dec.rowType = nil
+	}
+
+	pos := dec.index.end
+	if n < dec.index.Len() {
+		pos = dec.index.positions[n]
+	}
+	if err := dec.seekTo(pos); err != nil {
+		return err
+	}
+	dec.row = n
+	dec.skipRows = 0
+	dec.footer = nil
+	dec.stopped = false
+	return nil
+}
+
+// seekTo moves the input to pos and replaces the csv reader (which buffers its input) with a new one with the same
+// options.
+func (dec *Decoder) seekTo(pos recordPos) error {
+	seeker, ok := dec.src.r.(io.Seeker)
+	if !ok || dec.recordReader != nil || dec.customReader {
+		return errors.New("Seek requires input that implements io.Seeker")
+	}
+	if !dec.src.checked {
+		if err := dec.src.checkBOM(); err != nil {
+			return errors.Wrap(err, "unable to read input")
+		}
+	}
+	if dec.src.order != nil {
+		return errors.New("Seek doesn't support UTF-16 input")
+	}
+
+	if _, err := seeker.Seek(pos.offset+int64(dec.src.bomLen), io.SeekStart); err != nil {
+		return errors.Wrap(err, "unable to seek")
+	}
+	dec.src.prefix, dec.src.peeked = nil, nil
+	dec.src.n = pos.offset
+
+	csvReader := csv.NewReader(dec.src)
+	csvReader.Comma = dec.csvReader.Comma
+	csvReader.Comment = dec.csvReader.Comment
+	csvReader.FieldsPerRecord = dec.csvReader.FieldsPerRecord
+	csvReader.LazyQuotes = dec.csvReader.LazyQuotes
+	csvReader.TrimLeadingSpace = dec.csvReader.TrimLeadingSpace
+	csvReader.ReuseRecord = dec.csvReader.ReuseRecord
+	dec.csvReader = csvReader
+	dec.project, dec.escape = nil, nil
+	dec.posBase = recordPos{line: pos.line - 1, offset: pos.offset}
+	return nil
+}
@@ -0,0 +1,67 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDecoder_Seek(t *testing.T) {
+	type Item struct {
+		Name string `csvplus:"name"`
+		Age  int    `csvplus:"age"`
+	}
+	data := []byte("\xef\xbb\xbfname,age\nRob,1\n\"Russ\nCox\",2\nKen,3\nRobert,x\nIan,5\n")
+
+	idx, err := csvplus.NewDecoder(bytes.NewReader(data)).BuildIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Len() != 6 {
+		t.Errorf("expected 6 rows, got: %d", idx.Len())
+	}
+
+	dec := csvplus.NewDecoder(bytes.NewReader(data)).Index(idx)
+	if err := dec.Seek(3); err != nil {
+		t.Fatal(err)
+	}
+	var item Item
+	if err := dec.DecodeOneStruct(&item); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Item{"Ken", 3}); item != expected {
+		t.Errorf("expected %v, got: %v", expected, item)
+	}
+
+	var ue csvplus.UnmarshalError
+	err = dec.DecodeOneStruct(&item)
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected UnmarshalError, got: %v", err)
+	}
+	if ue.Row != 4 || ue.Line != 6 || ue.Offset != int64(strings.Index(string(data), "Robert")-3) {
+		t.Errorf("unexpected error position, row %d line %d offset %d", ue.Row, ue.Line, ue.Offset)
+	}
+
+	if err := dec.Seek(1); err != nil {
+		t.Fatal(err)
+	}
+	var items []Item
+	if err := dec.DecodeBatch(2, &items); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Item{{"Rob", 1}, {"Russ\nCox", 2}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %v, got: %v", expected, items)
+	}
+
+	if err := dec.Seek(7); err == nil {
+		t.Error("expected out of range error")
+	}
+	if err := csvplus.NewDecoder(strings.NewReader(string(data))).Seek(1); err == nil {
+		t.Error("expected error without an Index")
+	}
+}
@@ -0,0 +1,291 @@
+package csvplus
+
+import (
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// columnKind is the type inferred for a column from sample values.
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindTime
+	kindDate
+)
+
+// columnType is the type inferred for a column from sample values.
+type columnType struct {
+	kind     columnKind
+	layout   string // only set for kindTime and kindDate
+	nullable bool   // some of the sample values were empty
+}
+
+// dateLayouts are the date only layouts tried when detecting a layout, in order of preference (ISO, US then EU when
+// ambiguous).
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"02/01/2006",
+	"02.01.2006",
+	"01-02-2006",
+	"02-01-2006",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+	"02-Jan-2006",
+	"January 2, 2006",
+	"2 January 2006",
+}
+
+// timeLayouts are the date and time layouts tried when detecting a layout, in order of preference.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+	time.UnixDate,
+}
+
+// autoTimeLayouts are the layouts tried for csvplusFormat:"auto" time.Time fields, in order of preference.
+var autoTimeLayouts = append(append([]string(nil), dateLayouts...), timeLayouts...)
+
+// autoLayout returns the layout used for the value v of the csvplusFormat:"auto" column fi, it's the first of layouts
+// that can parse every value of the column so far. Ambiguous values are parsed with the first layout that fits them
+// until a later value rules it out (eg 01/02/2006 is used until 13/02/2006 is seen), so they can be decoded with a
+// different layout to the rest of the column. false is returned if none of the remaining layouts can parse v.
+func (dec *Decoder) autoLayout(fi fieldInfo, v string, layouts []string) (string, bool) {
+	if candidates, found := dec.autoLayouts[fi.ColIndex]; found {
+		layouts = candidates
+	}
+
+	var matched []string
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			matched = append(matched, layout)
+		}
+	}
+	if len(matched) == 0 {
+		return "", false
+	}
+	if dec.autoLayouts == nil {
+		dec.autoLayouts = make(map[int][]string)
+	}
+	dec.autoLayouts[fi.ColIndex] = matched
+	return matched[0], true
+}
+
+// detectLayout returns the first of layouts that all of the (non empty) values can be parsed with.
+func detectLayout(values []string, layouts []string) (string, bool) {
+	for _, layout := range layouts {
+		parsed := 0
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			if _, err := time.Parse(layout, v); err != nil {
+				break
+			}
+			parsed++
+		}
+		if parsed > 0 && parsed == countNonEmpty(values) {
+			return layout, true
+		}
+	}
+	return "", false
+}
+
+// detectTimeLayout returns a layout (date only layouts are preferred) that all of the values can be parsed with.
+func detectTimeLayout(values []string) (string, bool) {
+	if layout, ok := detectLayout(values, dateLayouts); ok {
+		return layout, true
+	}
+	return detectLayout(values, timeLayouts)
+}
+
+func countNonEmpty(values []string) int {
+	var n int
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// isBoolWord reports whether s is a bool that isn't also a number (strconv.ParseBool accepts 1 and 0).
+func isBoolWord(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "t", "f":
+		return true
+	}
+	return false
+}
+
+// inferColumnType infers the narrowest type that all of the sample values of a column can be converted to.
+func inferColumnType(values []string) columnType {
+	var ct columnType
+	nonEmpty := countNonEmpty(values)
+	ct.nullable = nonEmpty < len(values)
+	if nonEmpty == 0 {
+		return ct
+	}
+
+	all := func(fn func(string) bool) bool {
+		for _, v := range values {
+			if v != "" && !fn(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(isBoolWord):
+		ct.kind = kindBool
+	case all(func(s string) bool { _, err := strconv.ParseInt(s, 10, 64); return err == nil }):
+		ct.kind = kindInt
+	case all(func(s string) bool { _, err := strconv.ParseFloat(s, 64); return err == nil }):
+		ct.kind = kindFloat
+	default:
+		if layout, ok := detectLayout(values, dateLayouts); ok {
+			ct.kind, ct.layout = kindDate, layout
+		} else if layout, ok := detectLayout(values, timeLayouts); ok {
+			ct.kind, ct.layout = kindTime, layout
+		}
+	}
+	return ct
+}
+
+// InferTypes sets the number of data rows sampled to infer the type of each column (int, float64, bool, time.Time,
+// Date or string) when decoding into a *[]map[string]interface{} without a Schema, 0 (the default) disables inference.
+// The sampled rows are decoded as normal once the types are known. Columns with empty values get pointer types (eg
+// *int) and time layouts are detected, without a header row the map keys are the column indices (eg "0"). The inferred
+// schema is used for the rest of the data, values that don't convert to the inferred type are errors (see OnError).
+func (dec *Decoder) InferTypes(sampleRows int) *Decoder {
+	dec.inferRows = sampleRows
+	return dec
+}
+
+// inferSchema reads the header row and up to dec.inferRows data rows and sets a schema built from the types inferred
+// for their columns, the rows are held in dec.sample until they're returned by nextRecord.
+func (dec *Decoder) inferSchema() error {
+	if !dec.headerPassed && dec.skipRows > 0 {
+		if err := dec.skipPreamble(); err != nil {
+			return err
+		}
+	}
+
+	header := dec.header
+	if !dec.headerPassed && !dec.withoutHeader {
+		record, err := dec.readRecord()
+		if err != nil {
+			// returned by nextRecord
+			dec.schema = NewSchema()
+			if err != io.EOF {
+				dec.sample = append(dec.sample, footerRecord{record: record, err: err, pos: dec.pos})
+			}
+			return nil
+		}
+		dec.row++
+		header = dec.setHeader(record)
+		dec.headerPassed, dec.width = true, len(header)
+	}
+
+	var columns [][]string
+	for len(dec.sample) < dec.inferRows {
+		record, err := dec.readRecord()
+		if err == io.EOF {
+			break
+		}
+		record = append([]string(nil), record...)
+		dec.sample = append(dec.sample, footerRecord{record: record, err: err, pos: dec.pos})
+		if err != nil {
+			if _, isParseErr := err.(*csv.ParseError); !isParseErr {
+				break
+			}
+			continue
+		}
+		for i, v := range record {
+			if i == len(columns) {
+				columns = append(columns, nil)
+			}
+			columns[i] = append(columns[i], v)
+		}
+	}
+
+	if dec.withoutHeader {
+		header = make([]string, len(columns))
+		for i := range header {
+			header[i] = strconv.Itoa(i)
+		}
+	}
+
+	schema := NewSchema()
+	for i, colName := range header {
+		var values []string
+		if i < len(columns) {
+			values = columns[i]
+		}
+		schema.Add(inferredColumn(colName, inferColumnType(values)))
+	}
+	dec.schema = schema
+	return nil
+}
+
+// inferredColumn returns the schema column for a column of type ct.
+func inferredColumn(name string, ct columnType) ColumnSpec {
+	var v interface{}
+	switch ct.kind {
+	case kindBool:
+		v = false
+	case kindInt:
+		v = 0
+	case kindFloat:
+		v = 0.0
+	case kindDate:
+		v = Date{}
+	case kindTime:
+		v = time.Time{}
+	default:
+		return Column(name, nil)
+	}
+
+	col := Column(name, v).Layout(ct.layout)
+	if ct.nullable {
+		col.typ = reflect.PtrTo(col.typ)
+	}
+	return col
+}
+
+// readSample returns the rows read by inferSchema before reading any more rows.
+func (dec *Decoder) readSample() ([]string, error) {
+	if len(dec.sample) == 0 {
+		return dec.readRecord()
+	}
+	sr := dec.sample[0]
+	dec.sample = dec.sample[1:]
+	dec.pos = sr.pos
+	return sr.record, sr.err
+}
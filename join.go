@@ -0,0 +1,168 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// JoinType is the kind of join done by Join.
+type JoinType int
+
+const (
+	// InnerJoin only combines the left rows that have a matching right row.
+	InnerJoin JoinType = iota
+	// LeftJoin combines every left row, the right field is left as its zero value (nil for pointers) if there isn't a
+	// matching right row.
+	LeftJoin
+)
+
+// Join combines the rows of left and right (slices of structs or pointers to structs, or pointers to the slices) that
+// have the same value in their leftKey and rightKey columns, and appends the combined rows to the slice out points to.
+// The struct type of out must have a field of the left struct type and one of the right struct type, either can be a
+// pointer (eg Customer *Customer). A combined row is appended for each pair of matching rows, in the order of left,
+// with copies of the rows. Keys are compared after they're marshalled so an int column can be joined to a string
+// column. Use Unmarshal (or a Decoder) to read csv data into left and right.
+func Join(out, left, right interface{}, leftKey, rightKey string, jt JoinType) error {
+	outVal := reflect.ValueOf(out)
+	if outVal.Kind() != reflect.Ptr || outVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("out must be a pointer to a slice, got %T: %w", out, ErrNotSlicePtr)
+	}
+	outSlice := outVal.Elem()
+	elemType := outSlice.Type().Elem()
+	rowType := elemType
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
+	}
+	if rowType.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported slice element type %s", elemType)
+	}
+
+	leftRows, leftType, err := joinRows(left, leftKey)
+	if err != nil {
+		return errors.Wrap(err, "left")
+	}
+	rightRows, rightType, err := joinRows(right, rightKey)
+	if err != nil {
+		return errors.Wrap(err, "right")
+	}
+	leftField := joinField(rowType, leftType, -1)
+	if leftField < 0 {
+		return errors.Errorf("%s doesn't have a %s field", rowType, leftType)
+	}
+	rightField := joinField(rowType, rightType, leftField)
+	if rightField < 0 {
+		return errors.Errorf("%s doesn't have a %s field", rowType, rightType)
+	}
+
+	index := make(map[string][]int, len(rightRows))
+	for j, row := range rightRows {
+		index[row.key] = append(index[row.key], j)
+	}
+	for _, lr := range leftRows {
+		matches := index[lr.key]
+		if len(matches) == 0 {
+			if jt == InnerJoin {
+				continue
+			}
+			matches = []int{-1}
+		}
+
+		for _, j := range matches {
+			row := reflect.New(rowType)
+			setJoinField(row.Elem().Field(leftField), lr.v)
+			if j >= 0 {
+				setJoinField(row.Elem().Field(rightField), rightRows[j].v)
+			}
+			if elemType.Kind() != reflect.Ptr {
+				row = row.Elem()
+			}
+			outSlice.Set(reflect.Append(outSlice, row))
+		}
+	}
+	return nil
+}
+
+// joinRow is a row of one of the slices passed to Join.
+type joinRow struct {
+	key string
+	v   reflect.Value // the struct
+}
+
+// joinRows returns the rows of the slice (or pointer to a slice) v with the values of their key columns, and the struct
+// type of the rows.
+func joinRows(v interface{}, key string) ([]joinRow, reflect.Type, error) {
+	slice := reflect.Indirect(reflect.ValueOf(v))
+	if slice.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("expected slice, got %T: %w", v, ErrNotSlicePtr)
+	}
+	st := slice.Type().Elem()
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("unsupported slice element type %s", slice.Type().Elem())
+	}
+
+	fis, err := defaultEncRegister.encodeFields(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	var keyField *fieldInfo
+	for i := range fis {
+		if fis[i].ColName == key {
+			keyField = &fis[i]
+		}
+	}
+	if keyField == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrMissingColumn, key)
+	}
+
+	enc := &Encoder{}
+	rows := make([]joinRow, slice.Len())
+	for i := range rows {
+		sv := slice.Index(i)
+		if sv.Kind() == reflect.Ptr {
+			if sv.IsNil() {
+				return nil, nil, newEncodeError(st, "", i, errors.New("nil element"))
+			}
+			sv = sv.Elem()
+		}
+		if !sv.CanAddr() {
+			// fieldValue needs an addressable struct
+			p := reflect.New(st).Elem()
+			p.Set(sv)
+			sv = p
+		}
+		k, err := enc.fieldString(sv, *keyField)
+		if err != nil {
+			return nil, nil, newEncodeError(st, keyField.Name, i, err)
+		}
+		rows[i] = joinRow{key: k, v: sv}
+	}
+	return rows, st, nil
+}
+
+// joinField returns the index of the first field of the struct rowType (other than the field at skip) of type st or
+// *st, -1 is returned if there isn't one.
+func joinField(rowType, st reflect.Type, skip int) int {
+	for i := 0; i < rowType.NumField(); i++ {
+		ft := rowType.Field(i).Type
+		if i != skip && (ft == st || ft == reflect.PtrTo(st)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setJoinField sets the field f (a struct or pointer to a struct) to a copy of the struct sv.
+func setJoinField(f, sv reflect.Value) {
+	if f.Kind() != reflect.Ptr {
+		f.Set(sv)
+		return
+	}
+	p := reflect.New(sv.Type())
+	p.Elem().Set(sv)
+	f.Set(p)
+}
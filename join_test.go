@@ -0,0 +1,91 @@
+package csvplus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestJoin(t *testing.T) {
+	type Order struct {
+		ID         int    `csvplus:"id"`
+		CustomerID string `csvplus:"customer_id"`
+	}
+	type Customer struct {
+		ID   int    `csvplus:"id"`
+		Name string `csvplus:"name"`
+	}
+	type OrderCustomer struct {
+		Order    Order
+		Customer *Customer
+	}
+
+	var orders []Order
+	if err := csvplus.Unmarshal([]byte("id,customer_id\n1,10\n2,20\n3,10\n"), &orders); err != nil {
+		t.Fatal(err)
+	}
+	customers := []*Customer{{10, "Rob"}, {30, "Ken"}}
+
+	t.Run("inner", func(t *testing.T) {
+		var joined []OrderCustomer
+		if err := csvplus.Join(&joined, orders, &customers, "customer_id", "id", csvplus.InnerJoin); err != nil {
+			t.Fatal(err)
+		}
+		if len(joined) != 2 {
+			t.Fatalf("expected 2 rows, got: %v", joined)
+		}
+		for i, id := range []int{1, 3} {
+			if joined[i].Order.ID != id || joined[i].Customer == nil || joined[i].Customer.Name != "Rob" {
+				t.Errorf("unexpected row %d: %+v", i, joined[i])
+			}
+		}
+		// rows are copied
+		joined[0].Customer.Name = "changed"
+		if customers[0].Name != "Rob" {
+			t.Error("expected the right row to be copied")
+		}
+	})
+
+	t.Run("left", func(t *testing.T) {
+		var joined []*OrderCustomer
+		if err := csvplus.Join(&joined, &orders, customers, "customer_id", "id", csvplus.LeftJoin); err != nil {
+			t.Fatal(err)
+		}
+		if len(joined) != 3 {
+			t.Fatalf("expected 3 rows, got: %v", joined)
+		}
+		if joined[1].Order.ID != 2 || joined[1].Customer != nil {
+			t.Errorf("expected order 2 without a customer, got: %+v", joined[1])
+		}
+	})
+
+	t.Run("multiple matches", func(t *testing.T) {
+		type CustomerOrder struct {
+			Customer Customer
+			Order    Order
+		}
+		var joined []CustomerOrder
+		if err := csvplus.Join(&joined, customers, orders, "id", "customer_id", csvplus.InnerJoin); err != nil {
+			t.Fatal(err)
+		}
+		if len(joined) != 2 || joined[0].Order.ID != 1 || joined[1].Order.ID != 3 {
+			t.Errorf("expected orders 1 and 3, got: %+v", joined)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var joined []OrderCustomer
+		err := csvplus.Join(&joined, orders, customers, "customer", "id", csvplus.InnerJoin)
+		if !errors.Is(err, csvplus.ErrMissingColumn) {
+			t.Errorf("expected ErrMissingColumn, got: %v", err)
+		}
+		var wrong []Order
+		if err := csvplus.Join(&wrong, orders, customers, "customer_id", "id", csvplus.InnerJoin); err == nil {
+			t.Error("expected error for a struct without a Customer field")
+		}
+		if err := csvplus.Join(joined, orders, customers, "customer_id", "id", csvplus.InnerJoin); !errors.Is(err, csvplus.ErrNotSlicePtr) {
+			t.Errorf("expected ErrNotSlicePtr, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package csvplus
+
+import "fmt"
+
+// readLimitSlack is added to the number of bytes a record can use with MaxFieldBytes to allow for delimiters, quotes
+// and data read ahead by the csv reader.
+const readLimitSlack = 64 << 10
+
+// limitError is returned when input exceeds a limit set by MaxFieldBytes or MaxRows, kind is the sentinel error.
+type limitError struct {
+	msg  string
+	kind error
+}
+
+func (le limitError) Error() string {
+	return le.msg
+}
+
+// Unwrap returns the sentinel error.
+func (le limitError) Unwrap() error {
+	return le.kind
+}
+
+// errReadLimit is returned by the input reader when a record is too large to have values within the MaxFieldBytes
+// limit, it stops huge values being held in memory before they can be checked.
+var errReadLimit = limitError{msg: "record too large", kind: ErrFieldTooLarge}
+
+// MaxFieldBytes sets the maximum length in bytes of values (including the header row), Decode (and ReadRecord) stop
+// with an error wrapping ErrFieldTooLarge if a value is longer. Input isn't read further than about n bytes per column
+// (plus 64KB) past the start of a record so a huge value (eg an unterminated quote) is detected before it's held in
+// memory, use it and MaxRows to protect services that accept untrusted csv data. It has no effect with
+// NewRecordDecoder, other than values being checked once they've been read.
+func (dec *Decoder) MaxFieldBytes(n int) *Decoder {
+	dec.maxFieldBytes = n
+	return dec
+}
+
+// MaxRows sets the maximum number of data rows read (including rows skipped by Filter), Decode stops with an error
+// wrapping ErrTooManyRows when reading the row after the maximum.
+func (dec *Decoder) MaxRows(n int) *Decoder {
+	dec.maxRows = n
+	return dec
+}
+
+// setReadLimit limits how far the input can be read past the start of the next record, see MaxFieldBytes.
+func (dec *Decoder) setReadLimit() {
+	if dec.maxFieldBytes <= 0 {
+		return
+	}
+	cols := dec.width
+	if dec.csvReader.FieldsPerRecord > cols {
+		cols = dec.csvReader.FieldsPerRecord
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	dec.src.limit = dec.inputOffset() + int64(dec.maxFieldBytes)*int64(cols) + readLimitSlack
+}
+
+// checkFieldSizes returns an error wrapping ErrFieldTooLarge if a value of record is longer than the MaxFieldBytes
+// limit.
+func (dec *Decoder) checkFieldSizes(record []string) error {
+	if dec.maxFieldBytes <= 0 {
+		return nil
+	}
+	for i, v := range record {
+		if len(v) > dec.maxFieldBytes {
+			return limitError{
+				msg:  fmt.Sprintf("value in column %d is %d bytes, the limit is %d", i, len(v), dec.maxFieldBytes),
+				kind: ErrFieldTooLarge,
+			}
+		}
+	}
+	return nil
+}
+
+// countRow counts a data row and returns an error wrapping ErrTooManyRows if there are more than the MaxRows limit.
+func (dec *Decoder) countRow() error {
+	if dec.maxRows <= 0 {
+		return nil
+	}
+	dec.rowsRead++
+	if dec.rowsRead > dec.maxRows {
+		return limitError{msg: fmt.Sprintf("more than %d rows", dec.maxRows), kind: ErrTooManyRows}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package csvplus
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strconv"
+)
+
+// SetLogger sets a logger debug events are written to, eg how the header row was mapped to the fields of the struct
+// (including fields without a column and columns without a field), short rows that were padded or skipped and rows
+// skipped after an error was passed to the OnError function. Use it to find out why a field is unexpectedly empty,
+// events are only created if the logger is enabled for slog.LevelDebug.
+func (dec *Decoder) SetLogger(l *slog.Logger) *Decoder {
+	dec.logger = l
+	return dec
+}
+
+// debugEnabled reports whether debug events should be logged.
+func (dec *Decoder) debugEnabled() bool {
+	return dec.logger != nil && dec.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// debug logs a debug event, args are key value pairs as for slog.Logger.Debug.
+func (dec *Decoder) debug(msg string, args ...interface{}) {
+	if dec.debugEnabled() {
+		dec.logger.Debug(msg, args...)
+	}
+}
+
+// logMapping logs how the columns of header were mapped to the fields of rowType.
+func (dec *Decoder) logMapping(rowType reflect.Type, header []string) {
+	if !dec.debugEnabled() {
+		return
+	}
+	colName := func(i int) string {
+		if dec.withoutHeader || i >= len(header) {
+			return strconv.Itoa(i)
+		}
+		return header[i]
+	}
+
+	mapped := make(map[string]bool, len(dec.fis))
+	for _, fi := range dec.fis {
+		mapped[fi.Name] = true
+		if fi.SkipField || fi.ColName == "" {
+			continue
+		}
+		for k := 0; k < fi.columns(); k++ {
+			dec.logger.Debug("csvplus: column mapped", "column", colName(fi.ColIndex+k), "index", fi.ColIndex+k,
+				"field", fi.Name)
+		}
+	}
+
+	if rowType.Kind() == reflect.Struct {
+		for i := 0; i < rowType.NumField(); i++ {
+			sf := rowType.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			tag, _ := columnTag(sf, dec.tags)
+			switch {
+			case tag == "-":
+				dec.logger.Debug("csvplus: field skipped by tag", "field", sf.Name)
+			case !mapped[sf.Name]:
+				if tag == "" {
+					tag = sf.Name
+				}
+				dec.logger.Debug("csvplus: field has no column, it will have its zero value", "field", sf.Name,
+					"column", tag)
+			}
+		}
+	}
+
+	needed := neededColumns(dec.fis)
+	for i := range header {
+		if i >= len(needed) || !needed[i] {
+			dec.logger.Debug("csvplus: column not mapped to a field", "column", colName(i), "index", i)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDecoder_SetLogger(t *testing.T) {
+	type Item struct {
+		Name    string `csvplus:"name"`
+		Qty     int    `csvplus:"quantity"`
+		Ignored string `csvplus:"-"`
+	}
+	data := "name,qty,notes\na,1,x\nb\nc,z,y\n"
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader(data)).
+		OnShortRow(csvplus.ShortRowPad).
+		SetLogger(logger).
+		Decode(&items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected 3 items, got %d", len(items))
+	}
+
+	log := buf.String()
+	for _, want := range []string{
+		`msg="csvplus: column mapped" column=name index=0 field=Name`,
+		`msg="csvplus: field has no column, it will have its zero value" field=Qty column=quantity`,
+		`msg="csvplus: field skipped by tag" field=Ignored`,
+		`msg="csvplus: column not mapped to a field" column=qty index=1`,
+		`msg="csvplus: column not mapped to a field" column=notes index=2`,
+		`msg="csvplus: short row padded with empty values" line=3 fields=1 columns=3`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected log to contain %s, got:\n%s", want, log)
+		}
+	}
+
+	// the Qty column is mapped here so the last row can't be unmarshalled
+	type Item2 struct {
+		Name string `csvplus:"name"`
+		Qty  int    `csvplus:"qty"`
+	}
+	buf.Reset()
+	var items2 []Item2
+	err = csvplus.NewDecoder(strings.NewReader("name,qty\na,1\nb,x\n")).
+		OnError(func(row int, record []string, err error) error { return nil }).
+		SetLogger(logger).
+		Decode(&items2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `msg="csvplus: row skipped after error" row=2 code=TYPE_CONVERSION`; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected log to contain %s, got:\n%s", want, buf.String())
+	}
+
+	// nothing is logged above debug level
+	buf.Reset()
+	logger = slog.New(slog.NewTextHandler(&buf, nil))
+	err = csvplus.NewDecoder(strings.NewReader(data)).OnShortRow(csvplus.ShortRowPad).SetLogger(logger).Decode(&items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("expected nothing to be logged, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,129 @@
+package csvplus
+
+import (
+	"io"
+	"time"
+)
+
+// Stats are counters of the work done by a Decoder or Encoder, eg for exporting metrics from long running ingestion
+// services. Use Stats to get the totals (eg for Prometheus counter funcs) or OnStats to be passed the counts of each
+// call (eg to observe durations in a histogram).
+type Stats struct {
+	// Rows is the number of data rows decoded (not including rows dropped by Filter, FilterDecoded or DropDuplicates),
+	// or rows written by an Encoder.
+	Rows int64
+	// Rejected is the number of rows that failed, eg rows skipped after their error was passed to the OnError function
+	// or with values that failed validation. Encode stops at the first row that fails so Rejected is at most one per
+	// call for an Encoder.
+	Rejected int64
+	// Bytes is the number of bytes of csv data read (after a BOM is removed and the input is transcoded to UTF-8), or
+	// written to the underlying io.Writer. It's zero if SetCSVReader (or SetCSVWriter) is used.
+	Bytes int64
+	// Duration is the time spent in calls to the methods that decode (or encode) rows.
+	Duration time.Duration
+}
+
+// sub returns the counts of s since before.
+func (s Stats) sub(before Stats) Stats {
+	return Stats{
+		Rows:     s.Rows - before.Rows,
+		Rejected: s.Rejected - before.Rejected,
+		Bytes:    s.Bytes - before.Bytes,
+		Duration: s.Duration - before.Duration,
+	}
+}
+
+// statsCall is a call being measured, see startCall.
+type statsCall struct {
+	start  time.Time
+	before Stats
+	active bool // false for calls made by a call that's already being measured
+}
+
+// Stats returns the totals of the rows decoded by Decode, DecodeBatch, DecodeOneStruct (and methods that use it),
+// ReadRecord and Item.
+func (dec *Decoder) Stats() Stats {
+	s := dec.stats
+	if !dec.customReader {
+		s.Bytes = dec.src.total
+	}
+	return s
+}
+
+// OnStats sets a function that's passed the counts of each call to Decode, DecodeBatch, DecodeOneStruct, ReadRecord,
+// Scan and Item when it returns (including calls that return an error).
+func (dec *Decoder) OnStats(fn func(s Stats)) *Decoder {
+	dec.onStats = fn
+	return dec
+}
+
+// startCall starts measuring a call, it must be followed by a call to endCall.
+func (dec *Decoder) startCall() statsCall {
+	if dec.inCall {
+		return statsCall{}
+	}
+	dec.inCall = true
+	return statsCall{start: time.Now(), before: dec.Stats(), active: true}
+}
+
+// endCall adds the time taken by the call c to the stats and passes its counts to the OnStats function.
+func (dec *Decoder) endCall(c statsCall) {
+	if !c.active {
+		return
+	}
+	dec.inCall = false
+	dec.stats.Duration += time.Since(c.start)
+	if dec.onStats != nil {
+		dec.onStats(dec.Stats().sub(c.before))
+	}
+}
+
+// Stats returns the totals of the rows written by Encode, EncodeChan, EncodeSQLRows and WriteRecord.
+func (enc *Encoder) Stats() Stats {
+	s := enc.stats
+	if enc.out != nil {
+		s.Bytes = enc.counter.n
+	}
+	return s
+}
+
+// OnStats sets a function that's passed the counts of each call to Encode, EncodeChan, EncodeSQLRows and WriteRecord
+// when it returns (including calls that return an error). Rows written by WriteRecord are only counted as Bytes once
+// they're flushed.
+func (enc *Encoder) OnStats(fn func(s Stats)) *Encoder {
+	enc.onStats = fn
+	return enc
+}
+
+// startCall starts measuring a call, it must be followed by a call to endCall.
+func (enc *Encoder) startCall() statsCall {
+	if enc.inCall {
+		return statsCall{}
+	}
+	enc.inCall = true
+	return statsCall{start: time.Now(), before: enc.Stats(), active: true}
+}
+
+// endCall adds the time taken by the call c to the stats and passes its counts to the OnStats function.
+func (enc *Encoder) endCall(c statsCall) {
+	if !c.active {
+		return
+	}
+	enc.inCall = false
+	enc.stats.Duration += time.Since(c.start)
+	if enc.onStats != nil {
+		enc.onStats(enc.Stats().sub(c.before))
+	}
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
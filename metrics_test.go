@@ -0,0 +1,100 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDecoder_Stats(t *testing.T) {
+	type Item struct {
+		Name string `csvplus:"name"`
+		Qty  int    `csvplus:"qty"`
+	}
+	data := "name,qty\na,1\nb,x\nc,3\nd,4\n"
+
+	var calls []csvplus.Stats
+	dec := csvplus.NewDecoder(strings.NewReader(data)).
+		OnError(func(row int, record []string, err error) error { return nil }).
+		OnStats(func(s csvplus.Stats) { calls = append(calls, s) })
+	var items []Item
+	if err := dec.DecodeBatch(2, &items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := dec.DecodeBatch(2, &items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := dec.DecodeBatch(2, &items); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	s := dec.Stats()
+	if s.Rows != 3 || s.Rejected != 1 || s.Bytes != int64(len(data)) || s.Duration <= 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected OnStats to be called 3 times, got %d", len(calls))
+	}
+	if calls[0].Rows != 2 || calls[0].Rejected != 1 || calls[1].Rows != 1 || calls[1].Rejected != 0 || calls[2].Rows != 0 {
+		t.Errorf("unexpected counts of calls %+v", calls)
+	}
+	var total csvplus.Stats
+	for _, c := range calls {
+		total.Rows += c.Rows
+		total.Bytes += c.Bytes
+		total.Duration += c.Duration
+	}
+	if total.Rows != s.Rows || total.Bytes != s.Bytes || total.Duration != s.Duration {
+		t.Errorf("expected the counts of calls to add up to %+v, got %+v", s, total)
+	}
+
+	// ReadRecord doesn't count the header row
+	dec = csvplus.NewDecoder(strings.NewReader(data))
+	for {
+		if _, err := dec.ReadRecord(); err != nil {
+			break
+		}
+	}
+	if s := dec.Stats(); s.Rows != 4 || s.Bytes != int64(len(data)) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestEncoder_Stats(t *testing.T) {
+	type Item struct {
+		Name string `csvplus:"name"`
+		Qty  int    `csvplus:"qty"`
+	}
+	items := []Item{{"a", 1}, {"b", 2}}
+
+	var buf bytes.Buffer
+	var calls []csvplus.Stats
+	enc := csvplus.NewEncoder(&buf).OnStats(func(s csvplus.Stats) { calls = append(calls, s) })
+	if err := enc.Encode(&items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := enc.Encode(&items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := enc.Stats()
+	if s.Rows != 4 || s.Rejected != 0 || s.Bytes != int64(buf.Len()) || s.Duration <= 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if len(calls) != 2 || calls[0].Rows != 2 || calls[0].Bytes != int64(len("name,qty\na,1\nb,2\n")) {
+		t.Errorf("unexpected counts of calls %+v", calls)
+	}
+
+	// a nil element can't be marshalled
+	ptrs := []*Item{&items[0], nil}
+	enc = csvplus.NewEncoder(io.Discard)
+	if err := enc.Encode(&ptrs); err == nil {
+		t.Fatal("expected an error")
+	}
+	if s := enc.Stats(); s.Rows != 1 || s.Rejected != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,62 @@
+package csvplus
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DecodeAll decodes the csv data read from each of readers (eg daily partitions of the same export) into the slice
+// pointed to by v, in order. Every header row must have the same columns as the first one but they can be in a
+// different order, columns are mapped to fields by name for each reader. An error wrapping ErrHeaderMismatch is
+// returned for the first reader whose header row has missing or extra columns, rows already decoded from previous
+// readers are kept. Readers without any data are skipped.
+func DecodeAll(v interface{}, readers ...io.Reader) error {
+	var first []string
+	for i, r := range readers {
+		dec := NewDecoder(r)
+		if _, err := dec.ReadRecord(); err == io.EOF {
+			continue
+		} else if err != nil {
+			return errors.Wrapf(err, "reader %d: unable to read header row", i)
+		}
+
+		if first == nil {
+			first = dec.Header()
+		} else if missing, extra := compareHeaders(first, dec.Header()); len(missing) > 0 || len(extra) > 0 {
+			return fmt.Errorf("reader %d: %w: missing [%s], extra [%s]", i, ErrHeaderMismatch,
+				strings.Join(missing, ", "), strings.Join(extra, ", "))
+		}
+
+		if err := dec.Decode(v); err != nil {
+			return errors.Wrapf(err, "reader %d", i)
+		}
+	}
+	return nil
+}
+
+// compareHeaders returns the columns of want that aren't in header, and the columns of header that aren't in want.
+func compareHeaders(want, header []string) (missing, extra []string) {
+	counts := make(map[string]int, len(want))
+	for _, colName := range want {
+		counts[colName]++
+	}
+	for _, colName := range header {
+		counts[colName]--
+	}
+	for _, colName := range want {
+		if counts[colName] > 0 {
+			missing = append(missing, colName)
+			counts[colName]--
+		}
+	}
+	for _, colName := range header {
+		if counts[colName] < 0 {
+			extra = append(extra, colName)
+			counts[colName]++
+		}
+	}
+	return missing, extra
+}
@@ -0,0 +1,67 @@
+package csvplus_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDecodeAll(t *testing.T) {
+	type Item struct {
+		ID   int    `csvplus:"id"`
+		Name string `csvplus:"name"`
+	}
+
+	t.Run("reordered columns", func(t *testing.T) {
+		var items []Item
+		err := csvplus.DecodeAll(&items,
+			strings.NewReader("id,name\n1,Rob\n"),
+			strings.NewReader(""),
+			strings.NewReader("name,id\nRuss,2\nKen,3\n"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []Item{{1, "Rob"}, {2, "Russ"}, {3, "Ken"}}
+		if len(items) != len(expected) {
+			t.Fatalf("expected %v, got: %v", expected, items)
+		}
+		for i := range expected {
+			if items[i] != expected[i] {
+				t.Errorf("expected %v, got: %v", expected[i], items[i])
+			}
+		}
+	})
+
+	t.Run("header mismatch", func(t *testing.T) {
+		var items []Item
+		err := csvplus.DecodeAll(&items,
+			strings.NewReader("id,name\n1,Rob\n"),
+			strings.NewReader("id,full_name\n2,Russ\n"),
+		)
+		if !errors.Is(err, csvplus.ErrHeaderMismatch) {
+			t.Fatalf("expected ErrHeaderMismatch, got: %v", err)
+		}
+		expected := "reader 1: header row doesn't match: missing [name], extra [full_name]"
+		if err.Error() != expected {
+			t.Errorf("expected %q, got: %q", expected, err)
+		}
+		if len(items) != 1 {
+			t.Errorf("expected the rows of the first reader, got: %v", items)
+		}
+	})
+
+	t.Run("row error", func(t *testing.T) {
+		var items []Item
+		err := csvplus.DecodeAll(&items, strings.NewReader("id,name\n1,Rob\n"), strings.NewReader("id,name\nx,Russ\n"))
+		var ue csvplus.UnmarshalError
+		if !errors.As(err, &ue) || ue.Row != 1 {
+			t.Errorf("expected UnmarshalError for row 1, got: %v", err)
+		}
+		if !strings.HasPrefix(err.Error(), "reader 1: ") {
+			t.Errorf("expected the reader index in the error, got: %v", err)
+		}
+	})
+}
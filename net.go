@@ -0,0 +1,68 @@
+package csvplus
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ipType     = reflect.TypeOf(net.IP(nil))
+	addrType   = reflect.TypeOf(netip.Addr{})
+	prefixType = reflect.TypeOf(netip.Prefix{})
+)
+
+// isNetType reports whether t is net.IP, netip.Addr or netip.Prefix.
+func isNetType(t reflect.Type) bool {
+	return t == ipType || t == addrType || t == prefixType
+}
+
+// parseNet sets the net.IP, netip.Addr or netip.Prefix value f to the address (or prefix, eg 10.0.0.0/8) s.
+func parseNet(f reflect.Value, s string) error {
+	switch f.Type() {
+	case ipType:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return errors.Errorf("invalid IP address %q", s)
+		}
+		f.Set(reflect.ValueOf(ip))
+	case addrType:
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(addr))
+	case prefixType:
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(prefix))
+	}
+	return nil
+}
+
+// formatNet formats the net.IP, netip.Addr or netip.Prefix value fv, nil and zero values are formatted as an empty
+// value.
+func formatNet(fv reflect.Value) string {
+	switch v := fv.Interface().(type) {
+	case net.IP:
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	case netip.Addr:
+		if !v.IsValid() {
+			return ""
+		}
+		return v.String()
+	case netip.Prefix:
+		if !v.IsValid() {
+			return ""
+		}
+		return v.String()
+	}
+	return ""
+}
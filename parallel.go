@@ -0,0 +1,207 @@
+package csvplus
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// chunk is a byte range of csv data that starts at a record boundary.
+type chunk struct {
+	start, end int64
+	line       int // line the chunk starts on
+}
+
+// UnmarshalReaderAt is the same as UnmarshalReader but parses the size bytes of r in parallel, the data is split into
+// (up to) workers chunks at record boundaries and each chunk is decoded by its own goroutine, rows are stored in v in
+// the same order as the input. workers <= 0 means runtime.GOMAXPROCS(0). Finding the record boundaries takes a quick
+// pass over the data, quoted values can contain newlines but quotes must be used as they are in valid csv data (eg
+// data that needs AllowLazyQuotes isn't supported).
+func UnmarshalReaderAt(r io.ReaderAt, size int64, v interface{}, workers int) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("non pointer %T: %w", v, ErrNotSlicePtr)
+	}
+	if rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("expected slice to store data in, got %s: %w", rv.Elem().Type(), ErrNotSlicePtr)
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	headerEnd, headerLines, chunks, err := splitChunks(r, size, workers)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, headerEnd)
+	if _, err := r.ReadAt(header, 0); err != nil && err != io.EOF {
+		return errors.Wrap(err, "unable to read header row")
+	}
+
+	results := make([]reflect.Value, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func(i int, c chunk) {
+			defer wg.Done()
+			// each chunk is prefixed with the header row so its columns are mapped the same way
+			in := io.MultiReader(bytes.NewReader(header), io.NewSectionReader(r, c.start, c.end-c.start))
+			results[i] = reflect.New(rv.Elem().Type())
+			errs[i] = NewDecoder(in).Decode(results[i].Interface())
+		}(i, c)
+	}
+	wg.Wait()
+
+	var rows int
+	for i, c := range chunks {
+		if errs[i] != nil {
+			ue, ok := errs[i].(UnmarshalError)
+			if !ok {
+				return errs[i]
+			}
+			// positions are relative to the chunk (with the header row prefixed)
+			ue.Row += rows
+			ue.Line += c.line - headerLines - 1
+			ue.Offset += c.start - headerEnd
+			return ue
+		}
+		rows += results[i].Elem().Len()
+		rv.Elem().Set(reflect.AppendSlice(rv.Elem(), results[i].Elem()))
+	}
+	return nil
+}
+
+// splitChunks finds the end of the header row (and the number of lines it spans), and splits the rest of the size
+// bytes of r into (up to) n chunks of roughly equal size that start at record boundaries. Newlines in quoted values
+// aren't record boundaries, the data is only read as far as the start of the last chunk.
+func splitChunks(r io.ReaderAt, size int64, n int) (int64, int, []chunk, error) {
+	br := bufio.NewReaderSize(io.NewSectionReader(r, 0, size), 64*1024)
+	var offset, headerEnd, target int64
+	line := 1
+	inQuotes := false
+	var chunks []chunk
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, nil, errors.Wrap(err, "unable to read data")
+		}
+		offset++
+
+		switch {
+		case b == '"':
+			// escaped quotes ("") toggle twice
+			inQuotes = !inQuotes
+		case b == '\n':
+			line++
+			if inQuotes || offset < target {
+				continue
+			}
+			if chunks == nil {
+				headerEnd = offset
+			} else {
+				chunks[len(chunks)-1].end = offset
+			}
+			chunks = append(chunks, chunk{start: offset, end: size, line: line})
+			if len(chunks) == n {
+				return headerEnd, chunks[0].line - 1, chunks, nil
+			}
+			target = headerEnd + int64(len(chunks))*(size-headerEnd)/int64(n)
+		}
+	}
+
+	if chunks == nil {
+		// just a header row
+		return size, line, nil, nil
+	}
+	return headerEnd, chunks[0].line - 1, chunks, nil
+}
+
+// parallelBatchRows is the number of rows each goroutine marshals at a time when encoding in parallel.
+const parallelBatchRows = 1024
+
+// Parallel sets the number of goroutines Encode uses to marshal rows (eg formatting times and numbers), which
+// dominates the cost of encoding large slices, rows are still written in order. workers <= 0 means
+// runtime.GOMAXPROCS(0), 1 (the default) marshals rows on the calling goroutine. Rows are marshalled in batches so
+// memory use is bounded, BeforeMarshalCSV methods and converters can be called concurrently (for different rows) so
+// must be safe for concurrent use.
+func (enc *Encoder) Parallel(workers int) *Encoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	enc.workers = workers
+	return enc
+}
+
+// marshalled is a batch of rows marshalled by a goroutine, err is from the row after the last one.
+type marshalled struct {
+	records [][]string
+	strs    [][]bool
+	err     error
+}
+
+// encodeParallel marshals the rows of the slice s using enc.workers goroutines and writes them in order.
+func (enc *Encoder) encodeParallel(st reflect.Type, fis []fieldInfo, s reflect.Value) error {
+	n := s.Len()
+	batches := make([]marshalled, enc.workers)
+	for start := 0; start < n; start += enc.workers * parallelBatchRows {
+		var wg sync.WaitGroup
+		for w := range batches {
+			lo := start + w*parallelBatchRows
+			hi := lo + parallelBatchRows
+			if hi > n {
+				hi = n
+			}
+			batches[w] = marshalled{}
+			if lo >= n {
+				continue
+			}
+
+			wg.Add(1)
+			go func(b *marshalled, lo, hi int) {
+				defer wg.Done()
+				// each goroutine needs its own record buffers
+				m := *enc
+				m.record, m.strs, m.buf, m.spans = nil, nil, nil, nil
+				for i := lo; i < hi; i++ {
+					record, strs, err := m.marshalRow(st, fis, i, s.Index(i))
+					if err != nil {
+						b.err = err
+						return
+					}
+					b.records = append(b.records, append([]string(nil), record...))
+					b.strs = append(b.strs, append([]bool(nil), strs...))
+				}
+			}(&batches[w], lo, hi)
+		}
+		wg.Wait()
+
+		i := start
+		for _, b := range batches {
+			for k, record := range b.records {
+				if err := enc.write(record, b.strs[k]); err != nil {
+					return newEncodeError(st, "", i, errors.Wrap(err, "unable to write row"))
+				}
+				if err := enc.endRow(); err != nil {
+					return newEncodeError(st, "", i, errors.Wrap(err, "unable to write row"))
+				}
+				i++
+			}
+			if b.err != nil {
+				enc.stats.Rejected++
+				return b.err
+			}
+		}
+	}
+	return nil
+}
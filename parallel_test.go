@@ -0,0 +1,143 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestUnmarshalReaderAt(t *testing.T) {
+	type Item struct {
+		ID   int    `csvplus:"id"`
+		Note string `csvplus:"note"`
+	}
+	var sb strings.Builder
+	sb.WriteString("id,note\n")
+	for i := 0; i < 1000; i++ {
+		if i%3 == 0 {
+			fmt.Fprintf(&sb, "%d,\"multi\nline \"\"%d\"\"\"\n", i, i)
+		} else {
+			fmt.Fprintf(&sb, "%d,note %d\n", i, i)
+		}
+	}
+	data := sb.String()
+
+	var expected []Item
+	if err := csvplus.Unmarshal([]byte(data), &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 1, 3, 8, 2000} {
+		var items []Item
+		err := csvplus.UnmarshalReaderAt(strings.NewReader(data), int64(len(data)), &items, workers)
+		if err != nil {
+			t.Fatalf("workers %d: %v", workers, err)
+		}
+		if !reflect.DeepEqual(items, expected) {
+			t.Errorf("workers %d: decoded rows don't match sequential decoding", workers)
+		}
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		bad := strings.Replace(data, "\n900,", "\nx,", 1)
+		var expected csvplus.UnmarshalError
+		if err := csvplus.Unmarshal([]byte(bad), &[]Item{}); !errors.As(err, &expected) {
+			t.Fatalf("expected UnmarshalError, got: %v", err)
+		}
+
+		var items []Item
+		err := csvplus.UnmarshalReaderAt(strings.NewReader(bad), int64(len(bad)), &items, 4)
+		var ue csvplus.UnmarshalError
+		if !errors.As(err, &ue) {
+			t.Fatalf("expected UnmarshalError, got: %v", err)
+		}
+		if ue.Row != expected.Row || ue.Line != expected.Line || ue.Offset != expected.Offset {
+			t.Errorf("expected row %d line %d offset %d, got: row %d line %d offset %d",
+				expected.Row, expected.Line, expected.Offset, ue.Row, ue.Line, ue.Offset)
+		}
+	})
+
+	t.Run("header only", func(t *testing.T) {
+		var items []Item
+		err := csvplus.UnmarshalReaderAt(strings.NewReader("id,note\n"), 8, &items, 4)
+		if err != nil || len(items) != 0 {
+			t.Errorf("expected no rows, got: %v, %v", items, err)
+		}
+	})
+}
+
+// parallelItem fails to marshal when Fail is set.
+type parallelItem struct {
+	ID   int       `csvplus:"id"`
+	At   time.Time `csvplus:"at"`
+	Note *string   `csvplus:"note"`
+	Fail bool      `csvplus:"-"`
+}
+
+func (pi *parallelItem) BeforeMarshalCSV() error {
+	if pi.Fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestEncoder_Parallel(t *testing.T) {
+	items := make([]*parallelItem, 5000)
+	for i := range items {
+		items[i] = &parallelItem{ID: i, At: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC)}
+		if i%7 == 0 {
+			note := fmt.Sprintf("note, %d", i)
+			items[i].Note = &note
+		}
+	}
+
+	var expected bytes.Buffer
+	if err := csvplus.NewEncoder(&expected).Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 2, 3} {
+		var got bytes.Buffer
+		if err := csvplus.NewEncoder(&got).Parallel(workers).Encode(&items); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != expected.String() {
+			t.Errorf("workers %d: expected the same output as encoding sequentially", workers)
+		}
+	}
+
+	t.Run("error", func(t *testing.T) {
+		items[3000].Fail = true
+		var got bytes.Buffer
+		err := csvplus.NewEncoder(&got).Parallel(4).Encode(&items)
+		var ee csvplus.EncodeError
+		if !errors.As(err, &ee) || ee.Row != 3000 {
+			t.Fatalf("expected an error for row 3000, got: %v", err)
+		}
+		if lines := strings.Count(got.String(), "\n"); lines > 3001 {
+			t.Errorf("expected at most 3001 lines before the error, got %d", lines)
+		}
+	})
+}
+
+func BenchmarkEncoder_Parallel(b *testing.B) {
+	items := make([]parallelItem, 100000)
+	for i := range items {
+		items[i] = parallelItem{ID: i, At: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC)}
+	}
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				if err := csvplus.NewEncoder(io.Discard).Parallel(workers).Encode(&items); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
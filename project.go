@@ -0,0 +1,246 @@
+package csvplus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// projectReader reads records the same way as a csv.Reader but only copies the values of the columns that are needed,
+// the values of other columns are left empty. Lines without quotes are split directly, records with quotes (which can
+// span lines) are parsed by a csv.Reader that's fed one line at a time.
+type projectReader struct {
+	br     *bufio.Reader
+	opts   *csv.Reader // options (eg Comma) are read from it, and FieldsPerRecord is set on it as it would be by Read
+	raw    []byte      // holds lines longer than br's buffer
+	record []string
+	line   int   // number of lines read
+	offset int64 // number of bytes read
+
+	quoted *csv.Reader // parses records with quotes, created when first needed
+	feed   lineFeeder
+}
+
+func newProjectReader(r io.Reader, opts *csv.Reader) *projectReader {
+	pr := &projectReader{
+		br:   bufio.NewReader(r),
+		opts: opts,
+	}
+	pr.feed.pr = pr
+	return pr
+}
+
+// lineFeeder is the input of projectReader.quoted, it returns at most one line per Read so the csv.Reader never
+// buffers input past the end of the record it's parsing.
+type lineFeeder struct {
+	pr      *projectReader
+	pending []byte
+	lines   int // number of lines fed
+}
+
+func (lf *lineFeeder) Read(p []byte) (int, error) {
+	if len(lf.pending) == 0 {
+		line, err := lf.pr.readLine()
+		if len(line) == 0 {
+			return 0, err
+		}
+		lf.pending = line
+		lf.lines++
+	}
+	n := copy(p, lf.pending)
+	lf.pending = lf.pending[n:]
+	return n, nil
+}
+
+// readLine reads the next line, \r\n is normalised to \n. io.EOF is only returned if there's no more input.
+func (pr *projectReader) readLine() ([]byte, error) {
+	line, err := pr.br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		pr.raw = append(pr.raw[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = pr.br.ReadSlice('\n')
+			pr.raw = append(pr.raw, line...)
+		}
+		line = pr.raw
+	}
+	readSize := len(line)
+	if readSize > 0 && err == io.EOF {
+		err = nil
+		// the same as csv.Reader, a trailing \r before EOF is dropped
+		if line[readSize-1] == '\r' {
+			line = line[:readSize-1]
+		}
+	}
+	pr.line++
+	pr.offset += int64(readSize)
+	if n := len(line); n >= 2 && line[n-2] == '\r' && line[n-1] == '\n' {
+		line[n-2] = '\n'
+		line = line[:n-1]
+	}
+	return line, err
+}
+
+// Read reads the next record and returns it with the line it starts on, need[i] reports whether the value of column i
+// is needed (columns past the end of need aren't), a nil need means all columns are. The record is reused by the next
+// call.
+func (pr *projectReader) Read(need []bool) ([]string, int, error) {
+	opts := pr.opts
+	if err := checkDelims(opts); err != nil {
+		return nil, 0, err
+	}
+
+	var line []byte
+	var err error
+	for {
+		line, err = pr.readLine()
+		if err != nil {
+			return nil, 0, err
+		}
+		if opts.Comment != 0 && nextRune(line) == opts.Comment {
+			continue
+		}
+		if len(line) == lengthNL(line) {
+			continue
+		}
+		break
+	}
+	recLine := pr.line
+
+	var record []string
+	if bytes.IndexByte(line, '"') >= 0 {
+		if record, err = pr.readQuoted(line, recLine); err != nil {
+			return record, recLine, err
+		}
+	} else {
+		if opts.TrimLeadingSpace && unicode.IsSpace(opts.Comma) {
+			// leading space trimming can remove delimiters so the fields can't be counted
+			need = nil
+		}
+		record = pr.split(line[:len(line)-lengthNL(line)], need)
+	}
+
+	if opts.FieldsPerRecord > 0 {
+		if len(record) != opts.FieldsPerRecord {
+			return record, recLine, &csv.ParseError{StartLine: recLine, Line: recLine, Column: 1, Err: csv.ErrFieldCount}
+		}
+	} else if opts.FieldsPerRecord == 0 {
+		opts.FieldsPerRecord = len(record)
+	}
+	return record, recLine, nil
+}
+
+// split splits a line without quotes into its fields, only the values of needed columns are copied.
+func (pr *projectReader) split(line []byte, need []bool) []string {
+	comma := pr.opts.Comma
+	commaLen := utf8.RuneLen(comma)
+
+	// find the end of the last needed column so only the start of the line is copied
+	end := len(line)
+	if need != nil {
+		last := 0
+		for i := len(need) - 1; i > 0; i-- {
+			if need[i] {
+				last = i
+				break
+			}
+		}
+		var start int
+		for i := 0; i <= last; i++ {
+			j := bytes.IndexRune(line[start:], comma)
+			if j < 0 {
+				end = len(line)
+				break
+			}
+			end = start + j
+			start = end + commaLen
+		}
+	}
+
+	s := string(line[:end])
+	record := pr.record[:0]
+	for i := 0; ; i++ {
+		if pr.opts.TrimLeadingSpace {
+			s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		}
+		field := s
+		j := strings.IndexRune(s, comma)
+		if j >= 0 {
+			field = s[:j]
+		}
+		if need != nil && (i >= len(need) || !need[i]) {
+			field = ""
+		}
+		record = append(record, field)
+		if j < 0 {
+			break
+		}
+		s = s[j+commaLen:]
+	}
+
+	if end < len(line) {
+		// the rest of the line starts with a delimiter, the rest of the fields are only counted
+		for n := bytes.Count(line[end:], []byte(string(comma))); n > 0; n-- {
+			record = append(record, "")
+		}
+	}
+	pr.record = record
+	return record
+}
+
+// readQuoted parses the record that starts with line using a csv.Reader, errors have the line numbers they would have
+// if the record was read by a csv.Reader reading all the input.
+func (pr *projectReader) readQuoted(line []byte, recLine int) ([]string, error) {
+	if pr.quoted == nil {
+		pr.quoted = csv.NewReader(&pr.feed)
+		pr.quoted.Comma = pr.opts.Comma
+		pr.quoted.Comment = pr.opts.Comment
+		pr.quoted.LazyQuotes = pr.opts.LazyQuotes
+		pr.quoted.TrimLeadingSpace = pr.opts.TrimLeadingSpace
+		pr.quoted.FieldsPerRecord = -1
+		pr.quoted.ReuseRecord = true
+	}
+	pr.feed.pending = line
+	pr.feed.lines++
+	lineDiff := recLine - pr.feed.lines
+
+	record, err := pr.quoted.Read()
+	if pe, ok := err.(*csv.ParseError); ok {
+		pe.StartLine += lineDiff
+		pe.Line += lineDiff
+	}
+	return record, err
+}
+
+// checkDelims returns the error a csv.Reader returns for the delimiters of opts, if any.
+func checkDelims(opts *csv.Reader) error {
+	if opts.Comma == opts.Comment || !validDelim(opts.Comma) || (opts.Comment != 0 && !validDelim(opts.Comment)) {
+		// use the csv.Reader error
+		r := csv.NewReader(strings.NewReader(""))
+		r.Comma, r.Comment = opts.Comma, opts.Comment
+		_, err := r.Read()
+		return err
+	}
+	return nil
+}
+
+func validDelim(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+// nextRune returns the next rune in b or utf8.RuneError.
+func nextRune(b []byte) rune {
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+// lengthNL reports the number of bytes for the trailing \n.
+func lengthNL(b []byte) int {
+	if len(b) > 0 && b[len(b)-1] == '\n' {
+		return 1
+	}
+	return 0
+}
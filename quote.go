@@ -0,0 +1,138 @@
+package csvplus
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// QuoteMode controls which values are quoted by an Encoder.
+type QuoteMode int
+
+// Quote modes that can be passed to Encoder.Quote.
+const (
+	// QuoteMinimal only quotes values that need it (eg they contain the delimiter or a quote), the csv.Writer default.
+	QuoteMinimal QuoteMode = iota
+	// QuoteAll quotes every value, including the header row.
+	QuoteAll
+	// QuoteStrings quotes the values of string fields (and the header row), other values are only quoted if they need
+	// it.
+	QuoteStrings
+	// QuoteNever doesn't quote any values, Encode returns an error wrapping ErrNeedsQuotes if a value needs quoting.
+	QuoteNever
+)
+
+// Quote sets which values are quoted, defaults to QuoteMinimal. Quote modes other than QuoteMinimal can't be used with
+// SetCSVWriter.
+func (enc *Encoder) Quote(mode QuoteMode) *Encoder {
+	enc.quote = mode
+	return enc
+}
+
+// write writes record using the quote mode, strs reports which values are from string fields (nil means all of them).
+func (enc *Encoder) write(record []string, strs []bool) error {
+	if enc.quote == QuoteMinimal {
+		return enc.csvWriter.Write(record)
+	}
+	if enc.out == nil {
+		return errors.New("quote mode can't be used with SetCSVWriter")
+	}
+	if enc.bw == nil {
+		enc.bw = bufio.NewWriter(enc.out)
+	}
+	if enc.quote == QuoteNever {
+		// check every value first so part of the record isn't written
+		for _, field := range record {
+			if enc.needsQuotes(field) {
+				return errors.Wrapf(ErrNeedsQuotes, "value %q", field)
+			}
+		}
+	}
+
+	for i, field := range record {
+		if i > 0 {
+			enc.bw.WriteRune(enc.csvWriter.Comma) // nolint: errcheck
+		}
+
+		quote := enc.needsQuotes(field)
+		switch enc.quote {
+		case QuoteNever:
+			quote = false
+		case QuoteAll:
+			quote = true
+		case QuoteStrings:
+			quote = quote || strs == nil || strs[i]
+		}
+
+		if !quote {
+			enc.bw.WriteString(field) // nolint: errcheck
+			continue
+		}
+		enc.writeQuoted(field)
+	}
+
+	if enc.csvWriter.UseCRLF {
+		enc.bw.WriteString("\r\n") // nolint: errcheck
+	} else {
+		enc.bw.WriteByte('\n') // nolint: errcheck
+	}
+	// errors are sticky so are returned by Flush
+	return nil
+}
+
+// writeQuoted writes field surrounded by quotes, line endings are written the same way as by csv.Writer.
+func (enc *Encoder) writeQuoted(field string) {
+	enc.bw.WriteByte('"') // nolint: errcheck
+	for _, r := range field {
+		switch r {
+		case '"':
+			enc.bw.WriteString(`""`) // nolint: errcheck
+		case '\r':
+			if !enc.csvWriter.UseCRLF {
+				enc.bw.WriteByte('\r') // nolint: errcheck
+			}
+		case '\n':
+			if enc.csvWriter.UseCRLF {
+				enc.bw.WriteString("\r\n") // nolint: errcheck
+			} else {
+				enc.bw.WriteByte('\n') // nolint: errcheck
+			}
+		default:
+			enc.bw.WriteRune(r) // nolint: errcheck
+		}
+	}
+	enc.bw.WriteByte('"') // nolint: errcheck
+}
+
+// needsQuotes reports whether field must be quoted, the same rules as csv.Writer are used.
+func (enc *Encoder) needsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsRune(field, enc.csvWriter.Comma) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+// isStringType reports whether values of type t (or its elements for arrays) are strings.
+func isStringType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+// flush writes any buffered records to the underlying io.Writer.
+func (enc *Encoder) flush() error {
+	if enc.bw != nil {
+		return enc.bw.Flush()
+	}
+	enc.csvWriter.Flush()
+	return enc.csvWriter.Error()
+}
@@ -0,0 +1,65 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// UnmarshalRecord unmarshals a single record (eg from a csv.Reader loop) into the struct pointed to by v, values are
+// mapped to fields by position the same way as UnmarshalWithoutHeader. The same struct tags, and the Unmarshaler,
+// BeforeUnmarshaler and AfterUnmarshaler interfaces, are used as when unmarshalling whole csv data. Fields without a
+// value in the record are set to their zero values.
+func UnmarshalRecord(record []string, v interface{}) error {
+	return unmarshalRecord(nil, record, v)
+}
+
+// UnmarshalRecordWithHeader is the same as UnmarshalRecord but values are mapped to fields by column name using header,
+// so it can be used in csv.Reader loops where the order of the columns varies. The mapping for each header is cached so
+// the header isn't matched against the struct fields for every record.
+func UnmarshalRecordWithHeader(header, record []string, v interface{}) error {
+	if header == nil {
+		header = []string{}
+	}
+	return unmarshalRecord(header, record, v)
+}
+
+// unmarshalRecord unmarshals record into the struct pointed to by v, values are mapped to fields using header or by
+// position if header is nil.
+func unmarshalRecord(header, record []string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("non pointer or nil %T: %w", v, ErrNotStructPtr)
+	}
+	rowType := rv.Elem().Type()
+
+	dec := &Decoder{withoutHeader: header == nil}
+	if header == nil {
+		header = record
+	}
+	if err := dec.mapColumns(rowType, header); err != nil {
+		return err
+	}
+	rv.Elem().Set(reflect.Zero(rowType))
+	return dec.unmarshalRow(0, record, rv.Elem())
+}
+
+// MarshalRecord marshals the struct (or pointer to struct) v into a record, the values are the same (and in the same
+// order) as the row Marshal writes for v. The same struct tags, and the Marshaler and BeforeMarshaler interfaces, are
+// used as when marshalling a slice.
+func MarshalRecord(v interface{}) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	st := rv.Type()
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct or pointer to struct, got %T", v)
+	}
+
+	fis, err := defaultEncRegister.encodeFields(st)
+	if err != nil {
+		return nil, err
+	}
+	record, _, err := (&Encoder{}).marshalRow(st, fis, 0, rv)
+	return record, err
+}
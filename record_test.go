@@ -0,0 +1,173 @@
+package csvplus_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestUnmarshalMarshalRecord(t *testing.T) {
+	type Item struct {
+		Name    string
+		Age     *int
+		Joined  time.Time `csvplusFormat:"2006-01-02"`
+		Ignored string    `csvplus:"-"`
+		Score   float64
+	}
+
+	r := csv.NewReader(strings.NewReader("Rob,42,1999-11-01,7.5\nRuss,,2001-03-14,x\n"))
+	var items []Item
+	var errs []error
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		item := Item{Ignored: "set"}
+		if err := csvplus.UnmarshalRecord(record, &item); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	age := 42
+	expected := []Item{{Name: "Rob", Age: &age, Joined: time.Date(1999, 11, 1, 0, 0, 0, 0, time.UTC), Score: 7.5}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, items)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], csvplus.ErrTypeConversion) {
+		t.Errorf("expected a single ErrTypeConversion, got: %v", errs)
+	}
+
+	record, err := csvplus.MarshalRecord(&items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"Rob", "42", "1999-11-01", "7.5"}; !reflect.DeepEqual(record, expected) {
+		t.Errorf("expected %q, got: %q", expected, record)
+	}
+
+	if err := csvplus.UnmarshalRecord([]string{"a"}, items[0]); !errors.Is(err, csvplus.ErrNotStructPtr) {
+		t.Errorf("expected ErrNotStructPtr, got: %v", err)
+	}
+	if _, err := csvplus.MarshalRecord("a"); err == nil {
+		t.Error("expected error marshalling a string")
+	}
+}
+
+func TestUnmarshalRecordWithHeader(t *testing.T) {
+	type Item struct {
+		Name  string `csvplus:"name"`
+		Age   int    `csvplus:"age"`
+		Email string `csvplus:"email|e-mail"`
+	}
+
+	tests := []struct {
+		header, record []string
+	}{
+		{[]string{"name", "age", "email"}, []string{"Rob", "42", "rob@example.com"}},
+		{[]string{"e-mail", "name", "age", "extra"}, []string{"rob@example.com", "Rob", "42", "x"}},
+	}
+	expected := Item{Name: "Rob", Age: 42, Email: "rob@example.com"}
+	for _, tt := range tests {
+		var item Item
+		if err := csvplus.UnmarshalRecordWithHeader(tt.header, tt.record, &item); err != nil {
+			t.Fatal(err)
+		}
+		if item != expected {
+			t.Errorf("header %v: expected %+v, got: %+v", tt.header, expected, item)
+		}
+	}
+
+	var item Item
+	err := csvplus.UnmarshalRecordWithHeader([]string{"name", "age"}, []string{"Rob", "old"}, &item)
+	var ue csvplus.UnmarshalError
+	if !errors.As(err, &ue) || ue.Column != "age" {
+		t.Errorf("expected UnmarshalError for column age, got: %v", err)
+	}
+}
+
+func TestMarshalRecord_Concurrent(t *testing.T) {
+	// the field info of a type is cached the first time it's used, which can happen from many goroutines at once
+	type Item struct {
+		Name string
+		Age  int
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record, err := csvplus.MarshalRecord(Item{"Rob", i})
+			if err != nil || record[1] != strconv.Itoa(i) {
+				t.Errorf("unexpected record %q, %v", record, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkUnmarshalRecordWithHeader(b *testing.B) {
+	type Item struct {
+		Name  string  `csvplus:"name"`
+		Age   int     `csvplus:"age"`
+		Score float64 `csvplus:"score"`
+	}
+	header := []string{"name", "age", "score"}
+	record := []string{"Rob", "42", "7.5"}
+
+	var item Item
+	for n := 0; n < b.N; n++ {
+		if err := csvplus.UnmarshalRecordWithHeader(header, record, &item); err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchItems = item
+}
+
+func TestUnmarshalMarshalRecord_Types(t *testing.T) {
+	type Item struct {
+		I8      int8
+		I16     int16
+		I32     int32
+		I64     int64
+		U       uint
+		U8      uint8
+		U16     uint16
+		U32     uint32
+		U64     uint64
+		Skipped string `csvplus:"-"`
+		When    *time.Time
+		Never   *time.Time
+	}
+	record := []string{"-128", "-32768", "-2147483648", "-9223372036854775808", "1", "255", "65535", "4294967295",
+		"18446744073709551615", "2020-01-02T15:04:05Z", ""}
+
+	var item Item
+	if err := csvplus.UnmarshalRecord(record, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.I64 != -9223372036854775808 || item.U64 != 18446744073709551615 || item.When == nil || item.Never != nil {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	marshalled, err := csvplus.MarshalRecord(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(marshalled, record) {
+		t.Errorf("expected %q, got: %q", record, marshalled)
+	}
+}
@@ -0,0 +1,274 @@
+package csvplus
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ColumnType is the type of the values of a column passed to a RecordWriter.
+type ColumnType int
+
+const (
+	// TypeString values are strings, it's used for strings and for fields that are written as their csv values (eg
+	// types that implement Marshaler, net.IP or fields with a char option).
+	TypeString ColumnType = iota
+	// TypeBool values are bools.
+	TypeBool
+	// TypeInt64 values are int64s, it's used for all signed integer fields.
+	TypeInt64
+	// TypeUint64 values are uint64s, it's used for all unsigned integer fields.
+	TypeUint64
+	// TypeFloat64 values are float64s, it's used for float32 and float64 fields.
+	TypeFloat64
+	// TypeTimestamp values are time.Times.
+	TypeTimestamp
+	// TypeDate values are Dates.
+	TypeDate
+	// TypeBytes values are []bytes.
+	TypeBytes
+)
+
+var columnTypeNames = [...]string{"string", "bool", "int64", "uint64", "float64", "timestamp", "date", "bytes"}
+
+func (ct ColumnType) String() string {
+	if ct < 0 || int(ct) >= len(columnTypeNames) {
+		return fmt.Sprintf("ColumnType(%d)", int(ct))
+	}
+	return columnTypeNames[ct]
+}
+
+// RecordColumn describes a column passed to a RecordWriter.
+type RecordColumn struct {
+	Name     string // the column name from the csvplus tag (or the field name)
+	Field    string // the name of the struct field
+	Type     ColumnType
+	Nullable bool // the field is a pointer, nil values are passed as nil
+}
+
+// A RecordWriter receives decoded rows as typed values, it's the integration point for writing typed columnar formats
+// (eg Apache Arrow or Parquet) without redoing the mapping of csv columns to types. Begin is called once with the
+// columns before any rows are written, each row passed to Write has a value per column of the Go type given by its
+// ColumnType (or nil for nil pointers). The slice passed to Write is reused so must be copied if it's retained.
+type RecordWriter interface {
+	Begin(cols []RecordColumn) error
+	Write(row []interface{}) error
+}
+
+// RecordColumns returns the columns of the struct (or pointer to struct) type t that are passed to a RecordWriter, in
+// the same order as they're marshalled. Array fields without a delim option aren't supported.
+func RecordColumns(t reflect.Type) ([]RecordColumn, error) {
+	_, cols, err := recordFields(t)
+	return cols, err
+}
+
+// WriteRecords writes the rows of the slice v (of structs or pointers to structs, or a pointer to the slice) to w.
+func WriteRecords(w RecordWriter, v interface{}) error {
+	slice := reflect.Indirect(reflect.ValueOf(v))
+	if slice.Kind() != reflect.Slice {
+		return fmt.Errorf("expected slice, got %T: %w", v, ErrNotSlicePtr)
+	}
+	st := slice.Type().Elem()
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	fis, cols, err := recordFields(st)
+	if err != nil {
+		return err
+	}
+	if err := w.Begin(cols); err != nil {
+		return errors.Wrap(err, "unable to begin writing records")
+	}
+
+	row := make([]interface{}, len(fis))
+	for i := 0; i < slice.Len(); i++ {
+		sv := slice.Index(i)
+		if sv.Kind() == reflect.Ptr {
+			if sv.IsNil() {
+				return newEncodeError(st, "", i, errors.New("nil element"))
+			}
+			sv = sv.Elem()
+		}
+		if !sv.CanAddr() {
+			// fieldValue needs an addressable struct
+			p := reflect.New(st).Elem()
+			p.Set(sv)
+			sv = p
+		}
+		if err := writeRecord(w, sv, fis, row, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRecords decodes each row into the struct pointed to by v and writes it to w, so large inputs can be converted
+// without holding every row in memory. The number of rows written is returned.
+func (dec *Decoder) WriteRecords(w RecordWriter, v interface{}) (int, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0, fmt.Errorf("non pointer or nil %T: %w", v, ErrNotStructPtr)
+	}
+	st := rv.Type().Elem()
+	if st.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("unsupported type %s: %w", st, ErrNotStructPtr)
+	}
+	fis, cols, err := recordFields(st)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Begin(cols); err != nil {
+		return 0, errors.Wrap(err, "unable to begin writing records")
+	}
+
+	row := make([]interface{}, len(fis))
+	var n int
+	for {
+		err := dec.DecodeOneStruct(v)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := writeRecord(w, rv.Elem(), fis, row, n); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// writeRecord writes the fields fis of the struct sv to w, row is the slice the values are stored in.
+func writeRecord(w RecordWriter, sv reflect.Value, fis []fieldInfo, row []interface{}, i int) error {
+	for j, fi := range fis {
+		fv, err := fieldValue(sv, fi)
+		if err != nil {
+			return newEncodeError(sv.Type(), fi.Name, i, err)
+		}
+		if row[j], err = recordValue(fv, fi); err != nil {
+			return newEncodeError(sv.Type(), fi.Name, i, err)
+		}
+	}
+	if err := w.Write(row); err != nil {
+		return newEncodeError(sv.Type(), "", i, errors.Wrap(err, "unable to write record"))
+	}
+	return nil
+}
+
+// recordFields returns the fields of the struct st that are passed to a RecordWriter and their columns.
+func recordFields(st reflect.Type) ([]fieldInfo, []RecordColumn, error) {
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("unsupported type %s: %w", st, ErrNotStructPtr)
+	}
+	fis, err := defaultEncRegister.encodeFields(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cols := make([]RecordColumn, len(fis))
+	for i, fi := range fis {
+		if fi.ArrayLen > 0 && fi.ArrayDelim == "" {
+			return nil, nil, fmt.Errorf("array field %s without a delim option can't be written as a record", fi.Name)
+		}
+		t := stringType
+		if fi.Method == "" {
+			t = st.Field(fi.FieldIndex).Type
+		}
+		cols[i] = RecordColumn{
+			Name:     fi.ColName,
+			Field:    fi.Name,
+			Type:     recordType(t, fi),
+			Nullable: t.Kind() == reflect.Ptr,
+		}
+	}
+	return fis, cols, nil
+}
+
+// recordType returns the ColumnType of values of the field type t.
+func recordType(t reflect.Type, fi fieldInfo) ColumnType {
+	if fi.Char || fi.ArrayLen > 0 || t.Implements(csvMarshalerType) || reflect.PtrTo(t).Implements(csvMarshalerType) {
+		return TypeString
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if t.Implements(csvMarshalerType) || reflect.PtrTo(t).Implements(csvMarshalerType) {
+			return TypeString
+		}
+	}
+
+	switch {
+	case t.String() == timeType:
+		return TypeTimestamp
+	case t == dateType:
+		return TypeDate
+	case isNetType(t):
+		return TypeString
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return TypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return TypeInt64
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeUint64
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat64
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TypeBytes
+		}
+	}
+	return TypeString
+}
+
+// recordValue returns the value of the field fv passed to a RecordWriter.
+func recordValue(fv reflect.Value, fi fieldInfo) (interface{}, error) {
+	ct := recordType(fv.Type(), fi)
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil, nil
+		}
+		if ct != TypeString {
+			fv = fv.Elem()
+		}
+	}
+
+	switch ct {
+	case TypeBool:
+		return fv.Bool(), nil
+	case TypeInt64:
+		return fv.Int(), nil
+	case TypeUint64:
+		return fv.Uint(), nil
+	case TypeFloat64:
+		return fv.Float(), nil
+	case TypeTimestamp:
+		return fv.Interface().(time.Time), nil
+	case TypeDate:
+		return fv.Interface().(Date), nil
+	case TypeBytes:
+		return fv.Bytes(), nil
+	}
+
+	if fv.Kind() == reflect.String && !fv.Type().Implements(csvMarshalerType) {
+		return fv.String(), nil
+	}
+	if fi.ArrayLen == 0 {
+		return marshalField(fv, fi)
+	}
+	vals := make([]string, fi.ArrayLen)
+	for k := range vals {
+		var err error
+		if vals[k], err = marshalField(fv.Index(k), fi); err != nil {
+			return nil, err
+		}
+	}
+	return strings.Join(vals, fi.ArrayDelim), nil
+}
@@ -0,0 +1,106 @@
+package csvplus_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+// recordCollector is a RecordWriter that stores the records written to it.
+type recordCollector struct {
+	cols []csvplus.RecordColumn
+	rows [][]interface{}
+}
+
+func (rc *recordCollector) Begin(cols []csvplus.RecordColumn) error {
+	rc.cols = cols
+	return nil
+}
+
+func (rc *recordCollector) Write(row []interface{}) error {
+	rc.rows = append(rc.rows, append([]interface{}(nil), row...))
+	return nil
+}
+
+type recordItem struct {
+	Name   string       `csvplus:"name"`
+	Count  *int         `csvplus:"count"`
+	Size   uint16       `csvplus:"size"`
+	Price  float32      `csvplus:"price"`
+	Active bool         `csvplus:"active"`
+	At     time.Time    `csvplus:"at"`
+	Day    csvplus.Date `csvplus:"day"`
+	Tags   [2]string    `csvplus:"tags,delim=;"`
+	Kind   YesNoBool    `csvplus:"kind"`
+}
+
+func TestWriteRecords(t *testing.T) {
+	data := "name,count,size,price,active,at,day,tags,kind\n" +
+		"a,1,2,1.5,true,2024-01-02T03:04:05Z,2024-01-02,x;y,yes\n" +
+		"b,,3,2,false,2024-01-03T00:00:00Z,2024-01-03,p;q,no\n"
+
+	expectedCols := []csvplus.RecordColumn{
+		{Name: "name", Field: "Name", Type: csvplus.TypeString},
+		{Name: "count", Field: "Count", Type: csvplus.TypeInt64, Nullable: true},
+		{Name: "size", Field: "Size", Type: csvplus.TypeUint64},
+		{Name: "price", Field: "Price", Type: csvplus.TypeFloat64},
+		{Name: "active", Field: "Active", Type: csvplus.TypeBool},
+		{Name: "at", Field: "At", Type: csvplus.TypeTimestamp},
+		{Name: "day", Field: "Day", Type: csvplus.TypeDate},
+		{Name: "tags", Field: "Tags", Type: csvplus.TypeString},
+		{Name: "kind", Field: "Kind", Type: csvplus.TypeString},
+	}
+	expectedRows := [][]interface{}{
+		{"a", int64(1), uint64(2), 1.5, true, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			csvplus.Date{Year: 2024, Month: 1, Day: 2}, "x;y", "yes"},
+		{"b", nil, uint64(3), 2.0, false, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			csvplus.Date{Year: 2024, Month: 1, Day: 3}, "p;q", "no"},
+	}
+
+	check := func(t *testing.T, rc *recordCollector) {
+		t.Helper()
+		if !reflect.DeepEqual(rc.cols, expectedCols) {
+			t.Errorf("expected columns %+v, got %+v", expectedCols, rc.cols)
+		}
+		if !reflect.DeepEqual(rc.rows, expectedRows) {
+			t.Errorf("expected rows %v, got %v", expectedRows, rc.rows)
+		}
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		var items []recordItem
+		if err := csvplus.Unmarshal([]byte(data), &items); err != nil {
+			t.Fatal(err)
+		}
+		rc := &recordCollector{}
+		if err := csvplus.WriteRecords(rc, items); err != nil {
+			t.Fatal(err)
+		}
+		check(t, rc)
+	})
+
+	t.Run("decoder", func(t *testing.T) {
+		rc := &recordCollector{}
+		n, err := csvplus.NewDecoder(strings.NewReader(data)).WriteRecords(rc, &recordItem{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Errorf("expected 2 rows, got %d", n)
+		}
+		check(t, rc)
+	})
+
+	t.Run("columns", func(t *testing.T) {
+		cols, err := csvplus.RecordColumns(reflect.TypeOf(&recordItem{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cols, expectedCols) {
+			t.Errorf("expected columns %+v, got %+v", expectedCols, cols)
+		}
+	})
+}
@@ -1,11 +1,19 @@
 package csvplus
 
 import (
+	"container/list"
+	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/pkg/errors"
 )
 
 const timeType = "time.Time"
@@ -25,30 +33,225 @@ func newStructInfo() *structInfo {
 }
 
 // getTimeFormat gets a suitable time.Parse layout from a csvplusFormat struct tag, defaults to time.RFC3339 if no
-// format is found.
+// format is found. Layout constants of the time package can be used by name (eg time.Kitchen), the epoch formats unix,
+// unixmilli and unixnano (and auto) are returned as is. Date fields default to 2006-01-02.
 func getTimeFormat(sf reflect.StructField) (format string) {
+	if sf.Type == dateType || sf.Type == reflect.PtrTo(dateType) {
+		if format = sf.Tag.Get("csvplusFormat"); format == "" {
+			format = dateLayout
+		}
+		return namedLayout(format)
+	}
 	if sf.Type.String() == timeType || sf.Type.String() == timeTypePtr {
-		format = sf.Tag.Get("csvplusFormat")
-		switch format {
-		case "", "time.RFC3339":
+		if format = sf.Tag.Get("csvplusFormat"); format == "" {
 			format = time.RFC3339
-		case "time.RFC3339Nano":
-			format = time.RFC3339Nano
 		}
+		return namedLayout(format)
+	}
+	return format
+}
+
+// namedLayouts are the layout constants of the time package, by the name used in csvplusFormat tags.
+var namedLayouts = map[string]string{
+	"time.Layout":      time.Layout,
+	"time.ANSIC":       time.ANSIC,
+	"time.UnixDate":    time.UnixDate,
+	"time.RubyDate":    time.RubyDate,
+	"time.RFC822":      time.RFC822,
+	"time.RFC822Z":     time.RFC822Z,
+	"time.RFC850":      time.RFC850,
+	"time.RFC1123":     time.RFC1123,
+	"time.RFC1123Z":    time.RFC1123Z,
+	"time.RFC3339":     time.RFC3339,
+	"time.RFC3339Nano": time.RFC3339Nano,
+	"time.Kitchen":     time.Kitchen,
+	"time.Stamp":       time.Stamp,
+	"time.StampMilli":  time.StampMilli,
+	"time.StampMicro":  time.StampMicro,
+	"time.StampNano":   time.StampNano,
+	"time.DateTime":    time.DateTime,
+	"time.DateOnly":    time.DateOnly,
+	"time.TimeOnly":    time.TimeOnly,
+}
+
+// namedLayout returns the layout called format (eg time.ANSIC), or format if it isn't the name of a layout.
+func namedLayout(format string) string {
+	if layout, ok := namedLayouts[format]; ok {
+		return layout
 	}
 	return format
 }
 
-// Register maps columns in the csv data to struct fields.
-func getFieldInfo(st reflect.Type, withoutHeader bool, header []string) []fieldInfo {
+// getFloatFormat gets a fmt verb (eg %.2f) from a csvplusFormat struct tag for float fields, an empty string means the
+// shortest representation that round trips is used. Trailing zeros produced by the verb are kept unless the field also
+// has a csvplusTrimZeros:"true" tag.
+func getFloatFormat(sf reflect.StructField) (format string, trimZeros bool) {
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Float32 && t.Kind() != reflect.Float64 {
+		return "", false
+	}
+	trimZeros, _ = strconv.ParseBool(sf.Tag.Get("csvplusTrimZeros"))
+	return sf.Tag.Get("csvplusFormat"), trimZeros
+}
+
+// getBytesFormat gets the encoding (base64, base64url or hex) of []byte fields from a csvplusFormat struct tag, an empty
+// string means values are stored as is. false is returned for other fields.
+func getBytesFormat(sf reflect.StructField) (string, bool) {
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+		return "", false
+	}
+	return sf.Tag.Get("csvplusFormat"), true
+}
+
+// getIntBase gets the base used to parse/format integer fields from a csvplusBase struct tag (eg 16 for hex), defaults
+// to 10 if there's no tag. A tag that isn't a base from 2 to 36 is an error wrapping ErrInvalidTag.
+func getIntBase(sf reflect.StructField) (int, error) {
+	tag := sf.Tag.Get("csvplusBase")
+	if tag == "" {
+		return 10, nil
+	}
+	base, err := strconv.Atoi(tag)
+	if err != nil || base < 2 || base > 36 {
+		return 0, fmt.Errorf("invalid csvplusBase tag %q on field %s: %w", tag, sf.Name, ErrInvalidTag)
+	}
+	return base, nil
+}
+
+// checkCurrencyField checks that the field named by the currency option of fi (if any) is a string field of st, an
+// error wrapping ErrInvalidTag is returned if it isn't.
+func checkCurrencyField(st reflect.Type, fi fieldInfo) error {
+	if fi.CurrencyField == "" {
+		return nil
+	}
+	if sf, ok := st.FieldByName(fi.CurrencyField); !ok || sf.Type.Kind() != reflect.String {
+		return fmt.Errorf("currency field %s of field %s isn't a string field: %w", fi.CurrencyField, fi.Name, ErrInvalidTag)
+	}
+	return nil
+}
+
+// getDecimal gets the decimal separator of currency values from a csvplusDecimal struct tag ("." or ","), 0 is
+// returned if there's no tag (or it isn't valid) so the separator is detected from each value.
+func getDecimal(sf reflect.StructField) byte {
+	switch d := sf.Tag.Get("csvplusDecimal"); d {
+	case ".", ",":
+		return d[0]
+	}
+	return 0
+}
+
+// getConverters gets the names of the converters applied to a field from a csvplusConv struct tag (eg upper or
+// trim,upper), converters are applied in order.
+func getConverters(sf reflect.StructField) []string {
+	tag := sf.Tag.Get("csvplusConv")
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+// getNilValue gets the value written for nil pointer fields from a csvplusNilVal struct tag (eg NULL), nil is returned
+// if there's no tag.
+func getNilValue(sf reflect.StructField) *string {
+	if nilVal, ok := sf.Tag.Lookup("csvplusNilVal"); ok {
+		return &nilVal
+	}
+	return nil
+}
+
+// getArrayLen gets the length of array fields (eg [4]float64), 0 is returned for other fields and arrays that implement
+// Marshaler or Unmarshaler.
+func getArrayLen(sf reflect.StructField) int {
+	t := sf.Type
+	if t.Kind() != reflect.Array {
+		return 0
+	}
+	for _, it := range []reflect.Type{csvUnmarshalerType, csvMarshalerType} {
+		if t.Implements(it) || reflect.PtrTo(t).Implements(it) {
+			return 0
+		}
+	}
+	return t.Len()
+}
+
+// getColIndex gets the index of the column a field is bound to from a csvplusIndex struct tag, false is returned if
+// there's no tag or the tag isn't a valid index.
+func getColIndex(sf reflect.StructField) (int, bool) {
+	idx, err := strconv.Atoi(sf.Tag.Get("csvplusIndex"))
+	if err != nil || idx < 0 {
+		return 0, false
+	}
+	return idx, true
+}
+
+// getLocation loads the location from a csvplusTZ struct tag (eg America/New_York) for time.Time fields, nil is
+// returned if there's no tag.
+func getLocation(sf reflect.StructField) (*time.Location, error) {
+	tz := sf.Tag.Get("csvplusTZ")
+	if tz == "" || (sf.Type.String() != timeType && sf.Type.String() != timeTypePtr) {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid csvplusTZ tag on field %s", sf.Name)
+	}
+	return loc, nil
+}
+
+// matchOptions control how columns in the csv data are mapped to struct fields.
+type matchOptions struct {
+	withoutHeader bool
+	loose         bool // match column names case insensitively, ignoring spaces, underscores and hyphens
+	firstDup      bool // fields are mapped to the first of duplicate columns rather than the last
+	tags          tagSource
+}
+
+// looseColName normalises a column name for loose matching, eg "Created At", "created_at" and "CreatedAt" all become
+// "createdat".
+func looseColName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// getFieldInfo maps columns in the csv data to struct fields.
+func getFieldInfo(st reflect.Type, header []string, mo matchOptions) ([]fieldInfo, error) {
+	withoutHeader := mo.withoutHeader
+	normalise := func(s string) string { return s }
+	if mo.loose && !withoutHeader {
+		normalise = looseColName
+	}
+
 	headersMap := make(map[string]int)
 	for i, header := range header {
-		headersMap[header] = i
+		if _, dup := headersMap[normalise(header)]; dup && mo.firstDup {
+			continue
+		}
+		headersMap[normalise(header)] = i
+	}
+	for i, header := range header {
+		// the first of the consecutive columns of an array field (eg coords[0]) matches the field
+		if name := strings.TrimSuffix(normalise(header), "[0]"); name != normalise(header) {
+			if _, found := headersMap[name]; !found {
+				headersMap[name] = i
+			}
+		}
 	}
 	fieldCounts := make(map[string]int)
 
 	ColNameToFieldInfo := make(map[string]fieldInfo)
 	var skipCount int
+	var extraCols int // columns used by array fields (after their first one) when there's no header
 
 	// iterate struct tags to extract all names
 	var fi fieldInfo
@@ -60,14 +263,24 @@ func getFieldInfo(st reflect.Type, withoutHeader bool, header []string) []fieldI
 			FieldIndex: i,
 		}
 
-		tag := sf.Tag.Get("csvplus")
-
-		switch tag {
-		case "":
+		tag, opts := columnTag(sf, mo.tags)
+		colIndex, hasColIndex := getColIndex(sf)
+		fi.ArrayLen = getArrayLen(sf)
+		fi.setOptions(opts)
+
+		switch {
+		case hasColIndex && tag != "-":
+			// bound to a column by position rather than name
+			fi.ColIndex = colIndex
+			fi.ColName = strconv.Itoa(colIndex)
+			if !withoutHeader && colIndex < len(header) {
+				fi.ColName = header[colIndex]
+			}
+		case tag == "":
 			var found bool
 			var colIndex int
 
-			if colIndex, found = headersMap[fi.Name]; found {
+			if colIndex, found = headersMap[normalise(fi.Name)]; found {
 				if withoutHeader {
 					fi.ColName = strconv.Itoa(i)
 				} else {
@@ -80,33 +293,62 @@ func getFieldInfo(st reflect.Type, withoutHeader bool, header []string) []fieldI
 			// try again with first char lowercased
 			r, n := utf8.DecodeRuneInString(fi.Name)
 			lowerName := string(unicode.ToLower(r)) + fi.Name[n:]
-			if colIndex, found := headersMap[lowerName]; found {
+			if colIndex, found := headersMap[normalise(lowerName)]; found {
 				fi.ColName = lowerName
 				fi.ColIndex = colIndex
 				break
 			} else if withoutHeader {
 				fi.ColName = strconv.Itoa(i)
-				fi.ColIndex = i - skipCount
+				fi.ColIndex = i - skipCount + extraCols
+				extraCols += fi.columns() - 1
 				break
 			}
 
 			// this field isn't mapped to a header row
 			continue
 
-		case "-":
+		case tag == "-":
 			fi.SkipField = true // used only for marshalling, if at all, maybe remove later
 			fi.ColName = "-"
 			skipCount++
 		default:
-			fi.ColName = tag
-			if colIndex, found := headersMap[fi.ColName]; found {
-				fi.ColIndex = colIndex
-				break
+			// the tag can have aliases (eg email|e-mail), the first one found in the header is used
+			var found bool
+			for _, colName := range strings.Split(tag, "|") {
+				var colIndex int
+				if colIndex, found = headersMap[normalise(colName)]; found {
+					fi.ColName = colName
+					fi.ColIndex = colIndex
+					break
+				}
+			}
+			if !found {
+				continue
 			}
-			continue
 		}
 
 		fi.Format = getTimeFormat(sf)
+		if format, ok := getBytesFormat(sf); ok {
+			fi.Format = format
+		}
+		base, err := getIntBase(sf)
+		if err != nil {
+			return nil, err
+		}
+		fi.Base = base
+		fi.Decimal = getDecimal(sf)
+		if err := checkCurrencyField(st, fi); err != nil {
+			return nil, err
+		}
+		fi.Converters = getConverters(sf)
+		loc, err := getLocation(sf)
+		if err != nil {
+			return nil, err
+		}
+		fi.Location = loc
+		if fi.Rules, err = getRules(sf); err != nil {
+			return nil, err
+		}
 
 		fieldCounts[fi.ColName]++
 		ColNameToFieldInfo[fi.ColName] = fi
@@ -126,7 +368,26 @@ func getFieldInfo(st reflect.Type, withoutHeader bool, header []string) []fieldI
 		}
 	}
 
-	return fieldsToStore
+	return fieldsToStore, nil
+}
+
+// missingColumns returns the column names from the tags (see columnTag) on st that aren't mapped to a column in fis.
+func missingColumns(st reflect.Type, fis []fieldInfo, tags tagSource) []string {
+	mapped := make(map[string]bool, len(fis))
+	for _, fi := range fis {
+		mapped[fi.Name] = true
+	}
+
+	var missing []string
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		tag, _ := columnTag(sf, tags)
+		if tag == "" || tag == "-" || mapped[sf.Name] {
+			continue
+		}
+		missing = append(missing, tag)
+	}
+	return missing
 }
 
 // fieldInfo represents a field in a struct with tags parsed and stuct/csv record indices mapped.
@@ -135,18 +396,200 @@ type fieldInfo struct {
 	FieldIndex int
 	ColName    string // only populated for csv data with header rows
 	ColIndex   int
-	Format     string // only populated for time.Time fields
+	Format     string         // only populated for time.Time, Date, float and []byte fields
+	TrimZeros  bool           // only used for float fields with a Format
+	Base       int            // only used for integer fields
+	Location   *time.Location // only populated for time.Time fields with a csvplusTZ tag
+	Converters []string       // names of converters from a csvplusConv tag
+	NilValue   *string        // written for nil pointers instead of an empty value, only used when marshalling
 	SkipField  bool
+
+	Currency      bool   // parse currency formatted values (eg $1,234.50) into float fields
+	CurrencyField string // name of a sibling string field that stores the currency symbol
+	Decimal       byte   // decimal separator of currency values from a csvplusDecimal tag, 0 if it's detected
+	Percent       bool   // values are percentages (eg 45%)
+	PercentWhole  bool   // percentages are stored as whole numbers (45) rather than fractions (0.45)
+	Char          bool   // integer (eg byte or rune) values are single characters rather than numbers
+	ArrayLen      int    // length of array fields, 0 for other fields
+	ArrayDelim    string // array elements are in a single value separated by ArrayDelim rather than consecutive columns
+	Order         int    // position of the column when marshalling, -1 if there's no order option
+	StrictEmpty   bool   // empty values are an error for non pointer (and non string) fields
+	Method        string // name of the method that returns the value of a computed column, only used when marshalling
+	Rules         []rule // validation rules from csvplusValidate and csvplusPattern tags, only used when unmarshalling
+
+	Required bool         // only used with a Schema, empty values are an error
+	Default  string       // only used with a Schema, replaces empty values
+	Type     reflect.Type // only used with a Schema, the type of map values
+}
+
+// setOptions sets the fields that are controlled by options in the csvplus struct tag.
+func (fi *fieldInfo) setOptions(opts tagOptions) {
+	fi.CurrencyField, fi.Currency = opts.Get("currency")
+	var percent string
+	percent, fi.Percent = opts.Get("percent")
+	fi.PercentWhole = percent == "whole"
+	fi.ArrayDelim, _ = opts.Get("delim")
+	fi.Char = opts.Contains("char")
+	fi.StrictEmpty = opts.Contains("strictempty")
+	fi.Order = -1
+	if order, found := opts.Get("order"); found {
+		if n, err := strconv.Atoi(order); err == nil && n >= 0 {
+			fi.Order = n
+		}
+	}
+}
+
+// columns returns the number of columns the field is stored in, array fields without a delim option are stored in
+// consecutive columns.
+func (fi fieldInfo) columns() int {
+	if fi.ArrayLen > 0 && fi.ArrayDelim == "" {
+		return fi.ArrayLen
+	}
+	return 1
+}
+
+// tagOptions is the string following a comma in a csvplus struct tag (eg `csvplus:"price,currency"`).
+type tagOptions string
+
+// tagSource is the struct tags column names are read from, see columnTag.
+type tagSource struct {
+	name     string // tag used in place of the csvplus tag (eg csv)
+	json     bool   // use the name from the json tag of fields without a name tag or csvplus tag
+	computed bool   // write computed columns, only used by Encoders (see Encoder.ComputedColumns)
+}
+
+// columnTag returns the column name and options of sf from its tags.name struct tag (eg csv), the csvplus tag is used
+// if there's no name or sf doesn't have a tag called name. If tags.json is set fields without either tag use the name
+// from their json tag, json options (eg omitempty) are ignored.
+func columnTag(sf reflect.StructField, tags tagSource) (string, tagOptions) {
+	if tags.name != "" {
+		if tag, ok := sf.Tag.Lookup(tags.name); ok {
+			return parseTag(tag)
+		}
+	}
+	tag, ok := sf.Tag.Lookup("csvplus")
+	if !ok && tags.json {
+		name, _ := parseTag(sf.Tag.Get("json"))
+		return name, ""
+	}
+	return parseTag(tag)
+}
+
+// parseTag splits a csvplus struct tag into its column name and options.
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, ""
+}
+
+// Contains reports whether a comma-separated list of options contains a particular option.
+func (o tagOptions) Contains(name string) bool {
+	_, found := o.Get(name)
+	return found
+}
+
+// Get returns the value of an option (eg `currency=Symbol`), an option without a value returns an empty string.
+func (o tagOptions) Get(name string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == name {
+			return "", true
+		}
+		if strings.HasPrefix(s, name+"=") {
+			return s[len(name)+1:], true
+		}
+		s = next
+	}
+	return "", false
+}
+
+// decRegisterSize is the number of struct type and header row combinations decRegister caches the field info of.
+const decRegisterSize = 256
+
+// decRegister is a cache of the field info needed to unmarshal csv data, keyed by struct type and header row. Header
+// rows can come from user input so the least recently used entries are evicted when it's full.
+type decRegister struct {
+	mu     sync.Mutex
+	size   int
+	lru    *list.List // of *decRegisterEntry, most recently used first
+	fields map[decRegisterKey]*list.Element
+}
+
+type decRegisterKey struct {
+	st     reflect.Type
+	header string // header row joined with a null byte
+	mo     matchOptions
+}
+
+type decRegisterEntry struct {
+	key decRegisterKey
+	fis []fieldInfo
+}
+
+// newDecRegister returns an initialised decRegister that caches up to size entries.
+func newDecRegister(size int) *decRegister {
+	return &decRegister{
+		size:   size,
+		lru:    list.New(),
+		fields: make(map[decRegisterKey]*list.Element),
+	}
+}
+
+// defaultDecRegister is a decRegister singleton since there only needs to be one.
+var defaultDecRegister = newDecRegister(decRegisterSize)
+
+// GetFieldInfo returns the (possibly cached) result of getFieldInfo, the returned slice must not be modified.
+func (dr *decRegister) GetFieldInfo(st reflect.Type, header []string, mo matchOptions) ([]fieldInfo, error) {
+	if mo.withoutHeader {
+		// the first row is data so it can't affect the mapping
+		header = nil
+	}
+	key := decRegisterKey{st: st, header: strings.Join(header, "\x00"), mo: mo}
+
+	dr.mu.Lock()
+	if el, found := dr.fields[key]; found {
+		dr.lru.MoveToFront(el)
+		dr.mu.Unlock()
+		return el.Value.(*decRegisterEntry).fis, nil
+	}
+	dr.mu.Unlock()
+
+	fis, err := getFieldInfo(st, header, mo)
+	if err != nil {
+		return nil, err
+	}
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if el, found := dr.fields[key]; found {
+		// added by another goroutine
+		dr.lru.MoveToFront(el)
+		return fis, nil
+	}
+	dr.fields[key] = dr.lru.PushFront(&decRegisterEntry{key: key, fis: fis})
+	if dr.lru.Len() > dr.size {
+		oldest := dr.lru.Back()
+		dr.lru.Remove(oldest)
+		delete(dr.fields, oldest.Value.(*decRegisterEntry).key)
+	}
+	return fis, nil
 }
 
 // encRegister is a cache for data needed to marshal, since a
 type encRegister struct {
+	mu     sync.RWMutex
 	Fields map[reflect.Type]structInfo
+	tags   tagSource
 }
 
 // newEncRegister returns an initialised encRegister.
-func newEncRegister() encRegister {
-	return encRegister{
+func newEncRegister() *encRegister {
+	return &encRegister{
 		Fields: make(map[reflect.Type]structInfo),
 	}
 }
@@ -154,22 +597,46 @@ func newEncRegister() encRegister {
 // defaultEncRegister is a encRegister singleton since there only needs to be one.
 var defaultEncRegister = newEncRegister()
 
+var (
+	tagEncRegistersMu sync.Mutex
+	tagEncRegisters   = map[tagSource]*encRegister{{}: defaultEncRegister}
+)
+
+// tagEncRegister returns the encRegister for structs whose columns are named by the tags in tags, there's one per
+// tagSource.
+func tagEncRegister(tags tagSource) *encRegister {
+	tagEncRegistersMu.Lock()
+	defer tagEncRegistersMu.Unlock()
+	er, found := tagEncRegisters[tags]
+	if !found {
+		er = newEncRegister()
+		er.tags = tags
+		tagEncRegisters[tags] = er
+	}
+	return er
+}
+
 // Register introspects and stores the necessary data to marshal csv data.
-func (er *encRegister) Register(st reflect.Type) {
-	if _, found := er.Fields[st]; found {
-		return
+func (er *encRegister) Register(st reflect.Type) error {
+	if _, found := er.get(st); found {
+		return nil
 	}
 
 	si := newStructInfo()
+	var encoded []fieldInfo
 	for i := 0; i < st.NumField(); i++ {
 		fi := fieldInfo{FieldIndex: i}
 		sf := st.Field(i)
-		fi.ColName = sf.Tag.Get("csvplus")
+		var opts tagOptions
+		fi.ColName, opts = columnTag(sf, er.tags)
 		switch fi.ColName {
 		case "-":
 			fi.SkipField = true
 		case "":
 			fi.ColName = sf.Name
+		default:
+			// use the first alias as the header
+			fi.ColName = strings.Split(fi.ColName, "|")[0]
 		}
 
 		fi.Name = sf.Name
@@ -177,24 +644,184 @@ func (er *encRegister) Register(st reflect.Type) {
 			fi.ColIndex = i
 		}
 
-		if sf.Type.String() == timeType || sf.Type.String() == timeTypePtr {
+		if sf.Type.String() == timeType || sf.Type.String() == timeTypePtr || sf.Type == dateType || sf.Type == reflect.PtrTo(dateType) {
 			fi.Format = getTimeFormat(sf)
+		} else if format, ok := getBytesFormat(sf); ok {
+			fi.Format = format
+		} else {
+			fi.Format, fi.TrimZeros = getFloatFormat(sf)
+		}
+		base, err := getIntBase(sf)
+		if err != nil {
+			return err
 		}
+		fi.Base = base
+		fi.Converters = getConverters(sf)
+		fi.NilValue = getNilValue(sf)
+		fi.ArrayLen = getArrayLen(sf)
+		fi.setOptions(opts)
+		loc, err := getLocation(sf)
+		if err != nil {
+			return err
+		}
+		fi.Location = loc
 
 		si.fields[fi.FieldIndex] = fi
-
 		if !fi.SkipField {
-			si.fieldIndices = append(si.fieldIndices, fi.ColIndex)
-			si.headerRow = append(si.headerRow, fi.ColName)
+			encoded = append(encoded, fi)
+		}
+	}
+
+	// fields with an order option come first, the rest are in the order they're declared
+	sort.SliceStable(encoded, func(i, j int) bool {
+		oi, oj := encoded[i].Order, encoded[j].Order
+		if oi < 0 {
+			oi = math.MaxInt
+		}
+		if oj < 0 {
+			oj = math.MaxInt
+		}
+		return oi < oj
+	})
+
+	// computed columns come after the fields
+	if er.tags.computed {
+		encoded = append(encoded, computedColumns(st)...)
+	}
+
+	for _, fi := range encoded {
+		si.fieldIndices = append(si.fieldIndices, fi.ColIndex)
+		if fi.Method != "" {
+			si.fields[fi.ColIndex] = fi
 		}
 	}
+	si.headerRow = headerRow(encoded, nil)
 
+	er.mu.Lock()
 	er.Fields[st] = *si
+	er.mu.Unlock()
+	return nil
+}
+
+// get returns the struct info of st if it's been registered.
+func (er *encRegister) get(st reflect.Type) (structInfo, bool) {
+	er.mu.RLock()
+	defer er.mu.RUnlock()
+	si, found := er.Fields[st]
+	return si, found
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// computedColumnPrefix is the prefix of the names of methods whose results are written as computed columns, eg the
+// value of the Total column is returned by a CSVTotal method.
+const computedColumnPrefix = "CSV"
+
+// computedColumns returns the computed columns of st, from methods (with value or pointer receivers) called CSV<Name>
+// that take no arguments and return a string, or a string and an error. They're in method name order, their indices
+// follow the fields of st.
+func computedColumns(st reflect.Type) []fieldInfo {
+	pt := reflect.PtrTo(st)
+	var fis []fieldInfo
+	for i := 0; i < pt.NumMethod(); i++ {
+		m := pt.Method(i)
+		colName := strings.TrimPrefix(m.Name, computedColumnPrefix)
+		if colName == m.Name || colName == "" {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(colName); !unicode.IsUpper(r) {
+			continue
+		}
+		mt := m.Type // the receiver is the first argument
+		if mt.NumIn() != 1 || mt.NumOut() == 0 || mt.NumOut() > 2 || mt.Out(0).Kind() != reflect.String ||
+			(mt.NumOut() == 2 && mt.Out(1) != errorType) {
+			continue
+		}
+		fis = append(fis, fieldInfo{
+			Name:       m.Name,
+			FieldIndex: -1,
+			ColName:    colName,
+			ColIndex:   st.NumField() + len(fis),
+			Base:       10,
+			Order:      -1,
+			Method:     m.Name,
+		})
+	}
+	return fis
+}
+
+// fieldValue returns the value of the field of the addressable struct sv that fi is for, or the value returned by the
+// method of a computed column.
+func fieldValue(sv reflect.Value, fi fieldInfo) (reflect.Value, error) {
+	if fi.Method == "" {
+		return sv.Field(fi.FieldIndex), nil
+	}
+	out := sv.Addr().MethodByName(fi.Method).Call(nil)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, errors.Wrapf(out[1].Interface().(error), "%s()", fi.Method)
+	}
+	return out[0], nil
+}
+
+// headerRow returns the header row for the columns of fis, array fields stored in consecutive columns have a column
+// per element (eg coords[0], coords[1]). If mapHeader isn't nil it's applied to the column names.
+func headerRow(fis []fieldInfo, mapHeader func(field, tag string) string) []string {
+	header := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		colName := fi.ColName
+		if mapHeader != nil {
+			colName = mapHeader(fi.Name, colName)
+		}
+		if fi.columns() == 1 {
+			header = append(header, colName)
+			continue
+		}
+		for k := 0; k < fi.ArrayLen; k++ {
+			header = append(header, fmt.Sprintf("%s[%d]", colName, k))
+		}
+	}
+	return header
+}
+
+// selectColumns returns the fields of fis with the column names in names, in the same order as names.
+func selectColumns(fis []fieldInfo, names []string) ([]fieldInfo, error) {
+	byName := make(map[string]fieldInfo, len(fis))
+	for _, fi := range fis {
+		byName[fi.ColName] = fi
+	}
+
+	selected := make([]fieldInfo, 0, len(names))
+	var unknown []string
+	for _, name := range names {
+		fi, found := byName[name]
+		if !found {
+			unknown = append(unknown, name)
+			continue
+		}
+		selected = append(selected, fi)
+	}
+	if len(unknown) > 0 {
+		return nil, errors.Errorf("unknown columns: %s", strings.Join(unknown, ", "))
+	}
+	return selected, nil
+}
+
+// encodeFields returns the fields of st that are marshalled, in column order.
+func (er *encRegister) encodeFields(st reflect.Type) ([]fieldInfo, error) {
+	if err := er.Register(st); err != nil {
+		return nil, err
+	}
+	si, _ := er.get(st)
+	var fis []fieldInfo
+	for _, fieldIndex := range si.fieldIndices {
+		fis = append(fis, si.fields[fieldIndex])
+	}
+	return fis, nil
 }
 
 // GetEncodeIndices returns the struct field indices needed to marshal csv data for this type.
 func (er *encRegister) GetEncodeIndices(st reflect.Type) []int {
-	si, found := er.Fields[st]
+	si, found := er.get(st)
 	if !found {
 		return nil
 	}
@@ -203,7 +830,7 @@ func (er *encRegister) GetEncodeIndices(st reflect.Type) []int {
 
 // GetEncodeHeaders returns the values for the csv header row for this type.
 func (er *encRegister) GetEncodeHeaders(st reflect.Type) []string {
-	si, found := er.Fields[st]
+	si, found := er.get(st)
 	if !found {
 		return nil
 	}
@@ -0,0 +1,152 @@
+package csvplus
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// NewRotatingEncoder returns an Encoder that writes to a sequence of parts (eg files called export-0001.csv,
+// export-0002.csv...) that are created by calling create with the number of the part, starting at 0. A new part is
+// started after maxRows rows, or before a row that would make the part bigger than maxBytes, 0 means there's no limit.
+// Every part starts with the BOM (if WriteBOM is set) and header row, a part is only bigger than maxBytes if it has a
+// single row that's bigger. Close must be called after the last call to Encode to flush and close the last part.
+// SetCSVWriter can't be used with a rotating Encoder.
+func NewRotatingEncoder(create func(part int) (io.WriteCloser, error), maxRows int, maxBytes int64) *Encoder {
+	rw := &rotatingWriter{
+		create:   create,
+		maxRows:  maxRows,
+		maxBytes: maxBytes,
+	}
+	enc := NewEncoder(rw)
+	enc.rotating = rw
+	return enc
+}
+
+// rotatingWriter is the output of a rotating Encoder, each row is held in pending until the Encoder calls endRow so
+// that it can be written to a new part if the current one is full.
+type rotatingWriter struct {
+	create   func(part int) (io.WriteCloser, error)
+	maxRows  int
+	maxBytes int64
+	parts    int // number of parts created
+	file     io.WriteCloser
+	bw       *bufio.Writer // buffers writes to file
+	size     int64         // bytes written to the current part
+	rows     int           // rows written to the current part
+	header   []byte        // written at the start of every part
+	pending  bytes.Buffer
+}
+
+func (rw *rotatingWriter) Write(p []byte) (int, error) {
+	return rw.pending.Write(p)
+}
+
+// endHeader makes the pending output (the BOM and header row) the start of every part.
+func (rw *rotatingWriter) endHeader() {
+	rw.header = append(rw.header[:0], rw.pending.Bytes()...)
+	rw.pending.Reset()
+}
+
+// endRow writes the pending row to the current part, a new part is started first if the current one is full.
+func (rw *rotatingWriter) endRow() error {
+	if rw.pending.Len() == 0 {
+		return nil
+	}
+	if rw.file != nil && rw.full(int64(rw.pending.Len())) {
+		if err := rw.closePart(); err != nil {
+			return err
+		}
+	}
+	if rw.file == nil {
+		if err := rw.openPart(); err != nil {
+			return err
+		}
+	}
+
+	n, err := rw.pending.WriteTo(rw.bw)
+	rw.size += n
+	rw.rows++
+	return err
+}
+
+// full reports whether the current part is full, rowSize is the size of the next row.
+func (rw *rotatingWriter) full(rowSize int64) bool {
+	if rw.maxRows > 0 && rw.rows >= rw.maxRows {
+		return true
+	}
+	return rw.maxBytes > 0 && rw.rows > 0 && rw.size+rowSize > rw.maxBytes
+}
+
+// openPart creates the next part and writes the header to it.
+func (rw *rotatingWriter) openPart() error {
+	f, err := rw.create(rw.parts)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create part %d", rw.parts)
+	}
+	rw.parts++
+	rw.file, rw.size, rw.rows = f, 0, 0
+	if rw.bw == nil {
+		rw.bw = bufio.NewWriter(f)
+	} else {
+		rw.bw.Reset(f)
+	}
+
+	n, err := rw.bw.Write(rw.header)
+	rw.size += int64(n)
+	return err
+}
+
+// closePart flushes and closes the current part.
+func (rw *rotatingWriter) closePart() error {
+	err := rw.bw.Flush()
+	if cerr := rw.file.Close(); err == nil {
+		err = cerr
+	}
+	rw.file = nil
+	return errors.Wrapf(err, "unable to close part %d", rw.parts-1)
+}
+
+// close writes any pending output and closes the last part, a part with just the header is created if there weren't
+// any rows.
+func (rw *rotatingWriter) close() error {
+	if err := rw.endRow(); err != nil {
+		return err
+	}
+	if rw.file == nil && rw.parts == 0 {
+		if err := rw.openPart(); err != nil {
+			return err
+		}
+	}
+	if rw.file == nil {
+		return nil
+	}
+	return rw.closePart()
+}
+
+// Close flushes any buffered rows, for an Encoder returned by NewRotatingEncoder it also closes the last part and for
+// one returned by NewFileEncoder it closes the file (even if the rows can't be flushed). The io.Writer passed to
+// NewEncoder isn't closed. It's safe to call more than once.
+func (enc *Encoder) Close() error {
+	err := enc.flush()
+	if err == nil && enc.rotating != nil {
+		err = enc.rotating.close()
+	}
+	closers := enc.closers
+	enc.closers = nil
+	return closeAll(err, closers)
+}
+
+// endRow counts the row just written and flushes it to the current part of a rotating Encoder.
+func (enc *Encoder) endRow() error {
+	enc.stats.Rows++
+	if enc.rotating == nil {
+		return nil
+	}
+	if err := enc.flush(); err != nil {
+		return err
+	}
+	return enc.rotating.endRow()
+}
@@ -0,0 +1,98 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+// bufferCloser is an in memory part of a rotating Encoder.
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (bc *bufferCloser) Close() error {
+	bc.closed = true
+	return nil
+}
+
+func TestNewRotatingEncoder(t *testing.T) {
+	type Item struct {
+		Name  string `csvplus:"name"`
+		Count int    `csvplus:"count"`
+	}
+	var items []Item
+	for i := 0; i < 5; i++ {
+		items = append(items, Item{fmt.Sprintf("item%d", i), i})
+	}
+
+	encode := func(t *testing.T, maxRows int, maxBytes int64, items []Item) []*bufferCloser {
+		var parts []*bufferCloser
+		create := func(part int) (io.WriteCloser, error) {
+			if part != len(parts) {
+				t.Errorf("expected part %d, got: %d", len(parts), part)
+			}
+			bc := &bufferCloser{}
+			parts = append(parts, bc)
+			return bc, nil
+		}
+		enc := csvplus.NewRotatingEncoder(create, maxRows, maxBytes).WriteBOM(true)
+		if err := enc.Encode(&items); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		for i, part := range parts {
+			if !part.closed {
+				t.Errorf("part %d wasn't closed", i)
+			}
+		}
+		return parts
+	}
+
+	t.Run("rows", func(t *testing.T) {
+		parts := encode(t, 2, 0, items)
+		expected := []string{
+			"\ufeffname,count\nitem0,0\nitem1,1\n",
+			"\ufeffname,count\nitem2,2\nitem3,3\n",
+			"\ufeffname,count\nitem4,4\n",
+		}
+		if len(parts) != len(expected) {
+			t.Fatalf("expected %d parts, got: %d", len(expected), len(parts))
+		}
+		for i, part := range parts {
+			if part.String() != expected[i] {
+				t.Errorf("part %d: expected %q, got: %q", i, expected[i], part.String())
+			}
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		// the BOM and header are 14 bytes, rows are 8 bytes
+		parts := encode(t, 0, 30, items)
+		if len(parts) != 3 {
+			t.Fatalf("expected 3 parts, got: %d", len(parts))
+		}
+		for i, part := range parts {
+			if part.Len() > 30 {
+				t.Errorf("part %d is %d bytes", i, part.Len())
+			}
+			if !strings.HasPrefix(part.String(), "\ufeffname,count\n") {
+				t.Errorf("part %d doesn't start with the header: %q", i, part.String())
+			}
+		}
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		parts := encode(t, 2, 0, nil)
+		if len(parts) != 1 || parts[0].String() != "\ufeffname,count\n" {
+			t.Errorf("expected a part with just the header, got: %v", parts)
+		}
+	})
+}
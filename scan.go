@@ -0,0 +1,89 @@
+package csvplus
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Scan advances to the next data row (after the header row, if there is one), which is then decoded by calling Item.
+// It returns false when there are no more rows or a row can't be read, Err returns the error (if any). It's the same
+// idiom as bufio.Scanner, eg
+//
+//	for dec.Scan() {
+//		var row Item
+//		if err := dec.Item(&row); err != nil {
+//			return err
+//		}
+//	}
+//	if err := dec.Err(); err != nil {
+//		return err
+//	}
+//
+// Rows that can't be read are passed to the OnError function, if they're skipped Scan advances to the next row
+// instead. Scan and Decode (or ReadRecord) shouldn't both be used with the same Decoder.
+func (dec *Decoder) Scan() bool {
+	dec.scanRecord = nil
+	if dec.scanErr != nil {
+		return false
+	}
+	if dec.err != nil {
+		dec.scanErr = dec.err
+		return false
+	}
+	c := dec.startCall()
+	defer dec.endCall(c)
+
+	row, record, err := dec.nextRecord(nil)
+	if err != nil {
+		if err != io.EOF {
+			dec.scanErr = err
+		}
+		return false
+	}
+	dec.scanRow, dec.scanRecord = row, record
+	return true
+}
+
+// Item decodes the row most recently read by Scan into the struct pointed to by v (or map with a Schema), fields
+// without a column in the csv data are set to their zero values. Unlike DecodeOneStruct an error unmarshalling the row
+// is returned rather than being passed to the OnError function, FilterDecoded isn't applied (use Filter instead).
+func (dec *Decoder) Item(v interface{}) error {
+	if dec.scanRecord == nil {
+		return errors.New("Item called without a successful call to Scan")
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("non pointer or nil %T: %w", v, ErrNotStructPtr)
+	}
+	rowType := rv.Type().Elem()
+	if err := checkElemType(rowType, dec.schema); err != nil {
+		return fmt.Errorf("%s: %w", err, ErrNotStructPtr)
+	}
+	c := dec.startCall()
+	defer dec.endCall(c)
+
+	if dec.rowType != rowType {
+		header := dec.header
+		if dec.withoutHeader {
+			header = dec.scanRecord
+		}
+		if err := dec.mapColumns(rowType, header); err != nil {
+			return err
+		}
+	}
+	rv.Elem().Set(reflect.Zero(rowType))
+	if err := dec.unmarshalRow(dec.scanRow, dec.scanRecord, rv.Elem()); err != nil {
+		dec.stats.Rejected++
+		return err
+	}
+	dec.stats.Rows++
+	return nil
+}
+
+// Err returns the first error that stopped Scan, nil if it stopped because there were no more rows.
+func (dec *Decoder) Err() error {
+	return dec.scanErr
+}
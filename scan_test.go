@@ -0,0 +1,77 @@
+package csvplus_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDecoder_Scan(t *testing.T) {
+	type Item struct {
+		Name string `csvplus:"name"`
+		Qty  int    `csvplus:"qty"`
+	}
+
+	dec := csvplus.NewDecoder(strings.NewReader("name,qty\na,1\nb,x\nc,3\n"))
+	var items []Item
+	var errs []error
+	for dec.Scan() {
+		var item Item
+		if err := dec.Item(&item); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0] != (Item{"a", 1}) || items[1] != (Item{"c", 3}) {
+		t.Errorf("unexpected items %v", items)
+	}
+	var ue csvplus.UnmarshalError
+	if len(errs) != 1 || !errors.As(errs[0], &ue) || ue.Row != 2 || ue.Column != "qty" {
+		t.Errorf("expected an error for row 2, got %v", errs)
+	}
+	if dec.Scan() {
+		t.Error("expected Scan to return false after the last row")
+	}
+	if err := dec.Item(&Item{}); err == nil {
+		t.Error("expected an error calling Item after Scan returned false")
+	}
+
+	t.Run("without header", func(t *testing.T) {
+		type Row struct {
+			Name string
+			Qty  int
+		}
+		dec := csvplus.NewDecoder(strings.NewReader("a,1\nb,2\n")).UseHeader(false)
+		var rows []Row
+		for dec.Scan() {
+			var row Row
+			if err := dec.Item(&row); err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+		if err := dec.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[1] != (Row{"b", 2}) {
+			t.Errorf("unexpected rows %v", rows)
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		dec := csvplus.NewDecoder(strings.NewReader("name,qty\na,1\nb,2,3\nc,3\n"))
+		var n int
+		for dec.Scan() {
+			n++
+		}
+		if n != 1 || dec.Err() == nil {
+			t.Errorf("expected Scan to stop with an error after 1 row, got %d rows and %v", n, dec.Err())
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package csvplus
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// mapType is the element type of slices that can be decoded into/encoded from using a Schema.
+var mapType = reflect.TypeOf(map[string]interface{}(nil))
+
+var stringType = reflect.TypeOf("")
+
+// ColumnSpec describes a single column of a Schema, use Column to create one.
+type ColumnSpec struct {
+	name     string
+	typ      reflect.Type
+	layout   string
+	required bool
+	def      string
+	field    string
+	rules    string
+	pattern  string
+}
+
+// Column returns a ColumnSpec for the column called name, values are converted to the type of v (eg 0, 0.0, true,
+// time.Time{} or csvplus.Date{}), a nil v means string. Pointer types can be used for nullable columns.
+func Column(name string, v interface{}) ColumnSpec {
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		typ = stringType
+	}
+	return ColumnSpec{name: name, typ: typ}
+}
+
+// Layout sets the time.Parse layout (or unix, unixmilli, unixnano or auto) for time.Time and Date columns, layout
+// constants of the time package can be used by name (eg time.DateTime). For float columns it's the fmt verb (eg %.2f)
+// used to marshal them.
+func (c ColumnSpec) Layout(layout string) ColumnSpec {
+	c.layout = layout
+	return c
+}
+
+// Required sets whether the column must be in the header row and have a value in every row.
+func (c ColumnSpec) Required(b bool) ColumnSpec {
+	c.required = b
+	return c
+}
+
+// Default sets the value used in place of empty values when decoding, and in place of missing (or nil) map values
+// when encoding.
+func (c ColumnSpec) Default(s string) ColumnSpec {
+	c.def = s
+	return c
+}
+
+// Validate sets the validation rules for values of the column, the same as a csvplusValidate struct tag (eg
+// nonempty,maxlen=20), see ValidationError.
+func (c ColumnSpec) Validate(rules string) ColumnSpec {
+	c.rules = rules
+	return c
+}
+
+// Pattern sets a regular expression that non empty values of the column must match, the same as a csvplusPattern struct
+// tag.
+func (c ColumnSpec) Pattern(expr string) ColumnSpec {
+	c.pattern = expr
+	return c
+}
+
+// Field sets the name of the struct field the column is stored in when decoding into/encoding from structs, by default
+// it's the field whose name matches the column name ignoring case, spaces, underscores and hyphens. The type of the
+// field is used rather than the type passed to Column.
+func (c ColumnSpec) Field(name string) ColumnSpec {
+	c.field = name
+	return c
+}
+
+// Schema describes the columns of csv data at runtime, it's an alternative to struct tags for when the columns are
+// only known at runtime (eg user configurable imports). A Schema can be used to decode into/encode from a
+// []map[string]interface{} as well as a slice of structs.
+type Schema struct {
+	columns []ColumnSpec
+}
+
+// NewSchema returns a Schema with the given columns, in order.
+func NewSchema(cols ...ColumnSpec) *Schema {
+	return &Schema{columns: cols}
+}
+
+// Add appends col to the columns of the schema.
+func (s *Schema) Add(col ColumnSpec) *Schema {
+	s.columns = append(s.columns, col)
+	return s
+}
+
+// Header returns the names of the columns of the schema, in order.
+func (s *Schema) Header() []string {
+	header := make([]string, len(s.columns))
+	for i, col := range s.columns {
+		header[i] = col.name
+	}
+	return header
+}
+
+// fieldInfo maps the columns in the csv data to the columns of the schema, t is the struct or map type values are
+// stored in. Columns that aren't in the header are omitted, without a header columns are mapped by position.
+func (s *Schema) fieldInfo(t reflect.Type, header []string, mo matchOptions) ([]fieldInfo, error) {
+	normalise := func(s string) string { return s }
+	if mo.loose && !mo.withoutHeader {
+		normalise = looseColName
+	}
+	headersMap := make(map[string]int)
+	for i, colName := range header {
+		if _, dup := headersMap[normalise(colName)]; dup && mo.firstDup {
+			continue
+		}
+		headersMap[normalise(colName)] = i
+	}
+
+	var fis []fieldInfo
+	for i, col := range s.columns {
+		fi := fieldInfo{
+			Name:     col.name,
+			ColName:  col.name,
+			ColIndex: i,
+			Base:     10,
+			Required: col.required,
+			Default:  col.def,
+			Type:     col.typ,
+		}
+
+		if t.Kind() == reflect.Struct {
+			sf, ok := col.structField(t)
+			if !ok {
+				return nil, fmt.Errorf("no field in %s for column %s", t, col.name)
+			}
+			fi.Name = sf.Name
+			fi.FieldIndex = sf.Index[0]
+			fi.Type = sf.Type
+		}
+
+		if !mo.withoutHeader {
+			colIndex, found := headersMap[normalise(col.name)]
+			if !found {
+				continue
+			}
+			fi.ColIndex = colIndex
+		}
+
+		fi.Format = col.format(fi.Type)
+		rules, err := parseRules(col.rules, col.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validation rules for column %s: %w", col.name, err)
+		}
+		fi.Rules = rules
+		fis = append(fis, fi)
+	}
+	return fis, nil
+}
+
+// missingColumns returns the names of the required columns (or all columns if all is set) that aren't in fis.
+func (s *Schema) missingColumns(fis []fieldInfo, all bool) []string {
+	mapped := make(map[string]bool, len(fis))
+	for _, fi := range fis {
+		mapped[fi.ColName] = true
+	}
+
+	var missing []string
+	for _, col := range s.columns {
+		if (col.required || all) && !mapped[col.name] {
+			missing = append(missing, col.name)
+		}
+	}
+	return missing
+}
+
+// structField returns the field of the struct t the column is stored in.
+func (c ColumnSpec) structField(t reflect.Type) (reflect.StructField, bool) {
+	if c.field != "" {
+		sf, ok := t.FieldByName(c.field)
+		return sf, ok && len(sf.Index) == 1
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath == "" && looseColName(sf.Name) == looseColName(c.name) {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// format returns the layout of the column, time.Time and Date columns without a layout default to time.RFC3339 and
+// 2006-01-02 respectively.
+func (c ColumnSpec) format(t reflect.Type) string {
+	if c.layout != "" {
+		return namedLayout(c.layout)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == dateType:
+		return dateLayout
+	case t == reflect.TypeOf(time.Time{}):
+		return time.RFC3339
+	}
+	return ""
+}
+
+// Schema sets the schema used to map columns to the fields of the structs (or keys of the maps) passed to Decode,
+// struct tags are ignored. Decode accepts a *[]map[string]interface{} when a schema is set, columns that aren't in the
+// schema are ignored. Missing required columns cause Decode to return an error wrapping ErrMissingColumn, and empty
+// values in required columns an error wrapping ErrEmptyValue.
+func (dec *Decoder) Schema(s *Schema) *Decoder {
+	dec.schema = s
+	return dec
+}
+
+// Schema sets the schema used to choose the columns (and their order) written by Encode, struct tags are ignored.
+// Encode accepts a *[]map[string]interface{} when a schema is set.
+func (enc *Encoder) Schema(s *Schema) *Encoder {
+	enc.schema = s
+	return enc
+}
+
+// checkElemType returns an error if values of type t can't be decoded into/encoded from, maps are only supported with a
+// schema.
+func checkElemType(t reflect.Type, s *Schema) error {
+	switch {
+	case t == mapType && s != nil:
+		return nil
+	case t.Kind() == reflect.Struct:
+		return nil
+	case t.Kind() == reflect.Map && s == nil:
+		return fmt.Errorf("%s elements require a Schema", t)
+	}
+	return fmt.Errorf("unsupported slice element type %s", t)
+}
+
+// MarshalMaps marshals rows into csv data with the columns in headers (in order), values are formatted according to
+// their type the same way as struct fields (time.Time values use time.RFC3339). Missing and nil values are written as
+// empty values. Use an Encoder with a Schema to set per column layouts.
+func MarshalMaps(headers []string, rows []map[string]interface{}) ([]byte, error) {
+	schema := NewSchema()
+	for _, h := range headers {
+		schema.Add(Column(h, nil))
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Schema(schema).Encode(&rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
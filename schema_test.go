@@ -0,0 +1,230 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func ExampleSchema() {
+	schema := csvplus.NewSchema(
+		csvplus.Column("id", 0).Required(true),
+		csvplus.Column("name", nil),
+		csvplus.Column("joined", csvplus.Date{}).Layout("02/01/2006"),
+		csvplus.Column("score", 0.0).Default("0"),
+	)
+
+	data := "id,name,joined,score,ignored\n1,Rob,01/11/1999,7.5,x\n2,Russ,14/03/2001,,y\n"
+
+	var rows []map[string]interface{}
+	err := csvplus.NewDecoder(strings.NewReader(data)).Schema(schema).Decode(&rows)
+	if err != nil {
+		panic(err)
+	}
+	for _, row := range rows {
+		fmt.Println(row["id"], row["name"], row["joined"], row["score"])
+	}
+
+	var buf bytes.Buffer
+	err = csvplus.NewEncoder(&buf).Schema(schema).Encode(&rows)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(buf.String())
+	// Output:
+	// 1 Rob 1999-11-01 7.5
+	// 2 Russ 2001-03-14 0
+	// id,name,joined,score
+	// 1,Rob,01/11/1999,7.5
+	// 2,Russ,14/03/2001,0
+}
+
+func TestDecoder_Schema(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		type Item struct {
+			ID      int    `csvplus:"not_used"`
+			Name    string `csvplus:"-"`
+			Created time.Time
+		}
+		schema := csvplus.NewSchema(
+			csvplus.Column("id", 0),
+			csvplus.Column("full_name", nil).Field("Name"),
+			csvplus.Column("created", time.Time{}).Layout("2006-01-02 15:04"),
+		)
+		data := "full_name,id,created\nRob,1,2020-01-02 15:04\n"
+
+		var items []Item
+		err := csvplus.NewDecoder(strings.NewReader(data)).Schema(schema).Decode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := Item{ID: 1, Name: "Rob", Created: time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC)}
+		if len(items) != 1 || items[0] != expected {
+			t.Errorf("expected %v, got: %v", expected, items)
+		}
+	})
+
+	t.Run("without header", func(t *testing.T) {
+		schema := csvplus.NewSchema(csvplus.Column("name", nil), csvplus.Column("age", new(int)))
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("Rob,\n")).UseHeader(false).Schema(schema).Decode(&rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0]["name"] != "Rob" {
+			t.Errorf("expected name Rob, got: %v", rows[0]["name"])
+		}
+		if age := rows[0]["age"].(*int); age != nil {
+			t.Errorf("expected nil age, got: %v", *age)
+		}
+	})
+
+	t.Run("missing required column", func(t *testing.T) {
+		schema := csvplus.NewSchema(csvplus.Column("id", 0).Required(true), csvplus.Column("name", nil))
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("name\nRob\n")).Schema(schema).Decode(&rows)
+		if !errors.Is(err, csvplus.ErrMissingColumn) {
+			t.Errorf("expected ErrMissingColumn, got: %v", err)
+		}
+	})
+
+	t.Run("empty required value", func(t *testing.T) {
+		schema := csvplus.NewSchema(csvplus.Column("id", 0).Required(true))
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("id\n1\n\"\"\n")).Schema(schema).Decode(&rows)
+		if !errors.Is(err, csvplus.ErrEmptyValue) {
+			t.Errorf("expected ErrEmptyValue, got: %v", err)
+		}
+		var ue csvplus.UnmarshalError
+		if errors.As(err, &ue) && ue.Row != 2 {
+			t.Errorf("expected row 2, got: %d", ue.Row)
+		}
+	})
+
+	t.Run("map without schema", func(t *testing.T) {
+		var rows []map[string]interface{}
+		err := csvplus.Unmarshal([]byte("id\n1\n"), &rows)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestDecoder_InferTypes(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		data := "id,name,score,active,joined,seen\n" +
+			"1,Rob,7.5,true,2020-01-02,2020-01-02T15:04:05Z\n" +
+			"2,Russ,,false,2021-03-04,2021-03-04T10:00:00Z\n" +
+			"3,Ken,9,true,2022-05-06,2022-05-06T11:30:00Z\n"
+
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader(data)).InferTypes(2).Decode(&rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 3 {
+			t.Fatalf("expected 3 rows, got: %d", len(rows))
+		}
+
+		row := rows[0]
+		if row["id"] != 1 {
+			t.Errorf("expected id int 1, got: %#v", row["id"])
+		}
+		if row["name"] != "Rob" {
+			t.Errorf("expected name Rob, got: %#v", row["name"])
+		}
+		if score, ok := row["score"].(*float64); !ok || score == nil || *score != 7.5 {
+			t.Errorf("expected score *float64 7.5, got: %#v", row["score"])
+		}
+		if score := rows[1]["score"].(*float64); score != nil {
+			t.Errorf("expected nil score, got: %v", *score)
+		}
+		if row["active"] != true {
+			t.Errorf("expected active true, got: %#v", row["active"])
+		}
+		if joined, ok := row["joined"].(csvplus.Date); !ok || joined.String() != "2020-01-02" {
+			t.Errorf("expected joined Date 2020-01-02, got: %#v", row["joined"])
+		}
+		expected := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+		if seen, ok := row["seen"].(time.Time); !ok || !seen.Equal(expected) {
+			t.Errorf("expected seen %s, got: %#v", expected, row["seen"])
+		}
+		// rows after the sample use the inferred types
+		if score := rows[2]["score"].(*float64); score == nil || *score != 9 {
+			t.Errorf("expected score 9, got: %#v", rows[2]["score"])
+		}
+	})
+
+	t.Run("without header", func(t *testing.T) {
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("Rob,1\nRuss,2\n")).UseHeader(false).InferTypes(10).Decode(&rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[1]["0"] != "Russ" || rows[1]["1"] != 2 {
+			t.Errorf("unexpected rows: %v", rows)
+		}
+	})
+
+	t.Run("value outside sample", func(t *testing.T) {
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("id\n1\nx\n")).InferTypes(1).Decode(&rows)
+		if !errors.Is(err, csvplus.ErrTypeConversion) {
+			t.Errorf("expected ErrTypeConversion, got: %v", err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader("")).InferTypes(10).Decode(&rows)
+		if err != nil || len(rows) != 0 {
+			t.Errorf("expected no rows or error, got: %v, %v", rows, err)
+		}
+	})
+}
+
+func TestEncoder_Schema(t *testing.T) {
+	type Item struct {
+		First  string
+		Second float64
+		Third  bool
+	}
+	items := []Item{{"a", 1.5, true}}
+	schema := csvplus.NewSchema(csvplus.Column("third", false), csvplus.Column("second", 0.0).Layout("%.2f"))
+
+	var buf bytes.Buffer
+	err := csvplus.NewEncoder(&buf).Schema(schema).Encode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "third,second\ntrue,1.50\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestMarshalMaps(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "Rob", "score": 7.5, "joined": time.Date(1999, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"id": uint8(2), "name": "Russ", "active": true, "joined": csvplus.Date{Year: 2001, Month: 3, Day: 14}, "score": nil},
+	}
+	data, err := csvplus.MarshalMaps([]string{"id", "name", "score", "active", "joined"}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "id,name,score,active,joined\n1,Rob,7.5,,1999-11-01T00:00:00Z\n2,Russ,,true,2001-03-14\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got: %q", expected, data)
+	}
+
+	_, err = csvplus.MarshalMaps([]string{"id"}, []map[string]interface{}{{"id": []int{1}}})
+	var ee csvplus.EncodeError
+	if !errors.As(err, &ee) || ee.Field != "id" {
+		t.Errorf("expected EncodeError for id, got: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package csvplus
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// sniffSampleBytes is the amount of input sampled by Sniff.
+const sniffSampleBytes = 8 << 10
+
+// sniffDelimiters are the delimiters Sniff detects, in order of preference when the input fits more than one.
+var sniffDelimiters = []rune{',', ';', '\t', '|'}
+
+// SniffResult is the dialect of csv data detected by Sniff.
+type SniffResult struct {
+	Comma rune // the field delimiter, one of , ; tab or |
+	// Quote is the character values are quoted with, " or ' (the csv reader only supports ", values quoted with '
+	// keep their quotes).
+	Quote     rune
+	HasHeader bool // the first row is a header row
+}
+
+// Sniff reads a sample (the first 8KB) of the csv data from r and detects its delimiter (the one that splits the most
+// rows into the same number of fields), quote character and whether the first row is a header row. The first row is
+// taken to be a header row if its values don't have the types of the rest of the column (eg a name in a column of
+// numbers, or a value with a different length to the rest of a column of codes), or if there's no evidence either
+// way. The sample is consumed, use the Decoder's Sniff option to sniff input before decoding it.
+func Sniff(r io.Reader) (SniffResult, error) {
+	sample := make([]byte, sniffSampleBytes)
+	n, err := io.ReadFull(r, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return SniffResult{}, errors.Wrap(err, "unable to read sample")
+	}
+	return sniff(sample[:n], n < len(sample)), nil
+}
+
+// Sniff sets Comma and UseHeader to the dialect detected from a sample of the input (see the Sniff function), the
+// sample is read straight away but is still decoded. Call it after Charset if that's used, it has no effect if
+// SetCSVReader is used.
+func (dec *Decoder) Sniff() *Decoder {
+	if dec.customReader || dec.recordReader != nil {
+		return dec
+	}
+	sample, err := dec.src.peek(sniffSampleBytes)
+	if err != nil {
+		dec.err = errors.Wrap(err, "unable to sniff input")
+		return dec
+	}
+	s := sniff(sample, len(sample) < sniffSampleBytes)
+	dec.csvReader.Comma = s.Comma
+	dec.withoutHeader = !s.HasHeader
+	return dec
+}
+
+// sniff detects the dialect of sample, complete is false if the sample is the start of longer input (its last line is
+// ignored as it's probably cut short).
+func sniff(sample []byte, complete bool) SniffResult {
+	sample = bytes.TrimPrefix(sample, utf8BOM)
+	if !complete {
+		if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+			sample = sample[:i+1]
+		}
+	}
+
+	s := SniffResult{Comma: sniffDelimiters[0], Quote: '"', HasHeader: true}
+	bestRows, bestFields := 0, 0
+	var records [][]string
+	for _, comma := range sniffDelimiters {
+		recs := sniffRecords(sample, comma)
+		rows, fields := consistentRows(recs)
+		if fields > 1 && (rows > bestRows || (rows == bestRows && fields > bestFields)) {
+			s.Comma, bestRows, bestFields, records = comma, rows, fields, recs
+		}
+	}
+	if records == nil {
+		records = sniffRecords(sample, s.Comma)
+	}
+
+	if countQuoted(sample, '\'', s.Comma) > countQuoted(sample, '"', s.Comma) {
+		s.Quote = '\''
+	}
+	s.HasHeader = hasHeader(records)
+	return s
+}
+
+// sniffRecords parses sample with the delimiter comma, records up to the first parse error are returned.
+func sniffRecords(sample []byte, comma rune) [][]string {
+	r := csv.NewReader(bytes.NewReader(sample))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err != nil {
+			return records
+		}
+		records = append(records, record)
+	}
+}
+
+// consistentRows returns the most common number of fields of records and the number of records that have it.
+func consistentRows(records [][]string) (rows, fields int) {
+	counts := make(map[int]int)
+	for _, record := range records {
+		counts[len(record)]++
+	}
+	for f, n := range counts {
+		if n > rows || (n == rows && f > fields) {
+			rows, fields = n, f
+		}
+	}
+	return rows, fields
+}
+
+// countQuoted returns the number of values in sample that start with the quote character q.
+func countQuoted(sample []byte, q, comma rune) int {
+	var n int
+	prev := '\n'
+	for _, r := range string(sample) {
+		if r == q && (prev == comma || prev == '\n') {
+			n++
+		}
+		prev = r
+	}
+	return n
+}
+
+// hasHeader guesses whether the first of records is a header row, each column votes for a header if the type (or
+// length, for strings with the same length) of the first value is different to the rest of its values and against if
+// it's the same (all numbers are the same). There's a header unless the columns vote against.
+func hasHeader(records [][]string) bool {
+	if len(records) < 2 {
+		return true
+	}
+	first := records[0]
+	var votes int
+	for j, v := range first {
+		values := make([]string, 0, len(records)-1)
+		for _, record := range records[1:] {
+			if j < len(record) {
+				values = append(values, record[j])
+			}
+		}
+		if countNonEmpty(values) == 0 || v == "" {
+			continue
+		}
+
+		ct := inferColumnType(values)
+		if ct.kind != kindString {
+			if k := inferColumnType([]string{v}).kind; k == ct.kind || (isNumberKind(k) && isNumberKind(ct.kind)) {
+				votes--
+			} else {
+				votes++
+			}
+			continue
+		}
+		if l, ok := sameLength(values); ok && countNonEmpty(values) > 1 {
+			if utf8.RuneCountInString(v) == l {
+				votes--
+			} else {
+				votes++
+			}
+		}
+	}
+	return votes >= 0
+}
+
+func isNumberKind(k columnKind) bool {
+	return k == kindInt || k == kindFloat
+}
+
+// sameLength returns the length (in characters) of the non empty values if they're all the same.
+func sameLength(values []string) (int, bool) {
+	l := -1
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		n := utf8.RuneCountInString(v)
+		if l >= 0 && n != l {
+			return 0, false
+		}
+		l = n
+	}
+	return l, l >= 0
+}
@@ -0,0 +1,74 @@
+package csvplus_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want csvplus.SniffResult
+	}{
+		{"comma", "name,qty\na,1\nb,2\n", csvplus.SniffResult{Comma: ',', Quote: '"', HasHeader: true}},
+		{"semicolon", "name;price\nwidget;1,50\ngadget;10,00\n", csvplus.SniffResult{Comma: ';', Quote: '"', HasHeader: true}},
+		{"tab", "id\tname\n1\ta\n2\tb\n", csvplus.SniffResult{Comma: '\t', Quote: '"', HasHeader: true}},
+		{"pipe", "1|\"a|b\"|2.5\n2|c|3\n", csvplus.SniffResult{Comma: '|', Quote: '"', HasHeader: false}},
+		{"single quotes", "'a',1\n'b',2\n", csvplus.SniffResult{Comma: ',', Quote: '\'', HasHeader: false}},
+		{"codes", "code,country\nGB,United Kingdom\nFR,France\n", csvplus.SniffResult{Comma: ',', Quote: '"', HasHeader: true}},
+		{"no header", "GB,44\nFR,33\nDE,49\n", csvplus.SniffResult{Comma: ',', Quote: '"', HasHeader: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := csvplus.Sniff(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecoder_Sniff(t *testing.T) {
+	type Item struct {
+		Name  string
+		Price float64
+	}
+
+	var items []Item
+	err := csvplus.NewDecoder(strings.NewReader("Name;Price\nwidget;1.50\ngadget;10\n")).Sniff().Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1] != (Item{"gadget", 10}) {
+		t.Errorf("unexpected items %v", items)
+	}
+
+	items = nil
+	err = csvplus.NewDecoder(strings.NewReader("widget\t1.50\ngadget\t10\n")).Sniff().Decode(&items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0] != (Item{"widget", 1.5}) {
+		t.Errorf("unexpected items %v", items)
+	}
+
+	// the sample is longer than the input that's sniffed
+	data := "Name,Price\n" + strings.Repeat("widget,1.50\n", 1000)
+	items = nil
+	dec := csvplus.NewDecoder(strings.NewReader(data)).Sniff()
+	if err := dec.Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1000 {
+		t.Errorf("expected 1000 items, got %d", len(items))
+	}
+	if s := dec.Stats(); s.Bytes != int64(len(data)) {
+		t.Errorf("expected %d bytes read, got %d", len(data), s.Bytes)
+	}
+}
@@ -0,0 +1,274 @@
+package csvplus
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EncodeSQLRows writes the rows of a database query to w as csv data, the column names of the query are used as the
+// header row and values are formatted according to their type the same way as struct fields (time.Time values use
+// time.RFC3339). NULL values are written as empty values. rows isn't closed.
+func EncodeSQLRows(w io.Writer, rows *sql.Rows) error {
+	return NewEncoder(w).EncodeSQLRows(rows)
+}
+
+// EncodeSQLRows is the same as the EncodeSQLRows function but uses the options of the Encoder, if a Schema is set it's
+// used to select the columns (by name) and set their layouts.
+func (enc *Encoder) EncodeSQLRows(rows *sql.Rows) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	c := enc.startCall()
+	defer enc.endCall(c)
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return errors.Wrap(err, "unable to get columns")
+	}
+	schema := enc.schema
+	if schema == nil {
+		schema = NewSchema()
+		for _, col := range cols {
+			schema.Add(Column(col, nil))
+		}
+	}
+
+	fis, err := enc.begin(mapType, schema)
+	if err != nil {
+		return err
+	}
+
+	vals := make([]interface{}, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	row := make(map[string]interface{}, len(cols))
+	for i := 0; rows.Next(); i++ {
+		if err := rows.Scan(dest...); err != nil {
+			return newEncodeError(mapType, "", i, errors.Wrap(err, "unable to scan row"))
+		}
+		for j, col := range cols {
+			row[col] = vals[j]
+		}
+		if err := enc.encodeRow(mapType, fis, i, reflect.ValueOf(row)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "error reading rows")
+	}
+
+	return enc.flush()
+}
+
+// An SQLInserter inserts decoded rows into a database table using batched multi row INSERT statements, the csvplus tag
+// names (or field names for fields without a tag) of the struct fields are used as the column names.
+type SQLInserter struct {
+	db          *sql.DB
+	table       string
+	batchSize   int
+	placeholder func(n int) string
+	quoteIdent  func(name string) string
+	err         error // set by options that fail, returned by Insert
+}
+
+// NewSQLInserter returns an SQLInserter that inserts rows into table, by default rows are inserted 100 at a time using ?
+// placeholders. The table name can be qualified (eg public.users), it's quoted (see QuoteIdentifier) but must come from
+// a trusted source as it's part of the INSERT statement.
+func NewSQLInserter(db *sql.DB, table string) *SQLInserter {
+	return &SQLInserter{
+		db:          db,
+		table:       table,
+		batchSize:   100,
+		placeholder: func(int) string { return "?" },
+		quoteIdent:  quoteANSIIdentifier,
+	}
+}
+
+// BatchSize sets the maximum number of rows inserted by each INSERT statement.
+func (ins *SQLInserter) BatchSize(n int) *SQLInserter {
+	if n > 0 {
+		ins.batchSize = n
+	}
+	return ins
+}
+
+// Placeholder sets a function that returns the placeholder for the nth (starting at 1) parameter of an INSERT
+// statement, eg func(n int) string { return fmt.Sprintf("$%d", n) } for PostgreSQL.
+func (ins *SQLInserter) Placeholder(fn func(n int) string) *SQLInserter {
+	if fn == nil {
+		ins.err = errors.New("nil Placeholder function")
+		return ins
+	}
+	ins.placeholder = fn
+	return ins
+}
+
+// QuoteIdentifier sets a function that quotes the table and column names of INSERT statements so reserved words (eg
+// order) and names with spaces can be used, eg func(s string) string { return "`" + s + "`" } for MySQL. The parts of
+// a qualified table name are quoted separately. Defaults to ANSI SQL double quotes (with " doubled).
+func (ins *SQLInserter) QuoteIdentifier(fn func(name string) string) *SQLInserter {
+	if fn == nil {
+		ins.err = errors.New("nil QuoteIdentifier function")
+		return ins
+	}
+	ins.quoteIdent = fn
+	return ins
+}
+
+// quoteANSIIdentifier quotes name with double quotes, the ANSI SQL identifier quote.
+func quoteANSIIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Insert decodes each row from dec into the struct pointed to by v and inserts it into the table, all rows are inserted
+// in a single transaction which is rolled back if there's an error. Values are passed to the database driver as their
+// Go types (eg int64, float64, time.Time or nil for nil pointers), values the driver can't accept (eg Date) and fields
+// of types that implement Marshaler are passed as their csv values. The number of rows inserted is returned.
+func (ins *SQLInserter) Insert(ctx context.Context, dec *Decoder, v interface{}) (int, error) {
+	if ins.err != nil {
+		return 0, ins.err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0, fmt.Errorf("non pointer or nil %T: %w", v, ErrNotStructPtr)
+	}
+	st := rv.Type().Elem()
+	if st.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("unsupported type %s: %w", st, ErrNotStructPtr)
+	}
+
+	fis, err := sqlFieldInfo(st)
+	if err != nil {
+		return 0, err
+	}
+	cols := make([]string, len(fis))
+	for i, fi := range fis {
+		cols[i] = ins.quoteIdent(fi.ColName)
+	}
+
+	tx, err := ins.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	var n int
+	args := make([]interface{}, 0, ins.batchSize*len(cols))
+	for {
+		err := dec.DecodeOneStruct(v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		for _, fi := range fis {
+			fv, err := fieldValue(rv.Elem(), fi)
+			if err != nil {
+				return 0, newEncodeError(st, fi.Name, n, err)
+			}
+			val, err := sqlValue(fv, fi)
+			if err != nil {
+				return 0, newEncodeError(st, fi.Name, n, err)
+			}
+			args = append(args, val)
+		}
+		n++
+		if len(args) == cap(args) {
+			if err := ins.exec(ctx, tx, cols, args); err != nil {
+				return 0, err
+			}
+			args = args[:0]
+		}
+	}
+	if len(args) > 0 {
+		if err := ins.exec(ctx, tx, cols, args); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "unable to commit transaction")
+	}
+	return n, nil
+}
+
+// exec inserts the rows in args (len(cols) values per row) using a single INSERT statement.
+func (ins *SQLInserter) exec(ctx context.Context, tx *sql.Tx, cols []string, args []interface{}) error {
+	var b strings.Builder
+	table := strings.Split(ins.table, ".")
+	for i, name := range table {
+		table[i] = ins.quoteIdent(name)
+	}
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", strings.Join(table, "."), strings.Join(cols, ", "))
+	for i := range args {
+		switch {
+		case i == 0:
+			b.WriteString("(")
+		case i%len(cols) == 0:
+			b.WriteString("), (")
+		default:
+			b.WriteString(", ")
+		}
+		b.WriteString(ins.placeholder(i + 1))
+	}
+	b.WriteString(")")
+
+	if _, err := tx.ExecContext(ctx, b.String(), args...); err != nil {
+		return errors.Wrapf(err, "unable to insert into %s", ins.table)
+	}
+	return nil
+}
+
+// sqlFieldInfo returns the fields of the struct st that are inserted, in column order.
+func sqlFieldInfo(st reflect.Type) ([]fieldInfo, error) {
+	fis, err := defaultEncRegister.encodeFields(st)
+	if err != nil {
+		return nil, err
+	}
+	inserted := fis[:0]
+	for _, fi := range fis {
+		if fi.ArrayLen > 0 && fi.ArrayDelim == "" {
+			return nil, fmt.Errorf("array field %s without a delim option can't be inserted", fi.Name)
+		}
+		if fi.Method != "" {
+			continue // computed columns aren't table columns
+		}
+		inserted = append(inserted, fi)
+	}
+	return inserted, nil
+}
+
+// sqlValue returns the value of the struct field fv to pass to the database driver.
+func sqlValue(fv reflect.Value, fi fieldInfo) (interface{}, error) {
+	if !fi.Char && fi.ArrayLen == 0 && !fv.Type().Implements(csvMarshalerType) &&
+		!reflect.PtrTo(fv.Type()).Implements(csvMarshalerType) {
+		if v, err := driver.DefaultParameterConverter.ConvertValue(fv.Interface()); err == nil {
+			return v, nil
+		}
+	}
+	if fv.Kind() == reflect.Ptr && fv.IsNil() {
+		return nil, nil
+	}
+
+	if fi.ArrayLen == 0 {
+		return marshalField(fv, fi)
+	}
+	vals := make([]string, fi.ArrayLen)
+	for k := range vals {
+		var err error
+		if vals[k], err = marshalField(fv.Index(k), fi); err != nil {
+			return nil, err
+		}
+	}
+	return strings.Join(vals, fi.ArrayDelim), nil
+}
@@ -0,0 +1,245 @@
+package csvplus_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+// fakeDB is the data behind a connection of the fake driver, queries return rows and execs are recorded.
+type fakeDB struct {
+	mu      sync.Mutex
+	columns []string
+	rows    [][]driver.Value
+	execs   []fakeExec
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register("csvplusfake", fakeDriver{})
+}
+
+// openFakeDB returns a *sql.DB backed by fdb.
+func openFakeDB(t *testing.T, fdb *fakeDB) *sql.DB {
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = fdb
+	fakeDBsMu.Unlock()
+
+	db, err := sql.Open("csvplusfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.execs = append(s.db.execs, fakeExec{query: s.query, args: args})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{columns: s.db.columns, rows: s.db.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestEncodeSQLRows(t *testing.T) {
+	db := openFakeDB(t, &fakeDB{
+		columns: []string{"id", "name", "score", "active", "created"},
+		rows: [][]driver.Value{
+			{int64(1), []byte("Rob"), 7.5, true, time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)},
+			{int64(2), "Russ, Jr", nil, false, nil},
+		},
+	})
+
+	rows, err := db.Query("SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	if err := csvplus.EncodeSQLRows(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	expected := "id,name,score,active,created\n1,Rob,7.5,true,2020-01-02T15:04:05Z\n2,\"Russ, Jr\",,false,\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, buf.String())
+	}
+
+	t.Run("schema", func(t *testing.T) {
+		rows, err := db.Query("SELECT * FROM users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		schema := csvplus.NewSchema(
+			csvplus.Column("created", time.Time{}).Layout("2006-01-02"),
+			csvplus.Column("id", 0),
+		)
+		var buf bytes.Buffer
+		if err := csvplus.NewEncoder(&buf).Schema(schema).EncodeSQLRows(rows); err != nil {
+			t.Fatal(err)
+		}
+		expected := "created,id\n2020-01-02,1\n,2\n"
+		if buf.String() != expected {
+			t.Errorf("expected %q, got: %q", expected, buf.String())
+		}
+	})
+}
+
+func TestSQLInserter(t *testing.T) {
+	type Item struct {
+		ID     int          `csvplus:"id"`
+		Name   string       `csvplus:"name"`
+		Score  *float64     `csvplus:"score"`
+		Joined csvplus.Date `csvplus:"joined"`
+	}
+	fdb := &fakeDB{}
+	db := openFakeDB(t, fdb)
+
+	data := "id,name,score,joined\n1,Rob,7.5,1999-11-01\n2,Russ,,2001-03-14\n3,Ken,9,1943-02-04\n"
+	dec := csvplus.NewDecoder(strings.NewReader(data))
+	n, err := csvplus.NewSQLInserter(db, "users").
+		BatchSize(2).
+		Placeholder(func(n int) string { return fmt.Sprintf("$%d", n) }).
+		Insert(context.Background(), dec, &Item{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 rows inserted, got: %d", n)
+	}
+
+	expected := []fakeExec{
+		{
+			query: "INSERT INTO \"users\" (\"id\", \"name\", \"score\", \"joined\") VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)",
+			args:  []driver.Value{int64(1), "Rob", 7.5, "1999-11-01", int64(2), "Russ", nil, "2001-03-14"},
+		},
+		{
+			query: "INSERT INTO \"users\" (\"id\", \"name\", \"score\", \"joined\") VALUES ($1, $2, $3, $4)",
+			args:  []driver.Value{int64(3), "Ken", 9.0, "1943-02-04"},
+		},
+	}
+	if !reflect.DeepEqual(fdb.execs, expected) {
+		t.Errorf("expected %v, got: %v", expected, fdb.execs)
+	}
+
+	_, err = csvplus.NewSQLInserter(db, "users").Insert(context.Background(), dec, Item{})
+	if !errors.Is(err, csvplus.ErrNotStructPtr) {
+		t.Errorf("expected ErrNotStructPtr, got: %v", err)
+	}
+
+	_, err = csvplus.NewSQLInserter(db, "users").Placeholder(nil).Insert(context.Background(), dec, &Item{})
+	if err == nil {
+		t.Error("expected error for nil Placeholder")
+	}
+}
+
+func TestSQLInserter_QuoteIdentifier(t *testing.T) {
+	type Item struct {
+		Name  string `csvplus:"first name"`
+		Order int    `csvplus:"order"`
+	}
+	data := "first name,order\nRob,1\n"
+
+	for _, tt := range []struct {
+		name     string
+		quote    func(string) string
+		expected string
+	}{
+		{"ANSI", nil, `INSERT INTO "public"."my ""items""" ("first name", "order") VALUES (?, ?)`},
+		{
+			"MySQL",
+			func(s string) string { return "`" + s + "`" },
+			"INSERT INTO `public`.`my \"items\"` (`first name`, `order`) VALUES (?, ?)",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fdb := &fakeDB{}
+			ins := csvplus.NewSQLInserter(openFakeDB(t, fdb), `public.my "items"`)
+			if tt.quote != nil {
+				ins.QuoteIdentifier(tt.quote)
+			}
+			_, err := ins.Insert(context.Background(), csvplus.NewDecoder(strings.NewReader(data)), &Item{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fdb.execs) != 1 || fdb.execs[0].query != tt.expected {
+				t.Errorf("expected %s, got: %v", tt.expected, fdb.execs)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package csvplus
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// rule is a validation rule for the values of a column, check returns an error describing why v is invalid.
+type rule struct {
+	name  string
+	check func(v string) error
+}
+
+// getRules gets the validation rules of a field from its csvplusValidate and csvplusPattern struct tags.
+func getRules(sf reflect.StructField) ([]rule, error) {
+	rules, err := parseRules(sf.Tag.Get("csvplusValidate"), sf.Tag.Get("csvplusPattern"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid validation tag on field %s", sf.Name)
+	}
+	return rules, nil
+}
+
+// parseRules parses comma separated validation rules (eg nonempty,maxlen=20,min=0,max=100,oneof=a|b|c) and a regular
+// expression values must match, either can be empty.
+func parseRules(rules, pattern string) ([]rule, error) { // nolint: gocyclo
+	var parsed []rule
+	if rules != "" {
+		for _, r := range strings.Split(rules, ",") {
+			name, arg, hasArg := strings.Cut(r, "=")
+			var check func(v string) error
+			switch name {
+			case "nonempty":
+				check = func(v string) error {
+					if v == "" {
+						return errors.New("must not be empty")
+					}
+					return nil
+				}
+			case "minlen", "maxlen":
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 0 {
+					return nil, errors.Errorf("invalid %s rule %q", name, r)
+				}
+				check = func(v string) error {
+					l := utf8.RuneCountInString(v)
+					if name == "minlen" && l < n {
+						return errors.Errorf("must be at least %d characters", n)
+					}
+					if name == "maxlen" && l > n {
+						return errors.Errorf("must be at most %d characters", n)
+					}
+					return nil
+				}
+			case "min", "max":
+				limit, err := strconv.ParseFloat(arg, 64)
+				if err != nil {
+					return nil, errors.Errorf("invalid %s rule %q", name, r)
+				}
+				check = func(v string) error {
+					f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+					if err != nil {
+						return errors.New("must be a number")
+					}
+					if name == "min" && f < limit {
+						return errors.Errorf("must be at least %s", arg)
+					}
+					if name == "max" && f > limit {
+						return errors.Errorf("must be at most %s", arg)
+					}
+					return nil
+				}
+			case "oneof":
+				if !hasArg || arg == "" {
+					return nil, errors.Errorf("invalid oneof rule %q", r)
+				}
+				allowed := strings.Split(arg, "|")
+				check = func(v string) error {
+					for _, a := range allowed {
+						if v == a {
+							return nil
+						}
+					}
+					return errors.Errorf("must be one of %s", strings.Join(allowed, ", "))
+				}
+			default:
+				return nil, errors.Errorf("unknown rule %s", name)
+			}
+			parsed = append(parsed, rule{name: name, check: check})
+		}
+	}
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid pattern")
+		}
+		parsed = append(parsed, rule{name: "pattern", check: func(v string) error {
+			if !re.MatchString(v) {
+				return errors.Errorf("must match %s", pattern)
+			}
+			return nil
+		}})
+	}
+	return parsed, nil
+}
+
+// checkRules returns the error of the first rule v fails, empty values are only checked by nonempty rules.
+func checkRules(rules []rule, v string) error {
+	for _, r := range rules {
+		if v == "" && r.name != "nonempty" {
+			continue
+		}
+		if err := r.check(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidationError is returned by Decode when values fail the validation rules of their columns, the rows with invalid
+// values aren't stored but decoding continues so every invalid value is reported (unless there's an OnError function,
+// then each row's ValidationError is passed to it). Rules are set with a csvplusValidate tag (or ColumnSpec.Validate),
+// eg csvplusValidate:"nonempty,maxlen=20", the rules are nonempty, minlen=n, maxlen=n, min=x, max=x (values must be
+// numbers) and oneof=a|b|c. A csvplusPattern tag (or ColumnSpec.Pattern) sets a regular expression values must match.
+// Rules are checked after converters and defaults are applied, empty values are only checked by nonempty.
+type ValidationError struct {
+	// Violations has an UnmarshalError (wrapping ErrValidation) for each invalid value in row order, RawErr describes
+	// the rule that failed (eg must be at most 20 characters) and is suitable for showing to end users.
+	Violations []UnmarshalError
+}
+
+func (ve ValidationError) Error() string {
+	rows := make(map[int]bool)
+	for _, v := range ve.Violations {
+		rows[v.Row] = true
+	}
+	msg := fmt.Sprintf("%d invalid values in %d rows", len(ve.Violations), len(rows))
+	if len(ve.Violations) > 0 {
+		msg += ", first: " + ve.Violations[0].Error()
+	}
+	return msg
+}
+
+// Is reports whether target is ErrValidation.
+func (ve ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Rows returns the violations grouped by row number.
+func (ve ValidationError) Rows() map[int][]UnmarshalError {
+	rows := make(map[int][]UnmarshalError)
+	for _, v := range ve.Violations {
+		rows[v.Row] = append(rows[v.Row], v)
+	}
+	return rows
+}
@@ -0,0 +1,119 @@
+package csvplus_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/j0hnsmith/csvplus"
+)
+
+func TestDecoder_Validation(t *testing.T) {
+	type Item struct {
+		Code   string  `csvplus:"code" csvplusPattern:"^[A-Z]{3}$"`
+		Name   string  `csvplus:"name" csvplusValidate:"nonempty,maxlen=5"`
+		Price  float64 `csvplus:"price" csvplusValidate:"min=0,max=100"`
+		Status string  `csvplus:"status" csvplusValidate:"oneof=new|used"`
+	}
+	data := "code,name,price,status\n" +
+		"ABC,pen,1.5,new\n" +
+		"abc,,200,broken\n" +
+		"DEF,pencil,2,\n" +
+		"GHI,ink,3,used\n"
+
+	var items []Item
+	err := csvplus.Unmarshal([]byte(data), &items)
+	if !errors.Is(err, csvplus.ErrValidation) {
+		t.Fatalf("expected ErrValidation, got: %v", err)
+	}
+	var ve csvplus.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected ValidationError, got: %T", err)
+	}
+
+	type violation struct {
+		row    int
+		column string
+		value  string
+		msg    string
+	}
+	expected := []violation{
+		{2, "code", "abc", "must match ^[A-Z]{3}$"},
+		{2, "name", "", "must not be empty"},
+		{2, "price", "200", "must be at most 100"},
+		{2, "status", "broken", "must be one of new, used"},
+		{3, "name", "pencil", "must be at most 5 characters"},
+	}
+	var got []violation
+	for _, v := range ve.Violations {
+		if !errors.Is(v, csvplus.ErrValidation) {
+			t.Errorf("expected violation to be ErrValidation: %v", v)
+		}
+		got = append(got, violation{v.Row, v.Column, v.Value, v.RawErr.Error()})
+	}
+	// fields are checked in no particular order
+	sort.Slice(got, func(i, j int) bool {
+		return got[i].row < got[j].row || got[i].row == got[j].row && got[i].column < got[j].column
+	})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if len(ve.Rows()) != 2 {
+		t.Errorf("expected 2 invalid rows, got: %v", ve.Rows())
+	}
+
+	if len(items) != 2 || items[0].Code != "ABC" || items[1].Code != "GHI" {
+		t.Errorf("expected only the valid rows to be stored, got: %+v", items)
+	}
+
+	t.Run("on error", func(t *testing.T) {
+		var items []Item
+		var rows []int
+		err := csvplus.NewDecoder(strings.NewReader(data)).
+			OnError(func(row int, record []string, err error) error {
+				if !errors.Is(err, csvplus.ErrValidation) {
+					return err
+				}
+				rows = append(rows, row)
+				return nil
+			}).
+			Decode(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rows, []int{2, 3}) {
+			t.Errorf("expected rows 2 and 3, got: %v", rows)
+		}
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		schema := csvplus.NewSchema(
+			csvplus.Column("code", nil).Pattern("^[A-Z]+$"),
+			csvplus.Column("price", 0.0).Validate("max=10"),
+		)
+		var rows []map[string]interface{}
+		err := csvplus.NewDecoder(strings.NewReader(data)).Schema(schema).Decode(&rows)
+		var ve csvplus.ValidationError
+		if !errors.As(err, &ve) || len(ve.Violations) != 2 {
+			t.Fatalf("expected 2 violations, got: %v", err)
+		}
+		if len(rows) != 3 {
+			t.Errorf("expected 3 rows, got: %v", rows)
+		}
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		type Bad struct {
+			Name string `csvplusValidate:"maxlen=x"`
+		}
+		var bad []Bad
+		if err := csvplus.Unmarshal([]byte("Name\na\n"), &bad); err == nil {
+			t.Error("expected an error for an invalid rule")
+		}
+		if err := csvplus.CheckType(reflect.TypeOf(Bad{})); err == nil {
+			t.Error("expected CheckType to report the invalid rule")
+		}
+	})
+}